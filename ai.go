@@ -0,0 +1,196 @@
+package gorillas
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"strings"
+)
+
+// AILevel sets how well AutoShot aims.
+type AILevel int
+
+const (
+	// AIEasy misjudges the wind badly and has an unsteady hand.
+	AIEasy AILevel = iota
+	// AINormal allows for about half the wind and aims roughly.
+	AINormal
+	// AIHard allows for most of the wind and rarely slips.
+	AIHard
+	// AIPerfect throws the shot FindShot works out, every time.
+	AIPerfect
+)
+
+// AILevels lists every level from weakest to strongest.
+var AILevels = []AILevel{AIEasy, AINormal, AIHard, AIPerfect}
+
+// String returns the name used for the level in flags and settings files.
+func (l AILevel) String() string {
+	switch l {
+	case AIEasy:
+		return "easy"
+	case AIHard:
+		return "hard"
+	case AIPerfect:
+		return "perfect"
+	default:
+		return "normal"
+	}
+}
+
+// Step returns the level n places stronger than l, wrapping round from
+// AIPerfect to AIEasy and back.
+func (l AILevel) Step(n int) AILevel {
+	k := len(AILevels)
+	return AILevels[((int(l)+n)%k+k)%k]
+}
+
+// ParseAILevel converts a name such as "easy" or "Perfect" into an AILevel.
+func ParseAILevel(s string) (AILevel, error) {
+	switch strings.ToLower(strings.NewReplacer("-", "", "_", "", " ", "").Replace(strings.TrimSpace(s))) {
+	case "easy":
+		return AIEasy, nil
+	case "normal", "medium":
+		return AINormal, nil
+	case "hard":
+		return AIHard, nil
+	case "perfect", "expert":
+		return AIPerfect, nil
+	}
+	return AINormal, fmt.Errorf("unknown AI level %q", s)
+}
+
+// aiSkill describes how a level below AIPerfect throws: the share of the
+// wind it allows for on its first throw at a target, and how many degrees
+// and units of power its hand may slip either way.
+type aiSkill struct {
+	windSense  float64
+	angleNoise float64
+	powerNoise float64
+}
+
+var aiSkills = map[AILevel]aiSkill{
+	AIEasy:   {windSense: 0, angleNoise: 6, powerNoise: 8},
+	AINormal: {windSense: 0.5, angleNoise: 3, powerNoise: 4},
+	AIHard:   {windSense: 0.9, angleNoise: 1, powerNoise: 1.5},
+}
+
+// aiSeedSalt separates the AI's random stream from the city's, so the same
+// seed does not make them move together.
+const aiSeedSalt = 0x5eed_a1
+
+// After aiBlockedThrows throws stopped in the first half of the way, the AI
+// lobs aiLobStep degrees higher, up to aiMaxAngle.
+const (
+	aiBlockedThrows = 2
+	aiLobStep       = 10
+	aiMaxAngle      = 80
+	aiSlipTries     = 3
+)
+
+// aim is what a computer player remembers of its throws at one target.
+type aim struct {
+	// aimed is set once the AI has thrown at target.
+	aimed  bool
+	target float64
+	// angle is the angle it meant to throw at, before its hand slipped.
+	angle float64
+	// power is the power of the last throw and reach how far along the way
+	// to target it landed, 1 being on target.
+	power float64
+	reach float64
+	// short is the strongest throw that fell short and long the weakest that
+	// went past, or 0 before there was one.
+	short, long float64
+	blocked     int
+}
+
+// aiRng returns the random source the AI's hand slips with. It is kept
+// apart from Rand so a replay, which records only the throws, builds the
+// same cities.
+func (g *Game) aiRng() *rand.Rand {
+	if g.aiRand == nil {
+		g.aiRand = rand.New(rand.NewSource(g.Seed ^ aiSeedSalt))
+	}
+	return g.aiRand
+}
+
+// aimAt returns the memory of the player at idx, making room for it first.
+func (g *Game) aimAt(idx int) *aim {
+	if len(g.aims) < len(g.Gorillas) {
+		g.aims = append(g.aims, make([]aim, len(g.Gorillas)-len(g.aims))...)
+	}
+	return &g.aims[idx]
+}
+
+// AIShot picks the angle and power the current player throws at level. A
+// perfect player uses FindShot. The others start from a shot worked out with
+// only part of the wind allowed for, then correct the power from where their
+// own throws land: scaling it by how far short or long the last one was, and
+// splitting the difference once they have thrown both short and long. When
+// their throws keep hitting something close by they lob higher. Every throw
+// slips by a random amount that grows as the level falls, though never into
+// the thrower's own side.
+func (g *Game) AIShot(level AILevel) (angle, power float64) {
+	skill, ok := aiSkills[level]
+	if !ok {
+		return g.FindShot()
+	}
+	a := g.aimAt(g.Current)
+	target := g.targetX(g.facing(g.Current))
+	switch {
+	case !a.aimed || a.target != target:
+		sim := g.Clone()
+		sim.Wind *= skill.windSense
+		angle, power = sim.FindShot()
+		*a = aim{aimed: true, target: target, angle: angle}
+	case a.blocked >= aiBlockedThrows:
+		angle = math.Min(a.angle+aiLobStep, aiMaxAngle)
+		power = a.power
+		*a = aim{aimed: true, target: target, angle: angle}
+	case a.short > 0 && a.long > 0:
+		angle = a.angle
+		power = (a.short + a.long) / 2
+	default:
+		angle = a.angle
+		power = a.power / math.Sqrt(math.Max(0.25, math.Min(4, a.reach)))
+	}
+	// nobody means to throw into their own face, so a slip that would is
+	// tried again, and after aiSlipTries the AI holds steady
+	r := g.aiRng()
+	for try := 0; try < aiSlipTries; try++ {
+		a := math.Max(5, math.Min(85, angle+(r.Float64()*2-1)*skill.angleNoise))
+		p := math.Max(10, math.Min(200, power+(r.Float64()*2-1)*skill.powerNoise))
+		if _, hurt := g.tryShot(a, p); !hurt {
+			return a, p
+		}
+	}
+	return math.Max(5, math.Min(85, angle)), math.Max(10, math.Min(200, power))
+}
+
+// rememberMiss lets a computer player learn from the throw that just missed.
+func (g *Game) rememberMiss() {
+	if g.Current >= len(g.aims) || !g.aims[g.Current].aimed {
+		return
+	}
+	a := &g.aims[g.Current]
+	a.power = g.LastPower[g.Current]
+	a.reach = 0
+	if d := g.lastOtherX - g.lastStartX; d != 0 {
+		a.reach = (g.Banana.X - g.lastStartX) / d
+	}
+	if a.reach < 1 {
+		a.short = math.Max(a.short, a.power)
+	} else if a.long == 0 || a.power < a.long {
+		a.long = a.power
+	}
+	if a.long > 0 && a.short >= a.long {
+		// the wind changed under it; trust only the latest throw
+		a.short, a.long = 0, 0
+	}
+	if a.reach < 0.5 {
+		a.blocked++
+	} else {
+		a.blocked = 0
+	}
+}
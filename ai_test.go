@@ -0,0 +1,77 @@
+package gorillas
+
+import "testing"
+
+func TestParseAILevel(t *testing.T) {
+	for _, l := range AILevels {
+		if got, err := ParseAILevel(l.String()); err != nil || got != l {
+			t.Errorf("ParseAILevel(%q) = %v, %v", l.String(), got, err)
+		}
+	}
+	if got, err := ParseAILevel(" Perfect "); err != nil || got != AIPerfect {
+		t.Errorf("ParseAILevel(\" Perfect \") = %v, %v", got, err)
+	}
+	if _, err := ParseAILevel("cheating"); err == nil {
+		t.Error("expected an error for an unknown level")
+	}
+	if AIPerfect.Step(1) != AIEasy || AIEasy.Step(-1) != AIPerfect || AINormal.Step(1) != AIHard {
+		t.Error("Step should walk the levels in order, wrapping round")
+	}
+}
+
+func TestPerfectAIThrowsTheFoundShot(t *testing.T) {
+	g := newFlatGame(t)
+	wantA, wantP := g.FindShot()
+	if a, p := g.AIShot(AIPerfect); a != wantA || p != wantP {
+		t.Fatalf("perfect AI threw %v/%v, FindShot gives %v/%v", a, p, wantA, wantP)
+	}
+}
+
+func TestAICorrectsFromItsOwnMisses(t *testing.T) {
+	g := newFlatGame(t)
+	g.AIShot(AIHard)
+	// the throw falls into the next roof
+	throwAndLand(g, 45, 30)
+	g.setCurrent(0)
+	if _, p := g.AIShot(AIHard); p <= 30+aiSkills[AIHard].powerNoise {
+		t.Fatalf("after falling short the AI threw at power %v", p)
+	}
+	// and this one sails off the far side of the city
+	throwAndLand(g, 45, 150)
+	g.setCurrent(0)
+	if _, p := g.AIShot(AIHard); p >= 150-aiSkills[AIHard].powerNoise || p <= 30 {
+		t.Fatalf("between a short and a long throw the AI threw at power %v", p)
+	}
+}
+
+func TestAIFindsTheRangeAtEveryLevel(t *testing.T) {
+	for _, level := range AILevels {
+		t.Run(level.String(), func(t *testing.T) {
+			g := newFlatGame(t)
+			// a wind the weaker levels misjudge
+			g.Wind = 4
+			// the round is won once the city is rebuilt, so watch the score
+			for throws := 1; g.Wins[0] == 0; throws++ {
+				if throws > 20 {
+					t.Fatal("the AI never hit")
+				}
+				g.setCurrent(0)
+				g.Angle, g.Power = g.AIShot(level)
+				throwAndLand(g, g.Angle, g.Power)
+				if g.Wins[1] > 0 {
+					t.Fatal("the AI hit itself")
+				}
+			}
+		})
+	}
+}
+
+func TestAILeavesTheGameRandomStreamAlone(t *testing.T) {
+	g, same := newFlatGame(t), newFlatGame(t)
+	for i := 0; i < 3; i++ {
+		g.AIShot(AIEasy)
+	}
+	if g.Rand.Int63() != same.Rand.Int63() {
+		t.Fatal("aiming drew from the game's random stream")
+	}
+}
@@ -0,0 +1,373 @@
+package gorillas
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+)
+
+// BotProtocol is the version of the bot protocol sent with every turn.
+//
+// A bot is a program that plays a seat by reading one JSON object per line
+// on stdin and answering each with one JSON object per line on stdout. When
+// it is the bot's turn the game sends a BotTurn, {"type":"turn",...}, and the
+// bot replies with a BotReply such as {"angle":45,"power":60}. Angles are in
+// degrees above the horizontal towards Facing, as a player would type them,
+// from 0 to 360, and power runs from 0 to 200. Lines with other types may be
+// added in later versions; bots should ignore types they do not know. Stdin
+// is closed when the game is over. Anything the bot writes to stderr is for
+// its author.
+const BotProtocol = 1
+
+// DefaultBotTimeout is how long a bot may think about a turn before it
+// loses its seat.
+const DefaultBotTimeout = 5 * time.Second
+
+// botExitGrace is how long a closed bot has to exit before it is killed.
+const botExitGrace = time.Second
+
+// BotTurn is what a bot can see when it is its turn: the city in the map
+// file format, the gorillas, the weather and the throws made so far this
+// round. Positions are in world units from the top left.
+type BotTurn struct {
+	Type     string `json:"type"`
+	Protocol int    `json:"protocol"`
+	// Seat is the gorilla the bot throws for and Facing the way its throws
+	// go: 1 to the right, -1 to the left.
+	Seat   int     `json:"seat"`
+	Facing float64 `json:"facing"`
+	// Round counts the rounds of the match from one and Wins holds the
+	// rounds each side has won so far.
+	Round    int          `json:"round"`
+	Wins     []int        `json:"wins"`
+	Wind     float64      `json:"wind"`
+	Gravity  float64      `json:"gravity"`
+	City     *CityMap     `json:"city"`
+	Gorillas []BotGorilla `json:"gorillas"`
+	Shots    []BotShot    `json:"shots"`
+}
+
+// BotGorilla is one gorilla as a bot sees it.
+type BotGorilla struct {
+	Name string  `json:"name"`
+	X    float64 `json:"x"`
+	Y    float64 `json:"y"`
+	// Side is the team the gorilla plays for, or its seat without teams.
+	Side int  `json:"side"`
+	Out  bool `json:"out,omitempty"`
+}
+
+// BotShot is the result of one throw of the round.
+type BotShot struct {
+	Seat  int     `json:"seat"`
+	Angle float64 `json:"angle"`
+	Power float64 `json:"power"`
+	Wind  float64 `json:"wind"`
+	// X and Y give where the banana came down, or where it was last seen
+	// if it left the city.
+	X float64 `json:"x"`
+	Y float64 `json:"y"`
+	// Result is "building", "kill", "self" or "miss" for a banana that
+	// fell to the ground or left the city.
+	Result    string `json:"result"`
+	Knocked   []int  `json:"knocked,omitempty"`
+	Weak      bool   `json:"weak,omitempty"`
+	Backwards bool   `json:"backwards,omitempty"`
+	Sun       bool   `json:"sun,omitempty"`
+}
+
+// BotReply is a bot's answer to a turn. Err is set instead when the bot
+// gave no usable answer.
+type BotReply struct {
+	Angle float64 `json:"angle"`
+	Power float64 `json:"power"`
+	Err   error   `json:"-"`
+}
+
+// Bot is a running bot program.
+type Bot struct {
+	// Timeout is how long the bot may take to answer a turn.
+	Timeout time.Duration
+
+	cmd     *exec.Cmd
+	in      io.WriteCloser
+	lines   chan string
+	quit    chan struct{}
+	closing sync.Once
+	err     error
+}
+
+// StartBot runs command, split into a program and its arguments at spaces,
+// as a bot. Its stderr goes to stderr, or nowhere when that is nil.
+func StartBot(command string, stderr io.Writer) (*Bot, error) {
+	args := strings.Fields(command)
+	if len(args) == 0 {
+		return nil, errors.New("bot: no command")
+	}
+	cmd := exec.Command(args[0], args[1:]...)
+	cmd.Stderr = stderr
+	in, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, fmt.Errorf("bot: %w", err)
+	}
+	out, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, fmt.Errorf("bot: %w", err)
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("bot: %w", err)
+	}
+	b := &Bot{Timeout: DefaultBotTimeout, cmd: cmd, in: in, lines: make(chan string, 1), quit: make(chan struct{})}
+	go b.read(out)
+	return b, nil
+}
+
+// read passes the bot's output on line by line until it ends or the bot is
+// closed.
+func (b *Bot) read(r io.Reader) {
+	defer close(b.lines)
+	sc := bufio.NewScanner(r)
+	for sc.Scan() {
+		select {
+		case b.lines <- sc.Text():
+		case <-b.quit:
+			return
+		}
+	}
+}
+
+// Ask sends turn to the bot and returns a channel that receives its answer,
+// or an error if none comes within Timeout. Only one turn may be asked at a
+// time, and a bot that fails to answer should be closed.
+func (b *Bot) Ask(turn BotTurn) <-chan BotReply {
+	out := make(chan BotReply, 1)
+	go func() {
+		got := make(chan BotReply, 1)
+		go func() { got <- b.exchange(turn) }()
+		timer := time.NewTimer(b.Timeout)
+		defer timer.Stop()
+		select {
+		case r := <-got:
+			out <- r
+		case <-timer.C:
+			out <- BotReply{Err: fmt.Errorf("bot: no answer within %v", b.Timeout)}
+		}
+	}()
+	return out
+}
+
+func (b *Bot) exchange(turn BotTurn) BotReply {
+	line, err := json.Marshal(turn)
+	if err != nil {
+		return BotReply{Err: fmt.Errorf("bot: %w", err)}
+	}
+	if _, err := b.in.Write(append(line, '\n')); err != nil {
+		return BotReply{Err: fmt.Errorf("bot: send turn: %w", err)}
+	}
+	reply, ok := <-b.lines
+	if !ok {
+		return BotReply{Err: errors.New("bot: exited without answering")}
+	}
+	return ParseBotReply(reply)
+}
+
+// ParseBotReply reads a bot's answer, checking it names an angle and power
+// a player could have typed.
+func ParseBotReply(line string) BotReply {
+	var r struct {
+		Angle *float64 `json:"angle"`
+		Power *float64 `json:"power"`
+	}
+	if err := json.Unmarshal([]byte(line), &r); err != nil {
+		return BotReply{Err: fmt.Errorf("bot: bad answer %q: %w", line, err)}
+	}
+	if r.Angle == nil || r.Power == nil {
+		return BotReply{Err: fmt.Errorf("bot: answer %q needs an angle and a power", line)}
+	}
+	if *r.Angle < 0 || *r.Angle > 360 || math.IsNaN(*r.Angle) {
+		return BotReply{Err: fmt.Errorf("bot: angle %v is not between 0 and 360", *r.Angle)}
+	}
+	if *r.Power < 0 || *r.Power > 200 || math.IsNaN(*r.Power) {
+		return BotReply{Err: fmt.Errorf("bot: power %v is not between 0 and 200", *r.Power)}
+	}
+	return BotReply{Angle: *r.Angle, Power: *r.Power}
+}
+
+// Close ends the bot's input and waits a moment for it to exit before
+// killing it. It returns how the bot exited.
+func (b *Bot) Close() error {
+	b.closing.Do(func() {
+		close(b.quit)
+		b.in.Close()
+		done := make(chan error, 1)
+		go func() { done <- b.cmd.Wait() }()
+		select {
+		case b.err = <-done:
+		case <-time.After(botExitGrace):
+			b.cmd.Process.Kill()
+			b.err = <-done
+		}
+	})
+	return b.err
+}
+
+// Bots lets bot programs play some of a game's seats. It follows the game's
+// events to tell each bot how the round's throws went.
+type Bots struct {
+	g       *Game
+	seats   map[int]*Bot
+	dropped map[int]bool
+	reply   <-chan BotReply
+	shots   []BotShot
+}
+
+// NewBots returns an empty set of bots for g.
+func NewBots(g *Game) *Bots {
+	b := &Bots{g: g, seats: map[int]*Bot{}, dropped: map[int]bool{}}
+	g.Subscribe(b)
+	return b
+}
+
+// Seat hands the gorilla at idx to bot.
+func (b *Bots) Seat(idx int, bot *Bot) {
+	b.seats[idx] = bot
+}
+
+// Plays reports whether the gorilla at idx is played by a bot, or by the
+// computer standing in for one that failed.
+func (b *Bots) Plays(idx int) bool {
+	return b.seats[idx] != nil || b.dropped[idx]
+}
+
+// Poll is called while the game waits for a throw. When the current player
+// is a bot it asks the bot for a shot, the first time, and throws the answer
+// once it has arrived; it reports whether the player was a bot. A bot that
+// does not answer in time, answers nonsense or exits is closed and its error
+// returned, and from then on AutoShot throws for its seat.
+func (b *Bots) Poll() (bool, error) {
+	return b.poll(false)
+}
+
+// Wait is Poll that waits for the bot's answer before returning.
+func (b *Bots) Wait() (bool, error) {
+	return b.poll(true)
+}
+
+func (b *Bots) poll(wait bool) (bool, error) {
+	seat := b.g.Current
+	if b.dropped[seat] {
+		b.g.AutoShot()
+		return true, nil
+	}
+	bot := b.seats[seat]
+	if bot == nil {
+		return false, nil
+	}
+	if b.reply == nil {
+		b.reply = bot.Ask(b.Turn())
+	}
+	var r BotReply
+	if wait {
+		r = <-b.reply
+	} else {
+		select {
+		case r = <-b.reply:
+		default:
+			return true, nil
+		}
+	}
+	b.reply = nil
+	if r.Err != nil {
+		bot.Close()
+		delete(b.seats, seat)
+		b.dropped[seat] = true
+		return true, fmt.Errorf("%s: %w", b.g.Players[seat], r.Err)
+	}
+	b.g.Angle, b.g.Power = r.Angle, r.Power
+	b.g.Throw()
+	return true, nil
+}
+
+// Close closes every bot.
+func (b *Bots) Close() {
+	for _, bot := range b.seats {
+		bot.Close()
+	}
+}
+
+// Turn returns what the current player sees of the game.
+func (b *Bots) Turn() BotTurn {
+	g := b.g
+	t := BotTurn{
+		Type:     "turn",
+		Protocol: BotProtocol,
+		Seat:     g.Current,
+		Facing:   g.facing(g.Current),
+		Round:    1,
+		Wind:     g.Wind,
+		Gravity:  g.Gravity,
+		City:     g.CityMap(),
+		Shots:    append([]BotShot{}, b.shots...),
+	}
+	if g.Match != nil {
+		t.Round = g.Match.Played + 1
+		t.Wins = append([]int{}, g.Match.Wins...)
+	}
+	// the weather is given above and the colours and lit windows are only
+	// decoration
+	t.City.Wind, t.City.Gravity = nil, nil
+	for i := range t.City.Buildings {
+		t.City.Buildings[i].Colour = ""
+		t.City.Buildings[i].Windows = nil
+	}
+	for i, gr := range g.Gorillas {
+		t.Gorillas = append(t.Gorillas, BotGorilla{Name: g.Players[i], X: gr.X, Y: gr.Y, Side: g.Side(i), Out: gr.Dead})
+	}
+	return t
+}
+
+// HandleEvent notes how each throw of the round turns out.
+func (b *Bots) HandleEvent(e GameEvent) {
+	if e.Kind == ThrowStarted {
+		b.shots = append(b.shots, BotShot{Seat: e.Player, Angle: e.Angle, Power: e.Power, Wind: b.g.Wind, X: e.X, Y: e.Y, Result: "miss"})
+		return
+	}
+	if e.Kind == RoundOver {
+		b.shots = nil
+	}
+	if len(b.shots) == 0 {
+		return
+	}
+	s := &b.shots[len(b.shots)-1]
+	switch e.Kind {
+	case BananaMoved:
+		s.X, s.Y = e.X, e.Y
+	case BuildingHit:
+		s.X, s.Y = e.X, e.Y
+		s.Result = "building"
+	case GorillaKilled, SelfKill:
+		s.X, s.Y = e.X, e.Y
+		s.Knocked = append(s.Knocked, e.Target)
+		if s.Result != "self" {
+			s.Result = "kill"
+			if e.Kind == SelfKill {
+				s.Result = "self"
+			}
+		}
+	case WeakShot:
+		s.X, s.Y = e.X, e.Y
+		s.Weak = true
+	case Backwards:
+		s.X, s.Y = e.X, e.Y
+		s.Backwards = true
+	case SunHit:
+		s.Sun = true
+	}
+}
@@ -0,0 +1,118 @@
+package gorillas
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestHelperBot is not a test: it is the bot the other tests run, picked
+// with GORILLAS_TEST_BOT. "aim" answers every turn with 60/30 after writing
+// the turn to GORILLAS_TEST_BOT_LOG, "silent" never answers, "garbage"
+// answers nonsense and "quit" exits at the first turn.
+func TestHelperBot(t *testing.T) {
+	mode := os.Getenv("GORILLAS_TEST_BOT")
+	if mode == "" {
+		return
+	}
+	in := bufio.NewScanner(os.Stdin)
+	for in.Scan() {
+		switch mode {
+		case "aim":
+			if log := os.Getenv("GORILLAS_TEST_BOT_LOG"); log != "" {
+				os.WriteFile(log, in.Bytes(), 0644)
+			}
+			fmt.Println(`{"angle":60,"power":30}`)
+		case "garbage":
+			fmt.Println("throw it really hard")
+		case "quit":
+			os.Exit(0)
+		}
+	}
+	os.Exit(0)
+}
+
+// startTestBot runs TestHelperBot in mode as a bot.
+func startTestBot(t *testing.T, mode string) *Bot {
+	t.Helper()
+	t.Setenv("GORILLAS_TEST_BOT", mode)
+	b, err := StartBot(os.Args[0]+" -test.run=^TestHelperBot$", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { b.Close() })
+	return b
+}
+
+func TestBotPlaysItsSeat(t *testing.T) {
+	log := filepath.Join(t.TempDir(), "turn.json")
+	t.Setenv("GORILLAS_TEST_BOT_LOG", log)
+	g := newFlatGame(t)
+	bots := NewBots(g)
+	bots.Seat(1, startTestBot(t, "aim"))
+	if played, err := bots.Wait(); played || err != nil {
+		t.Fatalf("player 1 is not a bot, got %v, %v", played, err)
+	}
+	// player 1 throws into the next roof
+	throwAndLand(g, 60, 30)
+	if played, err := bots.Wait(); !played || err != nil {
+		t.Fatalf("the bot did not play: %v, %v", played, err)
+	}
+	if !g.Banana.Active || g.Angle != 60 || g.Power != 30 {
+		t.Fatalf("expected the bot's 60/30 in the air, got %v/%v", g.Angle, g.Power)
+	}
+	b, err := os.ReadFile(log)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var turn BotTurn
+	if err := json.Unmarshal(b, &turn); err != nil {
+		t.Fatal(err)
+	}
+	if turn.Type != "turn" || turn.Protocol != BotProtocol || turn.Seat != 1 || turn.Facing != -1 {
+		t.Errorf("unexpected turn header %+v", turn)
+	}
+	if len(turn.Gorillas) != 2 || len(turn.City.Buildings) != len(g.Buildings) {
+		t.Errorf("the turn should show the whole city, got %d gorillas and %d buildings", len(turn.Gorillas), len(turn.City.Buildings))
+	}
+	if len(turn.Shots) != 1 || turn.Shots[0].Seat != 0 || turn.Shots[0].Result != "building" {
+		t.Errorf("expected player 1's throw into a building, got %+v", turn.Shots)
+	}
+}
+
+func TestFailedBotLosesItsSeat(t *testing.T) {
+	for _, mode := range []string{"silent", "garbage", "quit"} {
+		t.Run(mode, func(t *testing.T) {
+			g := newFlatGame(t)
+			bots := NewBots(g)
+			bot := startTestBot(t, mode)
+			bot.Timeout = 200 * time.Millisecond
+			bots.Seat(0, bot)
+			if played, err := bots.Wait(); !played || err == nil {
+				t.Fatalf("expected the bot to fail, got %v, %v", played, err)
+			}
+			if g.Banana.Active {
+				t.Fatal("a failed bot should not throw")
+			}
+			if played, err := bots.Wait(); !played || err != nil || !g.Banana.Active {
+				t.Fatalf("expected the computer to throw in its place, got %v, %v", played, err)
+			}
+		})
+	}
+}
+
+func TestParseBotReply(t *testing.T) {
+	if r := ParseBotReply(`{"angle": 45.5, "power": 70}`); r.Err != nil || r.Angle != 45.5 || r.Power != 70 {
+		t.Errorf("unexpected reply %+v", r)
+	}
+	for _, bad := range []string{``, `45,70`, `{"angle":45}`, `{"angle":-1,"power":50}`, `{"angle":45,"power":201}`} {
+		if r := ParseBotReply(bad); r.Err == nil || !strings.HasPrefix(r.Err.Error(), "bot:") {
+			t.Errorf("ParseBotReply(%q) = %+v, expected an error", bad, r)
+		}
+	}
+}
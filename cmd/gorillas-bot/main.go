@@ -0,0 +1,66 @@
+// Command gorillas-bot is a small example of a bot for the bot protocol
+// described at gorillas.BotProtocol. It throws at 45 degrees with the power
+// the distance to the nearest opponent calls for on flat ground, then
+// corrects the power from where its own bananas land. Build it and hand it
+// a seat:
+//
+//	go build -o gorillas-bot ./cmd/gorillas-bot
+//	./gorillia-tcell -player2-bot ./gorillas-bot
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"os"
+
+	"github.com/arran4/gorillas"
+)
+
+const angle = 45
+
+func main() {
+	in := bufio.NewScanner(os.Stdin)
+	in.Buffer(nil, 1<<20)
+	for in.Scan() {
+		var turn gorillas.BotTurn
+		if err := json.Unmarshal(in.Bytes(), &turn); err != nil {
+			log.Fatal(err)
+		}
+		if turn.Type != "turn" {
+			continue
+		}
+		fmt.Printf(`{"angle":%d,"power":%.1f}`+"\n", angle, power(turn))
+	}
+}
+
+// power works out how hard to throw at the nearest opponent in turn.
+func power(turn gorillas.BotTurn) float64 {
+	me := turn.Gorillas[turn.Seat]
+	target := math.NaN()
+	for _, gr := range turn.Gorillas {
+		if gr.Out || gr.Side == me.Side || (gr.X-me.X)*turn.Facing < 0 {
+			continue
+		}
+		if math.IsNaN(target) || math.Abs(gr.X-me.X) < math.Abs(target-me.X) {
+			target = gr.X
+		}
+	}
+	if math.IsNaN(target) {
+		return 50
+	}
+	// a banana thrown at 45 degrees with power p travels p*p*34/(4*gravity)
+	// on the level, so start there
+	p := math.Sqrt(math.Abs(target-me.X) * 4 * turn.Gravity / 34)
+	for _, s := range turn.Shots {
+		if s.Seat != turn.Seat || s.Angle != angle {
+			continue
+		}
+		// range grows with the square of the power
+		reach := (s.X - me.X) / (target - me.X)
+		p = s.Power / math.Sqrt(math.Max(0.25, math.Min(4, reach)))
+	}
+	return math.Min(p, 200)
+}
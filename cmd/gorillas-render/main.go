@@ -0,0 +1,191 @@
+// Command gorillas-render draws a saved game state, such as the
+// dump_state.json the Ebiten port writes on F5, to a PNG without a GPU or
+// display. It can also export a recorded match, one of its throws or a shot
+// from the highlight reel as an animated GIF:
+//
+//	go run ./cmd/gorillas-render -o state.png dump_state.json
+//	go run ./cmd/gorillas-render -replay replays/20240101-120000.json -throw 3 -o oops.gif
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"image/png"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/arran4/gorillas"
+	imgdraw "github.com/arran4/gorillas/drawings/img"
+)
+
+func main() {
+	out := flag.String("o", "", "image to write (default: the input's name with .png or .gif)")
+	replay := flag.String("replay", "", "recorded match to export as a GIF")
+	throw := flag.Int("throw", 0, "with -replay, export only this throw, counting from 1")
+	highlight := flag.Int("highlight", 0, "export this shot of the highlight reel as a GIF, counting from 1")
+	shots := flag.String("shots", gorillas.DefaultShotsFile, "highlight reel to read -highlight from")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "usage: %s [-o out.png] state.json\n       %[1]s -replay match.json [-throw n] [-o out.gif]\n       %[1]s -highlight n [-shots file] [-o out.gif]\n\nReads a JSON game state, or stdin when the file is -, and renders it,\nor exports a replay or highlight as an animated GIF.\n\n", filepath.Base(os.Args[0]))
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	var err error
+	switch {
+	case *replay != "":
+		if *out == "" {
+			*out = strings.TrimSuffix(*replay, filepath.Ext(*replay)) + ".gif"
+		}
+		err = exportReplay(*replay, *throw, *out)
+	case *highlight > 0:
+		if *out == "" {
+			*out = fmt.Sprintf("highlight-%d.gif", *highlight)
+		}
+		err = exportHighlight(*shots, *highlight, *out)
+	default:
+		if flag.NArg() != 1 {
+			flag.Usage()
+			os.Exit(2)
+		}
+		in := flag.Arg(0)
+		if *out == "" {
+			if in == "-" {
+				fmt.Fprintln(os.Stderr, "-o is required when reading stdin")
+				os.Exit(2)
+			}
+			*out = strings.TrimSuffix(in, filepath.Ext(in)) + ".png"
+		}
+		err = render(in, *out)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+// render reads the game state at in and writes its picture to out.
+func render(in, out string) error {
+	var b []byte
+	var err error
+	if in == "-" {
+		b, err = io.ReadAll(os.Stdin)
+	} else {
+		b, err = os.ReadFile(in)
+	}
+	if err != nil {
+		return fmt.Errorf("read state: %w", err)
+	}
+	var g gorillas.Game
+	if err := json.Unmarshal(b, &g); err != nil {
+		return fmt.Errorf("parse state: %w", err)
+	}
+	if g.Width <= 0 || g.Height <= 0 {
+		g.Width, g.Height = gorillas.WorldWidth, gorillas.WorldHeight
+	}
+	f, err := os.Create(out)
+	if err != nil {
+		return fmt.Errorf("create image: %w", err)
+	}
+	if err := png.Encode(f, imgdraw.Render(&g)); err != nil {
+		f.Close()
+		return fmt.Errorf("encode png: %w", err)
+	}
+	return f.Close()
+}
+
+// exportReplay writes the match recorded at path to out as a GIF, or only
+// its nth throw when n is positive.
+func exportReplay(path string, n int, out string) error {
+	r, err := openReplay(path)
+	if err != nil {
+		return err
+	}
+	f := r.File
+	var a imgdraw.Animation
+	if n <= 0 {
+		err = a.Record(r.Game, r)
+	} else {
+		total := 0
+		for _, round := range f.Rounds {
+			total += len(round.Throws)
+		}
+		if n > total {
+			return fmt.Errorf("replay has %d throws, not %d", total, n)
+		}
+		// play on quietly until the throw before has landed
+		for r.Thrown() < n-1 || busy(r.Game) {
+			r.Step(gorillas.StepDuration)
+		}
+		err = a.RecordUntil(r.Game, r, func() bool { return r.Thrown() == n && !busy(r.Game) })
+	}
+	if err != nil {
+		return err
+	}
+	return writeGIF(&a, out)
+}
+
+// openReplay loads the match recorded at path ready to play back.
+func openReplay(path string) (*gorillas.Replay, error) {
+	f, err := gorillas.LoadReplay(path)
+	if err != nil {
+		return nil, err
+	}
+	r, err := gorillas.NewReplay(f)
+	if err != nil {
+		return nil, err
+	}
+	quiet(r.Game)
+	return r, nil
+}
+
+// quiet silences g, a game a replay throws in. Gorillas recorded without
+// their shape take that of the sprite they are drawn with, which is where
+// the ports that recorded the throws hit them.
+func quiet(g *gorillas.Game) {
+	g.Settings.UseSound = false
+	if g.GorillaMask == nil {
+		g.SetGorillaMask(imgdraw.DefaultGorillaSprite(1))
+	}
+}
+
+// busy reports whether a banana or its explosion is still on screen.
+func busy(g *gorillas.Game) bool {
+	return g.Banana.Active || g.Explosion.Active
+}
+
+// exportHighlight writes the nth shot of the highlight reel in file to out
+// as a GIF.
+func exportHighlight(file string, n int, out string) error {
+	reel, err := gorillas.LoadHighlights(file)
+	if err != nil {
+		return err
+	}
+	if n > len(reel) {
+		return fmt.Errorf("highlight reel has %d shots, not %d", len(reel), n)
+	}
+	r, err := gorillas.NewHighlightReplay(reel[n-1])
+	if err != nil {
+		return err
+	}
+	quiet(r.Game)
+	var a imgdraw.Animation
+	if err := a.Record(r.Game, r); err != nil {
+		return err
+	}
+	return writeGIF(&a, out)
+}
+
+func writeGIF(a *imgdraw.Animation, out string) error {
+	f, err := os.Create(out)
+	if err != nil {
+		return fmt.Errorf("create gif: %w", err)
+	}
+	if err := a.Encode(f); err != nil {
+		f.Close()
+		return fmt.Errorf("encode gif: %w", err)
+	}
+	return f.Close()
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/arran4/gorillas"
+	imgdraw "github.com/arran4/gorillas/drawings/img"
+)
+
+// kills notes who each banana in g kills, in turn.
+func kills(g *gorillas.Game) *[]int {
+	var killed []int
+	g.Subscribe(gorillas.ListenerFunc(func(e gorillas.GameEvent) {
+		if e.Kind == gorillas.GorillaKilled {
+			killed = append(killed, e.Target)
+		}
+	}))
+	return &killed
+}
+
+func TestExportedReplayKeepsItsKills(t *testing.T) {
+	g := gorillas.NewGameWithSeed(gorillas.WorldWidth, gorillas.WorldHeight, gorillas.DefaultBuildingCount, 5)
+	g.League = nil
+	g.ScoreFile, g.ShotsFile = os.DevNull, os.DevNull
+	g.ReplayDir = t.TempDir()
+	g.Settings.UseSound = false
+	g.Settings.DefaultRoundQty = 3
+	// the ports hit the gorillas where they draw them
+	g.SetGorillaMask(imgdraw.DefaultGorillaSprite(1))
+	g.StartMatch()
+	recorded := kills(g)
+	// both sides sweep their angle and power until something falls
+	throws := 0
+	for steps := 0; !g.MatchOver() || g.Explosion.Active || g.Dance.Active; steps++ {
+		if steps > 100000 {
+			t.Fatal("the match never ended")
+		}
+		if !g.Banana.Active && !g.Explosion.Active && !g.Dance.Active {
+			n := throws / 2
+			g.Angle, g.Power = float64(20+n/40%5*12), float64(30+n%40*2)
+			g.Throw()
+			throws++
+		}
+		g.Step(gorillas.StepDuration)
+	}
+	if len(*recorded) == 0 {
+		t.Fatal("nobody was killed")
+	}
+
+	paths, err := gorillas.ListReplays(g.ReplayDir)
+	if err != nil || len(paths) != 1 {
+		t.Fatalf("expected one saved replay, got %v, %v", paths, err)
+	}
+	r, err := openReplay(paths[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	replayed := kills(r.Game)
+	for steps := 0; !r.Done(); steps++ {
+		if steps > 100000 {
+			t.Fatal("the replay never ended")
+		}
+		r.Step(gorillas.StepDuration)
+	}
+	if !reflect.DeepEqual(*replayed, *recorded) {
+		t.Fatalf("the match killed %v, its replay %v", *recorded, *replayed)
+	}
+}
@@ -0,0 +1,64 @@
+// Command gorillas-server plays games for clients over HTTP, as described
+// at gorillas.GameServer, so chat bots and web pages can start games, throw
+// and follow them without linking the game in:
+//
+//	go run ./cmd/gorillas-server -addr :8080
+//	curl -d '{"players":["Ann","Bob"]}' localhost:8080/games
+//	curl -d '{"angle":45,"power":60}' localhost:8080/games/1/throws
+//
+// Every round decided on the server is added to the league file -league,
+// apart from the one the games keep, and served at /league. At most
+// -max-games games are played at once, and a game nobody asks after for
+// -idle is ended.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/arran4/gorillas"
+)
+
+// defaultLeagueFile keeps the server's results apart from the league the
+// games keep.
+const defaultLeagueFile = "gorillas-server.lge"
+
+func main() {
+	addr := flag.String("addr", ":8080", "address to serve on")
+	leagueFile := flag.String("league", defaultLeagueFile, "league file to add the rounds played to, or empty for none")
+	maxGames := flag.Int("max-games", gorillas.DefaultMaxGames, "most games to play at once, or 0 for no limit")
+	idle := flag.Duration("idle", gorillas.DefaultIdleTimeout, "end games nobody has asked after for this long, or 0 to keep them")
+	flag.Parse()
+
+	games := gorillas.NewGameServer()
+	games.MaxGames = *maxGames
+	games.IdleTimeout = *idle
+	if *leagueFile != "" {
+		games.League = gorillas.LoadLeague(*leagueFile)
+	}
+	srv := &http.Server{Addr: *addr, Handler: games}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	closed := make(chan struct{})
+	go func() {
+		defer close(closed)
+		<-ctx.Done()
+		// hang up on the event streams, which would otherwise hold
+		// Shutdown up
+		games.Close()
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		srv.Shutdown(ctx)
+	}()
+	log.Printf("serving games on %s", *addr)
+	if err := srv.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+		log.Fatal(err)
+	}
+	<-closed
+}
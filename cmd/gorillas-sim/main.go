@@ -0,0 +1,407 @@
+// Command gorillas-sim plays many computer-versus-computer matches without
+// any display and reports how they went, so rule changes such as
+// -winnerfirst or -wind variable can be judged on data:
+//
+//	go run ./cmd/gorillas-sim -matches 1000 -format csv
+//
+// With -player1-bot or -player2-bot a bot program plays that seat in every
+// match, so bots can be pitted against the computer or each other.
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/arran4/gorillas"
+)
+
+// windModes are the choices for -wind.
+var windModes = []string{"basic", "variable", "fluctuating", "calm"}
+
+// maxRoundThrows abandons a match whose round the computer players cannot
+// finish, such as one where nobody can find a way over the city.
+const maxRoundThrows = 50
+
+// config describes the matches to play.
+type config struct {
+	Matches     int     `json:"matches"`
+	Seed        int64   `json:"seed"`
+	Players     int     `json:"players"`
+	Rounds      int     `json:"rounds"`
+	Gravity     float64 `json:"gravity"`
+	Wind        string  `json:"wind"`
+	Buildings   int     `json:"buildings"`
+	Skyline     string  `json:"skyline"`
+	WinnerFirst bool    `json:"winnerFirst"`
+	AILevel     string  `json:"aiLevel"`
+	// Player1Bot and Player2Bot are the bot programs playing those seats.
+	Player1Bot string        `json:"player1Bot,omitempty"`
+	Player2Bot string        `json:"player2Bot,omitempty"`
+	BotTimeout time.Duration `json:"-"`
+}
+
+// tally counts what happened over one or more matches.
+type tally struct {
+	Matches   int `json:"matches"`
+	Stalled   int `json:"stalled"`
+	Drawn     int `json:"drawn"`
+	Rounds    int `json:"rounds"`
+	Throws    int `json:"throws"`
+	Kills     int `json:"kills"`
+	SelfKills int `json:"selfKills"`
+	Weak      int `json:"weak"`
+	Backwards int `json:"backwards"`
+	SunHits   int `json:"sunHits"`
+	// FirstMoverRounds counts rounds won by the side that threw first in
+	// them and FirstMoverMatches matches won by the side that threw first.
+	FirstMoverRounds  int `json:"firstMoverRounds"`
+	FirstMoverMatches int `json:"firstMoverMatches"`
+	// MatchWins counts the matches each player won and BotFailures the bots
+	// that lost their seat to the computer mid-match.
+	MatchWins   []int `json:"matchWins"`
+	BotFailures int   `json:"botFailures"`
+}
+
+func (t *tally) add(o tally) {
+	t.Matches += o.Matches
+	t.Stalled += o.Stalled
+	t.Drawn += o.Drawn
+	t.Rounds += o.Rounds
+	t.Throws += o.Throws
+	t.Kills += o.Kills
+	t.SelfKills += o.SelfKills
+	t.Weak += o.Weak
+	t.Backwards += o.Backwards
+	t.SunHits += o.SunHits
+	t.FirstMoverRounds += o.FirstMoverRounds
+	t.FirstMoverMatches += o.FirstMoverMatches
+	for i, n := range o.MatchWins {
+		if i == len(t.MatchWins) {
+			t.MatchWins = append(t.MatchWins, 0)
+		}
+		t.MatchWins[i] += n
+	}
+	t.BotFailures += o.BotFailures
+}
+
+// report is what gorillas-sim prints: the configuration, the raw counts and
+// the rates worked out from them. Rates per throw count every throw, so a
+// self kill rate of 0.05 means one throw in twenty knocked out the thrower.
+type report struct {
+	Config config `json:"config"`
+	Totals tally  `json:"totals"`
+	// FirstMoverRoundRate and FirstMoverMatchRate are the share of rounds
+	// and decided matches won by whoever threw first; 1/players is no
+	// advantage.
+	FirstMoverRoundRate float64 `json:"firstMoverRoundRate"`
+	FirstMoverMatchRate float64 `json:"firstMoverMatchRate"`
+	ThrowsPerRound      float64 `json:"throwsPerRound"`
+	ThrowsPerKill       float64 `json:"throwsPerKill"`
+	SelfKillRate        float64 `json:"selfKillRate"`
+	WeakRate            float64 `json:"weakRate"`
+	BackwardsRate       float64 `json:"backwardsRate"`
+}
+
+func newReport(c config, t tally) report {
+	decided := t.Matches - t.Stalled - t.Drawn
+	return report{
+		Config:              c,
+		Totals:              t,
+		FirstMoverRoundRate: ratio(t.FirstMoverRounds, t.Rounds),
+		FirstMoverMatchRate: ratio(t.FirstMoverMatches, decided),
+		ThrowsPerRound:      ratio(t.Throws, t.Rounds),
+		ThrowsPerKill:       ratio(t.Throws, t.Kills),
+		SelfKillRate:        ratio(t.SelfKills, t.Throws),
+		WeakRate:            ratio(t.Weak, t.Throws),
+		BackwardsRate:       ratio(t.Backwards, t.Throws),
+	}
+}
+
+func ratio(n, d int) float64 {
+	if d == 0 {
+		return 0
+	}
+	return float64(n) / float64(d)
+}
+
+// record returns the CSV header and row for r.
+func (r report) record() (header, row []string) {
+	c, t := r.Config, r.Totals
+	add := func(name, value string) {
+		header = append(header, name)
+		row = append(row, value)
+	}
+	num := func(f float64) string { return strconv.FormatFloat(f, 'f', -1, 64) }
+	add("matches", strconv.Itoa(c.Matches))
+	add("seed", strconv.FormatInt(c.Seed, 10))
+	add("players", strconv.Itoa(c.Players))
+	add("rounds", strconv.Itoa(c.Rounds))
+	add("gravity", num(c.Gravity))
+	add("wind", c.Wind)
+	add("buildings", strconv.Itoa(c.Buildings))
+	add("skyline", c.Skyline)
+	add("winnerFirst", strconv.FormatBool(c.WinnerFirst))
+	add("aiLevel", c.AILevel)
+	add("player1Bot", c.Player1Bot)
+	add("player2Bot", c.Player2Bot)
+	for _, v := range []struct {
+		name string
+		n    int
+	}{
+		{"stalled", t.Stalled}, {"drawn", t.Drawn}, {"roundsPlayed", t.Rounds}, {"throws", t.Throws},
+		{"kills", t.Kills}, {"selfKills", t.SelfKills}, {"weak", t.Weak},
+		{"backwards", t.Backwards}, {"sunHits", t.SunHits},
+		{"firstMoverRounds", t.FirstMoverRounds}, {"firstMoverMatches", t.FirstMoverMatches},
+	} {
+		add(v.name, strconv.Itoa(v.n))
+	}
+	for i, n := range t.MatchWins {
+		add(fmt.Sprintf("player%dMatchWins", i+1), strconv.Itoa(n))
+	}
+	add("botFailures", strconv.Itoa(t.BotFailures))
+	add("firstMoverRoundRate", num(r.FirstMoverRoundRate))
+	add("firstMoverMatchRate", num(r.FirstMoverMatchRate))
+	add("throwsPerRound", num(r.ThrowsPerRound))
+	add("throwsPerKill", num(r.ThrowsPerKill))
+	add("selfKillRate", num(r.SelfKillRate))
+	add("weakRate", num(r.WeakRate))
+	add("backwardsRate", num(r.BackwardsRate))
+	return header, row
+}
+
+// newMatch sets up a quiet game for one match that touches no files.
+func newMatch(c config, seed int64) (*gorillas.Game, error) {
+	settings := gorillas.DefaultSettings()
+	settings.DefaultGravity = c.Gravity
+	settings.DefaultRoundQty = c.Rounds
+	settings.Skyline = c.Skyline
+	settings.WinnerFirst = c.WinnerFirst
+	settings.VariableWind = c.Wind == "variable"
+	settings.WindFluctuations = c.Wind == "fluctuating"
+	level, err := gorillas.ParseAILevel(c.AILevel)
+	if err != nil {
+		return nil, err
+	}
+	settings.AILevel = level
+
+	g := gorillas.NewHeadlessGame(gorillas.WorldWidth, gorillas.WorldHeight, c.Buildings, seed, settings)
+	if c.Wind == "calm" {
+		g.ResetHook = func() { g.Wind = 0 }
+	}
+	// SetPlayers raises a new city under the settings above
+	if err := g.SetPlayers(gorillas.PlayerNames(c.Players)...); err != nil {
+		return nil, err
+	}
+	return g, nil
+}
+
+// play runs one match between computer players and any bots, and counts
+// what happened.
+func play(c config, seed int64) (tally, error) {
+	g, err := newMatch(c, seed)
+	if err != nil {
+		return tally{}, err
+	}
+	bots := gorillas.NewBots(g)
+	defer bots.Close()
+	for i, command := range []string{c.Player1Bot, c.Player2Bot} {
+		if command == "" {
+			continue
+		}
+		bot, err := gorillas.StartBot(command, os.Stderr)
+		if err != nil {
+			return tally{}, fmt.Errorf("-player%d-bot: %w", i+1, err)
+		}
+		bot.Timeout = c.BotTimeout
+		bots.Seat(i, bot)
+	}
+	t := tally{Matches: 1, MatchWins: make([]int, c.Players)}
+	roundStarter, matchStarter := -1, -1
+	roundThrows := 0
+	g.Subscribe(gorillas.ListenerFunc(func(e gorillas.GameEvent) {
+		switch e.Kind {
+		case gorillas.ThrowStarted:
+			t.Throws++
+			roundThrows++
+			if roundStarter < 0 {
+				roundStarter = g.Side(e.Player)
+			}
+			if matchStarter < 0 {
+				matchStarter = roundStarter
+			}
+		case gorillas.GorillaKilled:
+			t.Kills++
+		case gorillas.SelfKill:
+			t.SelfKills++
+		case gorillas.WeakShot:
+			t.Weak++
+		case gorillas.Backwards:
+			t.Backwards++
+		case gorillas.SunHit:
+			t.SunHits++
+		case gorillas.RoundOver:
+			t.Rounds++
+			if e.Winner == roundStarter {
+				t.FirstMoverRounds++
+			}
+			roundStarter, roundThrows = -1, 0
+		case gorillas.MatchOver:
+			if e.Winner < 0 {
+				t.Drawn++
+			} else if e.Winner == matchStarter {
+				t.FirstMoverMatches++
+			}
+			if e.Winner >= 0 {
+				t.MatchWins[e.Winner]++
+			}
+		}
+	}))
+	for !g.MatchOver() || g.Explosion.Active || g.Dance.Active {
+		if roundThrows >= maxRoundThrows {
+			t.Stalled++
+			break
+		}
+		if !g.Banana.Active && !g.Explosion.Active && !g.Dance.Active {
+			if played, err := bots.Wait(); err != nil {
+				t.BotFailures++
+				log.Printf("match with seed %d: %v", seed, err)
+			} else if !played {
+				g.AutoShot()
+			}
+		}
+		g.Step(gorillas.StepDuration)
+	}
+	return t, nil
+}
+
+// run plays c.Matches matches across workers goroutines. Match i always uses
+// seed c.Seed+i, so the totals do not depend on how many workers share them.
+func run(c config, workers int) (tally, error) {
+	seeds := make(chan int64)
+	results := make(chan tally)
+	errs := make(chan error, workers)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for seed := range seeds {
+				t, err := play(c, seed)
+				if err != nil {
+					errs <- err
+					return
+				}
+				results <- t
+			}
+		}()
+	}
+	go func() {
+		for i := 0; i < c.Matches; i++ {
+			seeds <- c.Seed + int64(i)
+		}
+		close(seeds)
+	}()
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+	var total tally
+	for t := range results {
+		total.add(t)
+	}
+	select {
+	case err := <-errs:
+		return total, err
+	default:
+		return total, nil
+	}
+}
+
+func write(w io.Writer, r report, format string) error {
+	switch format {
+	case "json":
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(r)
+	case "csv":
+		header, row := r.record()
+		cw := csv.NewWriter(w)
+		cw.Write(header)
+		cw.Write(row)
+		cw.Flush()
+		return cw.Error()
+	}
+	return fmt.Errorf("unknown format %q", format)
+}
+
+func main() {
+	settings := gorillas.DefaultSettings()
+	c := config{}
+	flag.IntVar(&c.Matches, "matches", 1000, "number of matches to play")
+	flag.Int64Var(&c.Seed, "seed", 1, "seed of the first match; match i uses seed+i")
+	flag.IntVar(&c.Players, "players", gorillas.MinPlayers, "number of gorillas in each match")
+	flag.IntVar(&c.Rounds, "rounds", settings.DefaultRoundQty, "round count of each match")
+	flag.Float64Var(&c.Gravity, "gravity", settings.DefaultGravity, "gravity")
+	flag.StringVar(&c.Wind, "wind", "basic", "wind mode: "+strings.Join(windModes, ", "))
+	flag.IntVar(&c.Buildings, "buildings", gorillas.DefaultBuildingCount, "building count")
+	flag.StringVar(&c.Skyline, "skyline", settings.Skyline, "skyline generator: "+strings.Join(gorillas.SkylineNames(), ", "))
+	flag.BoolVar(&c.WinnerFirst, "winnerfirst", settings.WinnerFirst, "winner starts the next round")
+	flag.StringVar(&c.AILevel, "ai-level", settings.AILevel.String(), "how well the players aim: easy, normal, hard or perfect")
+	flag.StringVar(&c.Player1Bot, "player1-bot", "", "bot program that plays player 1 in every match")
+	flag.StringVar(&c.Player2Bot, "player2-bot", "", "bot program that plays player 2 in every match")
+	flag.DurationVar(&c.BotTimeout, "bot-timeout", gorillas.DefaultBotTimeout, "how long a bot may think about a turn")
+	format := flag.String("format", "json", "output format: json or csv")
+	out := flag.String("o", "", "file to write the statistics to (default stdout)")
+	workers := flag.Int("workers", runtime.NumCPU(), "matches to play at once")
+	flag.Parse()
+
+	if c.Matches <= 0 {
+		log.Fatal("-matches: need at least one match")
+	}
+	if c.Players < gorillas.MinPlayers || c.Players > gorillas.MaxPlayers {
+		log.Fatalf("-players: need %d to %d players, got %d", gorillas.MinPlayers, gorillas.MaxPlayers, c.Players)
+	}
+	if _, err := gorillas.SkylineByName(c.Skyline); err != nil {
+		log.Fatalf("-skyline: %v", err)
+	}
+	if _, err := gorillas.ParseAILevel(c.AILevel); err != nil {
+		log.Fatalf("-ai-level: %v", err)
+	}
+	valid := false
+	for _, m := range windModes {
+		valid = valid || c.Wind == m
+	}
+	if !valid {
+		log.Fatalf("-wind: unknown mode %q", c.Wind)
+	}
+	if *format != "json" && *format != "csv" {
+		log.Fatalf("-format: unknown format %q", *format)
+	}
+
+	total, err := run(c, max(*workers, 1))
+	if err != nil {
+		log.Fatal(err)
+	}
+	w := io.Writer(os.Stdout)
+	if *out != "" {
+		f, err := os.Create(*out)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer f.Close()
+		w = f
+	}
+	if err := write(w, newReport(c, total), *format); err != nil {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,467 @@
+// Command gorillas-tournament plays a round-robin between computer players
+// and bot programs without any display and prints a crosstable and each
+// entrant's statistics:
+//
+//	go build -o gorillas-bot ./cmd/gorillas-bot
+//	go run ./cmd/gorillas-tournament -cities 10 ai:hard ai:normal ./gorillas-bot
+//
+// Every pair of entrants meets in the same -cities cities, each city built
+// from seed -seed+k with its wind fixed, and plays one round in each from
+// both sides, so neither gets the easier end of a skyline or the first
+// throw more often. An entrant is ai:easy, ai:normal, ai:hard or ai:perfect
+// for the built-in players, the last throwing FindShot's shot every time,
+// or else a bot command as for gorillas-sim's -player1-bot. A name may be
+// given first, as in mybot=./mybot -v. The decided rounds are added to the
+// league file -league, apart from the one the games keep.
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"github.com/arran4/gorillas"
+)
+
+// maxRoundThrows ends a round as a draw once it has gone on this long, such
+// as one where neither side can find a way over the city.
+const maxRoundThrows = 50
+
+// defaultLeagueFile keeps tournament results apart from the league the
+// frontends play into.
+const defaultLeagueFile = "gorillas-tournament.lge"
+
+// aiPrefix marks an entrant played by the built-in AI.
+const aiPrefix = "ai:"
+
+// config describes the tournament to play.
+type config struct {
+	Cities     int           `json:"cities"`
+	Seed       int64         `json:"seed"`
+	Gravity    float64       `json:"gravity"`
+	Buildings  int           `json:"buildings"`
+	Skyline    string        `json:"skyline"`
+	Entrants   []entrant     `json:"entrants"`
+	BotTimeout time.Duration `json:"-"`
+}
+
+// entrant is one player of the tournament: the built-in AI at level, or the
+// bot program Bot.
+type entrant struct {
+	Name  string `json:"name"`
+	Spec  string `json:"spec"`
+	Bot   string `json:"-"`
+	level gorillas.AILevel
+}
+
+// parseEntrant reads an entrant from the command line: an optional name and
+// =, then ai:level or a bot command. The name defaults to ai-level or the
+// bot program's file name.
+func parseEntrant(arg string) (entrant, error) {
+	e := entrant{Spec: strings.TrimSpace(arg)}
+	// the text before an = names the entrant unless it is already part of
+	// a command, such as a path or an argument
+	if name, spec, ok := strings.Cut(e.Spec, "="); ok && !strings.ContainsAny(name, " \t/\\") {
+		e.Name, e.Spec = strings.TrimSpace(name), strings.TrimSpace(spec)
+	}
+	if e.Spec == "" {
+		return e, fmt.Errorf("entrant %q: no player given", arg)
+	}
+	if level, ok := strings.CutPrefix(e.Spec, aiPrefix); ok {
+		l, err := gorillas.ParseAILevel(level)
+		if err != nil {
+			return e, fmt.Errorf("entrant %q: %w", arg, err)
+		}
+		e.level = l
+		if e.Name == "" {
+			e.Name = "ai-" + l.String()
+		}
+		return e, nil
+	}
+	e.Bot = e.Spec
+	if e.Name == "" {
+		e.Name = filepath.Base(strings.Fields(e.Spec)[0])
+	}
+	return e, nil
+}
+
+// pairing is one round of the tournament: entrants Seats[0] and Seats[1]
+// meet in city City, the first on the left and throwing first.
+type pairing struct {
+	City  int
+	Seats [2]int
+}
+
+// schedule lists every round of a round-robin between n entrants over
+// cities cities. Each pair plays each city twice, once from each side.
+func schedule(n, cities int) []pairing {
+	var out []pairing
+	for i := 0; i < n; i++ {
+		for j := i + 1; j < n; j++ {
+			for k := 0; k < cities; k++ {
+				out = append(out, pairing{City: k, Seats: [2]int{i, j}}, pairing{City: k, Seats: [2]int{j, i}})
+			}
+		}
+	}
+	return out
+}
+
+// seatStats counts what one seat did in a round.
+type seatStats struct {
+	Throws      int
+	Kills       int
+	SelfKills   int
+	BotFailures int
+}
+
+// result is how a round turned out. Winner is the winning seat, or -1 when
+// the round was stopped as a draw, and Shots the throws the winner took.
+type result struct {
+	Winner int
+	Shots  int
+	Seats  [2]seatStats
+}
+
+// newCity builds city k of the tournament and captures it, wind included,
+// as a map every pair plays on.
+func newCity(c config, k int) (*gorillas.CityMap, error) {
+	g := newGame(c, c.Seed+int64(k))
+	// SetPlayers raises a new city under the settings above
+	if err := g.SetPlayers(gorillas.PlayerNames(gorillas.MinPlayers)...); err != nil {
+		return nil, err
+	}
+	return g.CityMap(), nil
+}
+
+// newGame sets up a game played under the rules in c.
+func newGame(c config, seed int64) *gorillas.Game {
+	settings := gorillas.DefaultSettings()
+	settings.DefaultGravity = c.Gravity
+	settings.DefaultRoundQty = 1
+	settings.Skyline = c.Skyline
+	return gorillas.NewHeadlessGame(gorillas.WorldWidth, gorillas.WorldHeight, c.Buildings, seed, settings)
+}
+
+// play runs the round p in city and counts what each seat did.
+func play(c config, city *gorillas.CityMap, p pairing) (result, error) {
+	g := newGame(c, c.Seed+int64(p.City))
+	var names []string
+	for _, e := range p.Seats {
+		names = append(names, c.Entrants[e].Name)
+	}
+	if err := g.SetPlayers(names...); err != nil {
+		return result{}, err
+	}
+	if err := g.ApplyMap(city); err != nil {
+		return result{}, err
+	}
+	bots := gorillas.NewBots(g)
+	defer bots.Close()
+	for seat, e := range p.Seats {
+		if c.Entrants[e].Bot == "" {
+			continue
+		}
+		bot, err := gorillas.StartBot(c.Entrants[e].Bot, os.Stderr)
+		if err != nil {
+			return result{}, fmt.Errorf("%s: %w", c.Entrants[e].Name, err)
+		}
+		bot.Timeout = c.BotTimeout
+		bots.Seat(seat, bot)
+	}
+	r := result{Winner: -1}
+	throws := 0
+	g.Subscribe(gorillas.ListenerFunc(func(e gorillas.GameEvent) {
+		switch e.Kind {
+		case gorillas.ThrowStarted:
+			r.Seats[e.Player].Throws++
+			throws++
+		case gorillas.GorillaKilled:
+			r.Seats[e.Player].Kills++
+		case gorillas.SelfKill:
+			r.Seats[e.Player].SelfKills++
+		case gorillas.RoundOver:
+			r.Winner, r.Shots = e.Winner, e.Shots
+		}
+	}))
+	for !g.MatchOver() || g.Explosion.Active || g.Dance.Active {
+		if throws >= maxRoundThrows {
+			break
+		}
+		if !g.Banana.Active && !g.Explosion.Active && !g.Dance.Active {
+			if played, err := bots.Wait(); err != nil {
+				r.Seats[g.Current].BotFailures++
+				log.Printf("city %d: %v", p.City, err)
+			} else if !played {
+				g.Angle, g.Power = g.AIShot(c.Entrants[p.Seats[g.Current]].level)
+				g.Throw()
+			}
+		}
+		g.Step(gorillas.StepDuration)
+	}
+	return r, nil
+}
+
+// run plays every round in rounds across workers goroutines and returns the
+// results in the same order, so they do not depend on how many workers
+// share them.
+func run(c config, cities []*gorillas.CityMap, rounds []pairing, workers int) ([]result, error) {
+	results := make([]result, len(rounds))
+	jobs := make(chan int)
+	var (
+		mu       sync.Mutex
+		firstErr error
+		wg       sync.WaitGroup
+	)
+	failed := func() error {
+		mu.Lock()
+		defer mu.Unlock()
+		return firstErr
+	}
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				r, err := play(c, cities[rounds[i].City], rounds[i])
+				if err != nil {
+					mu.Lock()
+					if firstErr == nil {
+						firstErr = err
+					}
+					mu.Unlock()
+					continue
+				}
+				results[i] = r
+			}
+		}()
+	}
+	for i := range rounds {
+		if failed() != nil {
+			break
+		}
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+	if err := failed(); err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// standing is one entrant's record over the tournament. Score counts a won
+// round as one point and a drawn one as half.
+type standing struct {
+	Name        string  `json:"name"`
+	Spec        string  `json:"spec"`
+	Score       float64 `json:"score"`
+	Played      int     `json:"played"`
+	Won         int     `json:"won"`
+	Lost        int     `json:"lost"`
+	Drawn       int     `json:"drawn"`
+	Throws      int     `json:"throws"`
+	Kills       int     `json:"kills"`
+	SelfKills   int     `json:"selfKills"`
+	BotFailures int     `json:"botFailures"`
+	// ThrowsPerWin is the average number of throws a won round took.
+	ThrowsPerWin float64 `json:"throwsPerWin"`
+	winShots     int
+}
+
+// report is what gorillas-tournament prints. Crosstable[i][j] is the score
+// entrant i took off entrant j, in the order they were given, and Standings
+// the entrants from the highest score down.
+type report struct {
+	Config     config      `json:"config"`
+	Crosstable [][]float64 `json:"crosstable"`
+	Standings  []standing  `json:"standings"`
+}
+
+func newReport(c config, rounds []pairing, results []result) report {
+	n := len(c.Entrants)
+	r := report{Config: c, Crosstable: make([][]float64, n)}
+	for i := range r.Crosstable {
+		r.Crosstable[i] = make([]float64, n)
+	}
+	stands := make([]standing, n)
+	for i, e := range c.Entrants {
+		stands[i] = standing{Name: e.Name, Spec: e.Spec}
+	}
+	for k, res := range results {
+		p := rounds[k]
+		for seat, e := range p.Seats {
+			s, other := &stands[e], p.Seats[1-seat]
+			s.Played++
+			s.Throws += res.Seats[seat].Throws
+			s.Kills += res.Seats[seat].Kills
+			s.SelfKills += res.Seats[seat].SelfKills
+			s.BotFailures += res.Seats[seat].BotFailures
+			switch res.Winner {
+			case -1:
+				s.Drawn++
+				s.Score += 0.5
+				r.Crosstable[e][other] += 0.5
+			case seat:
+				s.Won++
+				s.Score++
+				s.winShots += res.Shots
+				r.Crosstable[e][other]++
+			default:
+				s.Lost++
+			}
+		}
+	}
+	for i := range stands {
+		if stands[i].Won > 0 {
+			stands[i].ThrowsPerWin = float64(stands[i].winShots) / float64(stands[i].Won)
+		}
+	}
+	// a stable sort keeps entrants on the same score in the order given
+	sort.SliceStable(stands, func(i, j int) bool { return stands[i].Score > stands[j].Score })
+	r.Standings = stands
+	return r
+}
+
+// record adds the decided rounds to league under the entrants' names. The
+// league has no draws, so drawn rounds are left out.
+func record(league *gorillas.League, c config, rounds []pairing, results []result) {
+	for k, res := range results {
+		if res.Winner < 0 {
+			continue
+		}
+		p := rounds[k]
+		league.RecordMatchRound([]string{c.Entrants[p.Seats[0]].Name, c.Entrants[p.Seats[1]].Name}, res.Winner, res.Shots)
+	}
+}
+
+func writeText(w io.Writer, r report) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprint(tw, "\tEntrant\t")
+	for i := range r.Crosstable {
+		fmt.Fprintf(tw, "%d\t", i+1)
+	}
+	fmt.Fprint(tw, "Score\t\n")
+	for i, row := range r.Crosstable {
+		fmt.Fprintf(tw, "%d\t%s\t", i+1, r.Config.Entrants[i].Name)
+		total := 0.0
+		for j, score := range row {
+			if i == j {
+				fmt.Fprint(tw, "-\t")
+				continue
+			}
+			fmt.Fprintf(tw, "%g\t", score)
+			total += score
+		}
+		fmt.Fprintf(tw, "%g\t\n", total)
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	fmt.Fprintln(w)
+	tw = tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprint(tw, "Rank\tEntrant\tPlayed\tWon\tLost\tDrawn\tScore\tThrows\tKills\tSelf\tFailures\tThrows/Win\t\n")
+	for i, s := range r.Standings {
+		fmt.Fprintf(tw, "%d\t%s\t%d\t%d\t%d\t%d\t%g\t%d\t%d\t%d\t%d\t%.1f\t\n",
+			i+1, s.Name, s.Played, s.Won, s.Lost, s.Drawn, s.Score, s.Throws, s.Kills, s.SelfKills, s.BotFailures, s.ThrowsPerWin)
+	}
+	return tw.Flush()
+}
+
+func write(w io.Writer, r report, format string) error {
+	switch format {
+	case "text":
+		return writeText(w, r)
+	case "json":
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(r)
+	}
+	return fmt.Errorf("unknown format %q", format)
+}
+
+func main() {
+	settings := gorillas.DefaultSettings()
+	c := config{}
+	flag.IntVar(&c.Cities, "cities", 5, "number of cities every pair plays in, once from each side")
+	flag.Int64Var(&c.Seed, "seed", 1, "seed of the first city; city k uses seed+k")
+	flag.Float64Var(&c.Gravity, "gravity", settings.DefaultGravity, "gravity")
+	flag.IntVar(&c.Buildings, "buildings", gorillas.DefaultBuildingCount, "building count")
+	flag.StringVar(&c.Skyline, "skyline", settings.Skyline, "skyline generator: "+strings.Join(gorillas.SkylineNames(), ", "))
+	flag.DurationVar(&c.BotTimeout, "bot-timeout", gorillas.DefaultBotTimeout, "how long a bot may think about a turn")
+	leagueFile := flag.String("league", defaultLeagueFile, "league file to add the results to, or empty for none")
+	format := flag.String("format", "text", "output format: text or json")
+	out := flag.String("o", "", "file to write the results to (default stdout)")
+	workers := flag.Int("workers", runtime.NumCPU(), "rounds to play at once")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "usage: %s [flags] entrant entrant...\n\nAn entrant is ai:easy, ai:normal, ai:hard, ai:perfect or a bot command,\noptionally named first as in name=./bot.\n\n", filepath.Base(os.Args[0]))
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if c.Cities <= 0 {
+		log.Fatal("-cities: need at least one city")
+	}
+	if _, err := gorillas.SkylineByName(c.Skyline); err != nil {
+		log.Fatalf("-skyline: %v", err)
+	}
+	if *format != "text" && *format != "json" {
+		log.Fatalf("-format: unknown format %q", *format)
+	}
+	if flag.NArg() < 2 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	names := map[string]bool{}
+	for _, arg := range flag.Args() {
+		e, err := parseEntrant(arg)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if names[e.Name] {
+			log.Fatalf("entrant %q: the name %s is taken; name it as in other=%s", arg, e.Name, e.Spec)
+		}
+		names[e.Name] = true
+		c.Entrants = append(c.Entrants, e)
+	}
+
+	cities := make([]*gorillas.CityMap, c.Cities)
+	for k := range cities {
+		city, err := newCity(c, k)
+		if err != nil {
+			log.Fatal(err)
+		}
+		cities[k] = city
+	}
+	rounds := schedule(len(c.Entrants), c.Cities)
+	results, err := run(c, cities, rounds, max(*workers, 1))
+	if err != nil {
+		log.Fatal(err)
+	}
+	if *leagueFile != "" {
+		league := gorillas.LoadLeague(*leagueFile)
+		record(league, c, rounds, results)
+		league.Save()
+	}
+	w := io.Writer(os.Stdout)
+	if *out != "" {
+		f, err := os.Create(*out)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer f.Close()
+		w = f
+	}
+	if err := write(w, newReport(c, rounds, results), *format); err != nil {
+		log.Fatal(err)
+	}
+}
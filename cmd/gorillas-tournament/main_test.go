@@ -0,0 +1,83 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"github.com/arran4/gorillas"
+)
+
+func TestScheduleReportAndRecord(t *testing.T) {
+	for _, tc := range []struct {
+		name             string
+		entrants, cities int
+		// winner picks the winning seat of round k, or -1 for a draw
+		winner func(k int) int
+	}{
+		{"first seat wins", 3, 2, func(int) int { return 0 }},
+		{"every round drawn", 2, 3, func(int) int { return -1 }},
+		{"won, lost and drawn", 4, 3, func(k int) int { return k%3 - 1 }},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			c := config{Cities: tc.cities}
+			for i := 0; i < tc.entrants; i++ {
+				c.Entrants = append(c.Entrants, entrant{Name: fmt.Sprintf("e%d", i)})
+			}
+			rounds := schedule(tc.entrants, tc.cities)
+			if want := tc.entrants * (tc.entrants - 1) * tc.cities; len(rounds) != want {
+				t.Fatalf("%d rounds scheduled, expected %d", len(rounds), want)
+			}
+			met := map[pairing]int{}
+			for _, p := range rounds {
+				met[p]++
+			}
+			for i := 0; i < tc.entrants; i++ {
+				for j := 0; j < tc.entrants; j++ {
+					for k := 0; k < tc.cities && i != j; k++ {
+						if n := met[pairing{City: k, Seats: [2]int{i, j}}]; n != 1 {
+							t.Fatalf("e%d meets e%d on the left in city %d %d times", i, j, k, n)
+						}
+					}
+				}
+			}
+
+			results := make([]result, len(rounds))
+			for k := range results {
+				results[k] = result{Winner: tc.winner(k), Shots: 3}
+			}
+			r := newReport(c, rounds, results)
+			index := map[string]int{}
+			for i, e := range c.Entrants {
+				index[e.Name] = i
+			}
+			for _, s := range r.Standings {
+				total := 0.0
+				for _, score := range r.Crosstable[index[s.Name]] {
+					total += score
+				}
+				if total != s.Score {
+					t.Fatalf("%s's crosstable row sums to %g, not their score %g", s.Name, total, s.Score)
+				}
+				if s.Won+s.Lost+s.Drawn != s.Played || s.Played != 2*(tc.entrants-1)*tc.cities {
+					t.Fatalf("%s: unexpected record %+v", s.Name, s)
+				}
+			}
+
+			league := gorillas.LoadLeague(filepath.Join(t.TempDir(), "tournament.lge"))
+			record(league, c, rounds, results)
+			for _, s := range r.Standings {
+				ps := league.Players[s.Name]
+				if s.Won+s.Lost == 0 {
+					if ps != nil {
+						t.Fatalf("%s drew every round yet is in the league: %+v", s.Name, ps)
+					}
+					continue
+				}
+				if ps == nil || ps.Rounds != s.Won+s.Lost || ps.Wins != s.Won {
+					t.Fatalf("%s won %d and lost %d, the league has %+v", s.Name, s.Won, s.Lost, ps)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,112 @@
+//go:build !test
+
+package main
+
+import (
+	"image/color"
+	"time"
+
+	"github.com/arran4/gorillas"
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/ebitenutil"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+
+	ebdraw "github.com/arran4/gorillas/drawings/ebiten"
+)
+
+// bestShotsState lists the highlight reel so a shot can be picked to watch.
+type bestShotsState struct {
+	sel     int
+	message string
+}
+
+func newBestShotsState(g *Game) *bestShotsState {
+	s := &bestShotsState{}
+	if len(g.Highlights) == 0 {
+		s.message = "No best shots yet - win a round to make the reel"
+	}
+	return s
+}
+
+func (s *bestShotsState) Update(g *Game) error {
+	for _, k := range inpututil.AppendJustPressedKeys(nil) {
+		switch k {
+		case ebiten.KeyEscape:
+			g.State = newMenuState(g.Settings.UseSound, g.Settings.UseSlidingText)
+		case ebiten.KeyUp:
+			if s.sel > 0 {
+				s.sel--
+			}
+		case ebiten.KeyDown:
+			if s.sel < len(g.Highlights)-1 {
+				s.sel++
+			}
+		case ebiten.KeyEnter, ebiten.KeySpace:
+			if len(g.Highlights) == 0 {
+				continue
+			}
+			r, err := gorillas.NewHighlightReplay(g.Highlights[s.sel])
+			if err != nil {
+				s.message = err.Error()
+				continue
+			}
+			g.State = newBestShotState(g, r)
+		}
+	}
+	return nil
+}
+
+func (s *bestShotsState) Draw(g *Game, screen *ebiten.Image) {
+	screen.Fill(color.RGBA{0, 0, 0, 255})
+	title := "BEST SHOTS"
+	ebitenutil.DebugPrintAt(screen, title, (g.Width-len(title)*charW)/2, 2*charH)
+	for i, h := range g.Highlights {
+		l := h.String()
+		if i == s.sel {
+			l = "> " + l
+		} else {
+			l = "  " + l
+		}
+		ebitenutil.DebugPrintAt(screen, l, 4*charW, (4+i)*charH)
+	}
+	if s.message != "" {
+		ebitenutil.DebugPrintAt(screen, s.message, (g.Width-len(s.message)*charW)/2, (5+len(g.Highlights))*charH)
+	}
+	help := "Up/Down choose, Enter watch, Esc back"
+	ebitenutil.DebugPrintAt(screen, help, (g.Width-len(help)*charW)/2, g.Height-2*charH)
+}
+
+// bestShotState throws a shot from the highlight reel again in slow motion
+// in place of the live game, handing the live game back when it lands or
+// Esc is pressed.
+type bestShotState struct {
+	replay *gorillas.ShotReplay
+	live   *gorillas.Game
+}
+
+func newBestShotState(g *Game, r *gorillas.ShotReplay) *bestShotState {
+	s := &bestShotState{replay: r, live: g.Game}
+	r.Game.Settings.UseSound = g.Settings.UseSound
+	r.Game.SetGorillaMask(g.GorillaMask)
+	g.Game = r.Game
+	g.initBuildings()
+	return s
+}
+
+func (s *bestShotState) Update(g *Game) error {
+	if inpututil.IsKeyJustPressed(ebiten.KeyEscape) || s.replay.Done() {
+		g.Game = s.live
+		g.initBuildings()
+		g.State = newBestShotsState(g)
+		return nil
+	}
+	s.replay.Step(time.Second / time.Duration(ebiten.TPS()))
+	return nil
+}
+
+func (s *bestShotState) Draw(g *Game, screen *ebiten.Image) {
+	playState{}.Draw(g, screen)
+	ebdraw.DrawVectorLines(screen, s.replay.Trail, color.RGBA{255, 255, 255, 255})
+	msg := "BEST SHOT - Esc to stop"
+	ebitenutil.DebugPrintAt(screen, msg, (g.Width-len(msg)*charW)/2, g.Height-charH)
+}
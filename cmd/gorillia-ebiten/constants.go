@@ -9,9 +9,8 @@ const (
 	charH = 16
 	// gorillaScale defines the base size of the gorilla sprite.
 	gorillaScale = 1
-	// bananaScale and sunScale remain fixed for the classic proportions.
+	// bananaScale remains fixed for the classic proportions.
 	bananaScale = 4
-	sunScale    = 2
 )
 
 // gorillaFrames represents the ASCII gorilla animation frames shared by
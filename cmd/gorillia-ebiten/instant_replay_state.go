@@ -0,0 +1,45 @@
+//go:build !test
+
+package main
+
+import (
+	"image/color"
+	"time"
+
+	"github.com/arran4/gorillas"
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/ebitenutil"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+
+	ebdraw "github.com/arran4/gorillas/drawings/ebiten"
+)
+
+// instantReplayState shows the throw that just won the round again in slow
+// motion, then returns to play so the live explosion can finish.
+type instantReplayState struct {
+	replay *gorillas.ShotReplay
+}
+
+func newInstantReplayState(r *gorillas.ShotReplay) *instantReplayState {
+	return &instantReplayState{replay: r}
+}
+
+func (s *instantReplayState) Update(g *Game) error {
+	if len(inpututil.AppendJustPressedKeys(nil)) > 0 || s.replay.Done() {
+		g.State = playState{}
+		return nil
+	}
+	s.replay.Step(time.Second / time.Duration(ebiten.TPS()))
+	return nil
+}
+
+func (s *instantReplayState) Draw(g *Game, screen *ebiten.Image) {
+	// draw the replay's copy of the city in place of the live one
+	live := g.Game
+	g.Game = s.replay.Game
+	playState{}.Draw(g, screen)
+	g.Game = live
+	ebdraw.DrawVectorLines(screen, s.replay.Trail, color.RGBA{255, 255, 255, 255})
+	msg := "INSTANT REPLAY - press any key to skip"
+	ebitenutil.DebugPrintAt(screen, msg, (g.Width-len(msg)*charW)/2, g.Height-charH)
+}
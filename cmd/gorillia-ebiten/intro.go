@@ -322,6 +322,12 @@ func showLeague(l *gorillas.League) error {
 	for _, s := range l.Standings() {
 		lines = append(lines, fmt.Sprintf("%-15s %6d %4d %8.1f", s.Name, s.Rounds, s.Wins, s.Accuracy))
 	}
+	if len(l.Teams) > 0 {
+		lines = append(lines, "", "Team             Rounds Wins Accuracy")
+		for _, s := range l.TeamStandings() {
+			lines = append(lines, fmt.Sprintf("%-15s %6d %4d %8.1f", s.Name, s.Rounds, s.Wins, s.Accuracy))
+		}
+	}
 	lines = append(lines, "", "Press any key to continue")
 	return SparklePause(lines, 0)
 }
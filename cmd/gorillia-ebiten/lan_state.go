@@ -0,0 +1,145 @@
+//go:build !test
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"image/color"
+
+	"github.com/arran4/gorillas"
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/ebitenutil"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+)
+
+// lanState lists the games hosted on the local network as their
+// announcements come and go, and joins the one picked.
+type lanState struct {
+	browser *gorillas.LANBrowser
+	sel     int
+	// message says why browsing or the last join failed.
+	message string
+	// joining is the address being joined, cancel gives up and joined
+	// brings the outcome.
+	joining string
+	cancel  context.CancelFunc
+	joined  chan lanJoin
+}
+
+// lanJoin is how joining a game turned out. The game is set up apart from
+// the one on screen, which is left alone unless the join succeeds.
+type lanJoin struct {
+	game    *gorillas.Game
+	session *gorillas.NetSession
+	err     error
+}
+
+func newLANState() *lanState {
+	s := &lanState{}
+	b, err := gorillas.BrowseLAN(fmt.Sprintf(":%d", gorillas.LANPort))
+	if err != nil {
+		s.message = err.Error()
+	}
+	s.browser = b
+	return s
+}
+
+func (s *lanState) games() []gorillas.LANGame {
+	if s.browser == nil {
+		return nil
+	}
+	return s.browser.Games()
+}
+
+func (s *lanState) Update(g *Game) error {
+	if s.joined != nil {
+		select {
+		case r := <-s.joined:
+			s.cancel()
+			s.joined = nil
+			if r.err != nil {
+				s.message = r.err.Error()
+				return nil
+			}
+			s.browser.Close()
+			g.takeOver(r.game, r.session)
+			g.State = playState{}
+		default:
+			if inpututil.IsKeyJustPressed(ebiten.KeyEscape) {
+				s.cancel()
+				if r := <-s.joined; r.err == nil {
+					// the host let us in just before
+					r.session.Close()
+				} else if !errors.Is(r.err, context.Canceled) {
+					s.message = r.err.Error()
+				}
+				s.joined = nil
+			}
+		}
+		return nil
+	}
+	games := s.games()
+	s.sel = max(min(s.sel, len(games)-1), 0)
+	for _, k := range inpututil.AppendJustPressedKeys(nil) {
+		switch k {
+		case ebiten.KeyEscape:
+			if s.browser != nil {
+				s.browser.Close()
+			}
+			g.State = newMenuState(g.Settings.UseSound, g.Settings.UseSlidingText)
+		case ebiten.KeyUp:
+			if s.sel > 0 {
+				s.sel--
+			}
+		case ebiten.KeyDown:
+			if s.sel < len(games)-1 {
+				s.sel++
+			}
+		case ebiten.KeyEnter, ebiten.KeySpace:
+			if len(games) == 0 {
+				continue
+			}
+			var ctx context.Context
+			ctx, s.cancel = context.WithCancel(context.Background())
+			s.joining = games[s.sel].Addr
+			s.joined = make(chan lanJoin, 1)
+			joined := g.joinable()
+			go func(addr string) {
+				session, err := gorillas.JoinNet(ctx, addr, joined)
+				s.joined <- lanJoin{joined, session, err}
+			}(s.joining)
+			return nil
+		}
+	}
+	return nil
+}
+
+func (s *lanState) Draw(g *Game, screen *ebiten.Image) {
+	screen.Fill(color.RGBA{0, 0, 0, 255})
+	title := "LAN GAMES"
+	ebitenutil.DebugPrintAt(screen, title, (g.Width-len(title)*charW)/2, 2*charH)
+	games := s.games()
+	for i, game := range games {
+		l := "  " + game.String()
+		if i == s.sel {
+			l = "> " + game.String()
+		}
+		ebitenutil.DebugPrintAt(screen, l, 4*charW, (4+i)*charH)
+	}
+	msg := s.message
+	if s.joined != nil {
+		msg = "Joining " + s.joining + "..."
+	} else if msg == "" && len(games) == 0 {
+		msg = "Looking for games on the local network..."
+	}
+	if msg != "" {
+		ebitenutil.DebugPrintAt(screen, msg, (g.Width-len(msg)*charW)/2, (5+len(games))*charH)
+	}
+	help := "Up/Down choose, Enter join, Esc back"
+	if s.joined != nil {
+		help = "Esc give up"
+	}
+	ebitenutil.DebugPrintAt(screen, help, (g.Width-len(help)*charW)/2, g.Height-2*charH)
+}
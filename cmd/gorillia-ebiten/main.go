@@ -11,18 +11,18 @@ import (
 	"math"
 	"math/rand"
 	"os"
+	"strings"
 	"time"
 
 	"github.com/arran4/gorillas"
 	ebdraw "github.com/arran4/gorillas/drawings/ebiten"
 	imgdraw "github.com/arran4/gorillas/drawings/img"
+	_ "github.com/arran4/gorillas/sound"
 	"github.com/hajimehoshi/ebiten/v2"
 	"github.com/hajimehoshi/ebiten/v2/ebitenutil"
 )
 
 const (
-	sunRadius          = 20 * sunScale
-	sunMaxIntegrity    = 4
 	digitBufferTimeout = 3 * time.Second
 )
 
@@ -31,15 +31,14 @@ func drawVectorLines(img *ebiten.Image, pts []gorillas.VectorPoint, clr color.Co
 }
 
 func (g *Game) drawSun(img *ebiten.Image) {
-	if g.sunIntegrity <= 0 {
+	if g.Sun.Integrity <= 0 {
 		return
 	}
 	clr := color.RGBA{255, 255, 0, 255}
 	if g.sunHitTicks > 0 {
 		clr = color.RGBA{255, 100, 100, 255}
 	}
-	r := float64(g.sunIntegrity) * sunRadius / sunMaxIntegrity
-	ebdraw.DrawBASSun(img, g.sunX, g.sunY, r, g.sunHitTicks > 0, clr)
+	ebdraw.DrawBASSun(img, g.Sun.X, g.Sun.Y, g.Sun.Radius(), g.sunHitTicks > 0, clr)
 }
 
 type Game struct {
@@ -47,9 +46,7 @@ type Game struct {
 	gamepads     []ebiten.GamepadID
 	buildingBase []*ebiten.Image
 	buildingImg  []*ebiten.Image
-	sunX, sunY   float64
 	sunHitTicks  int
-	sunIntegrity int
 	angleInput   string
 	powerInput   string
 	enteringAng  bool
@@ -65,36 +62,72 @@ type Game struct {
 	AI           bool
 	State        State
 	lastDigit    time.Time
+	// decor drives cosmetic randomness such as building colours so it is
+	// reproducible from the seed without disturbing the game's own stream.
+	decor *rand.Rand
+	// wind is the wind given with -wind, NaN when the game rolls its own.
+	wind float64
 	// Closed indicates whether the window was closed by the user.
 	Closed bool
+	// bots plays the seats given to bot programs, which seated keeps for
+	// a game joined from the LAN menu.
+	bots   *gorillas.Bots
+	seated map[int]*gorillas.Bot
+	// net is the other side of a network game, if this is one.
+	net *gorillas.NetSession
+	// spectators streams the game to anyone watching it on spectateAddr,
+	// and watching follows a game streamed from elsewhere along with why it
+	// stopped.
+	spectators   *gorillas.SpectatorServer
+	spectateAddr string
+	watching     *gorillas.Spectator
+	watchErr     error
+}
+
+// holdWind puts back the wind given with -wind, which seating the players
+// or laying a map out rolls afresh. A map's own wind comes first.
+func (g *Game) holdWind() {
+	if !math.IsNaN(g.wind) && (g.Map == nil || g.Map.Wind == nil) {
+		g.Game.Wind = g.wind
+	}
 }
 
 func (g *Game) initBuildings() {
 	g.buildingBase = g.buildingBase[:0]
 	g.buildingImg = g.buildingImg[:0]
-	bw := float64(g.Width) / float64(g.Game.BuildingCount)
-	for i := 0; i < g.Game.BuildingCount; i++ {
+	for i := range g.Buildings {
 		h := g.Buildings[i].H
+		// leave a one pixel gap between neighbouring buildings
+		w := math.Max(g.Buildings[i].W-1, 1)
 		// If building has color set, use it. Otherwise generate random and save it.
 		if g.Buildings[i].Color.A != 0 {
 			// already set
 		} else {
-			g.Buildings[i].Color = color.RGBA{uint8(rand.Intn(200)), uint8(rand.Intn(200)), uint8(rand.Intn(200)), 255}
+			g.Buildings[i].Color = color.RGBA{uint8(g.decor.Intn(200)), uint8(g.decor.Intn(200)), uint8(g.decor.Intn(200)), 255}
+		}
+		var base *ebiten.Image
+		if g.Buildings[i].Windows != nil {
+			base = ebdraw.CreateMapBuildingSprite(w, h, g.Buildings[i].Color, g.Buildings[i].Windows)
+		} else {
+			base = ebdraw.CreateBuildingSprite(w, h, g.Buildings[i].Color, g.decor)
 		}
-		base := ebdraw.CreateBuildingSprite(bw-1, h, g.Buildings[i].Color)
 		g.buildingBase = append(g.buildingBase, base)
-		img := ebiten.NewImage(int(bw-1), int(h))
+		img := ebiten.NewImage(int(w), int(h))
 		g.buildingImg = append(g.buildingImg, img)
 	}
 }
 
-func newGame(settings gorillas.Settings, buildings int, wind float64) *Game {
-	g := &Game{Game: gorillas.NewGame(800, 600, buildings)}
-	g.selAngle = true
-	if !math.IsNaN(wind) {
-		g.Game.Wind = wind
+func newGame(settings gorillas.Settings, buildings int, wind float64, seed int64) *Game {
+	if seed == 0 {
+		seed = time.Now().UnixNano()
 	}
+	g := &Game{Game: gorillas.NewGameWithSeed(gorillas.WorldWidth, gorillas.WorldHeight, buildings, seed)}
+	g.decor = rand.New(rand.NewSource(seed))
+	g.selAngle = true
+	g.wind = wind
+	g.holdWind()
 	g.Game.Settings = settings
+	g.StartMatch()
 	if art, err := gorillas.LoadGorillaArt("assets/gorilla.txt"); err == nil {
 		g.gorillaArt = art
 	} else {
@@ -102,30 +135,34 @@ func newGame(settings gorillas.Settings, buildings int, wind float64) *Game {
 	}
 	gorillaBase := imgdraw.DefaultGorillaSprite(gorillaScale)
 	g.gorillaImg = ebiten.NewImageFromImage(gorillaBase)
-	if g.Game.HitMap != nil {
-		for i, gr := range g.Game.Gorillas {
-			g.Game.HitMap.ClearGorilla(int(gr.X), int(gr.Y), i, 4)
-			g.Game.HitMap.DrawGorillaImage(int(gr.X), int(gr.Y), i, gorillaBase)
-		}
-	}
-	g.LoadScores()
-	rand.Seed(time.Now().UnixNano())
+	g.SetGorillaMask(gorillaBase)
+	g.follow()
 
 	g.initBuildings()
 
-	// centre the sun horizontally
-	g.sunX = float64(g.Width) / 2
-	g.sunY = 40
-	g.sunIntegrity = sunMaxIntegrity
-	g.Game.ResetHook = func() {
-		g.sunIntegrity = sunMaxIntegrity
-		g.initBuildings()
-	}
 	g.bananaLeft, g.bananaRight, g.bananaUp, g.bananaDown = ebdraw.CreateBananaSprites()
 	g.gamepads = ebiten.AppendGamepadIDs(nil)
 	return g
 }
 
+// follow loads the scores and shots of the game the window plays and
+// listens to it, laying the city out again whenever it is reset.
+func (g *Game) follow() {
+	g.LoadScores()
+	g.LoadShots()
+	g.Subscribe(gorillas.ListenerFunc(func(e gorillas.GameEvent) {
+		if e.Kind == gorillas.SunHit {
+			g.sunHitTicks = 10
+		}
+		if e.Kind == gorillas.RoundOver && g.Settings.InstantReplay {
+			if r := g.Game.InstantReplay(); r != nil {
+				g.State = newInstantReplayState(r)
+			}
+		}
+	}))
+	g.Game.ResetHook = g.initBuildings
+}
+
 func (g *Game) Update() error {
 	if ebiten.IsWindowBeingClosed() {
 		g.Closed = true
@@ -210,9 +247,27 @@ func main() {
 	buildings := flag.Int("buildings", gorillas.DefaultBuildingCount, "building count")
 	p1 := flag.String("player1", "Player 1", "name of player 1")
 	p2 := flag.String("player2", "Player 2", "name of player 2")
-	ai := flag.Bool("ai", false, "enable computer opponent")
+	players := flag.Int("players", gorillas.MinPlayers, "number of gorillas (2-8)")
+	names := flag.String("names", "", "comma separated player names, overriding -player1 and -player2")
+	teams := flag.String("teams", "", "comma separated team for each player, e.g. 1,2,1,2")
+	friendlyFire := flag.String("friendlyfire", settings.FriendlyFire.String(), "hits on teammates: self, off or ignored")
+	skyline := flag.String("skyline", settings.Skyline, "skyline generator: "+strings.Join(gorillas.SkylineNames(), ", "))
+	ai := flag.Bool("ai", false, "computer plays every gorilla but the first")
+	aiLevel := flag.String("ai-level", settings.AILevel.String(), "how well the computer aims: easy, normal, hard or perfect")
+	p1Bot := flag.String("player1-bot", "", "bot program that plays player 1, e.g. ./mybot")
+	p2Bot := flag.String("player2-bot", "", "bot program that plays player 2")
+	botTimeout := flag.Duration("bot-timeout", gorillas.DefaultBotTimeout, "how long a bot may think about a turn")
+	seed := flag.Int64("seed", 0, "random seed for the match (0 picks one)")
 	flag.BoolVar(&settings.UseSound, "sound", settings.UseSound, "enable sound")
 	flag.BoolVar(&settings.WinnerFirst, "winnerfirst", settings.WinnerFirst, "winner starts next round")
+	flag.BoolVar(&settings.InstantReplay, "instantreplay", settings.InstantReplay, "replay each round's winning throw in slow motion")
+	matchMode := flag.String("match", settings.MatchMode.String(), "match mode: bestof or firstto")
+	mapFile := flag.String("map", "", "JSON city map to play on instead of a random city")
+	replayDir := flag.String("replays", gorillas.DefaultReplayDir, "directory finished matches are recorded to (empty to disable)")
+	hostAddr := flag.String("host", "", "host a network game on this address, e.g. :4646")
+	joinAddr := flag.String("join", "", "join the network game at this address, e.g. example.com:4646")
+	spectators := flag.String("spectators", "", "let spectators watch the game on this address, e.g. :4648")
+	watchAddr := flag.String("watch", "", "watch the game streamed at this address, e.g. example.com:4648")
 
 	renderState := flag.String("render-state", "", "path to json state file to render")
 	outputImage := flag.String("output-image", "", "path to output rendered image (png)")
@@ -236,7 +291,7 @@ func main() {
 		}
 
 		// Initialize the main.Game wrapper
-		game := newGame(loadedGame.Settings, loadedGame.BuildingCount, loadedGame.Wind)
+		game := newGame(loadedGame.Settings, loadedGame.BuildingCount, loadedGame.Wind, loadedGame.Seed)
 
 		// Overwrite the core game state with loaded state
 		game.Game = &loadedGame
@@ -270,26 +325,151 @@ func main() {
 
 	settings.DefaultGravity = *gravity
 	settings.DefaultRoundQty = *rounds
-	game := newGame(settings, *buildings, *wind)
+	if m, err := gorillas.ParseMatchMode(*matchMode); err == nil {
+		settings.MatchMode = m
+	} else {
+		fmt.Fprintf(os.Stderr, "-match: %v\n", err)
+		os.Exit(1)
+	}
+	if _, err := gorillas.SkylineByName(*skyline); err == nil {
+		settings.Skyline = *skyline
+	} else {
+		fmt.Fprintf(os.Stderr, "-skyline: %v\n", err)
+		os.Exit(1)
+	}
+	if f, err := gorillas.ParseFriendlyFire(*friendlyFire); err == nil {
+		settings.FriendlyFire = f
+	} else {
+		fmt.Fprintf(os.Stderr, "-friendlyfire: %v\n", err)
+		os.Exit(1)
+	}
+	if l, err := gorillas.ParseAILevel(*aiLevel); err == nil {
+		settings.AILevel = l
+	} else {
+		fmt.Fprintf(os.Stderr, "-ai-level: %v\n", err)
+		os.Exit(1)
+	}
+	game := newGame(settings, *buildings, *wind, *seed)
 	game.AI = *ai
-	game.Players = [2]string{*p1, *p2}
-	if settings.ShowIntro {
+	game.ReplayDir = *replayDir
+	list := []string{*p1, *p2}
+	if *names != "" {
+		list = strings.Split(*names, ",")
+		*players = max(*players, len(list))
+	}
+	netGame := *hostAddr != "" || *joinAddr != ""
+	if *hostAddr != "" && *joinAddr != "" {
+		fmt.Fprintln(os.Stderr, "-host and -join: pick one")
+		os.Exit(1)
+	}
+	if netGame && (*players != gorillas.MinPlayers || *teams != "") {
+		fmt.Fprintln(os.Stderr, "-host and -join: network games are for two players without teams")
+		os.Exit(1)
+	}
+	if *watchAddr != "" && (netGame || *spectators != "") {
+		fmt.Fprintln(os.Stderr, "-watch: a spectator cannot also -host, -join or serve -spectators")
+		os.Exit(1)
+	}
+	if err := game.SetPlayers(gorillas.PlayerNames(*players, list...)...); err != nil {
+		fmt.Fprintf(os.Stderr, "-players: %v\n", err)
+		os.Exit(1)
+	}
+	labels, err := gorillas.ParseTeams(*teams)
+	if err == nil {
+		err = game.SetTeams(labels...)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "-teams: %v\n", err)
+		os.Exit(1)
+	}
+	if *mapFile != "" {
+		m, err := gorillas.LoadMap(*mapFile)
+		if err == nil {
+			err = game.ApplyMap(m)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "-map: %v\n", err)
+			os.Exit(1)
+		}
+	}
+	game.holdWind()
+	game.bots = gorillas.NewBots(game.Game)
+	game.seated = map[int]*gorillas.Bot{}
+	defer game.bots.Close()
+	for i, command := range []string{*p1Bot, *p2Bot} {
+		if command == "" {
+			continue
+		}
+		bot, err := gorillas.StartBot(command, os.Stderr)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "-player%d-bot: %v\n", i+1, err)
+			os.Exit(1)
+		}
+		bot.Timeout = *botTimeout
+		game.bots.Seat(i, bot)
+		game.seated[i] = bot
+	}
+	if netGame {
+		ok, err := game.connect(*hostAddr, *joinAddr)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "-host and -join: %v\n", err)
+			os.Exit(1)
+		}
+		if !ok {
+			return
+		}
+		// the host's flags settle a network game, so straight to play
+		game.State = playState{}
+	} else if *watchAddr != "" {
+		ok, err := game.watch(*watchAddr)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "-watch: %v\n", err)
+			os.Exit(1)
+		}
+		if !ok {
+			return
+		}
+		game.State = playState{}
+	} else if settings.ShowIntro {
 		game.State = newIntroMovieState(settings.UseSound, settings.UseSlidingText)
 	} else {
 		game.State = newMenuState(settings.UseSound, settings.UseSlidingText)
 	}
-	winsBackup := game.TotalWins
-	var playersBackup map[string]*gorillas.PlayerStats
+	if *spectators != "" {
+		if err := game.serveSpectators(*spectators); err != nil {
+			fmt.Fprintf(os.Stderr, "-spectators: %v\n", err)
+			os.Exit(1)
+		}
+	}
+	winsBackup := append([]int(nil), game.TotalWins...)
+	var playersBackup, teamsBackup map[string]*gorillas.PlayerStats
 	if game.League != nil {
 		playersBackup = make(map[string]*gorillas.PlayerStats, len(game.League.Players))
 		for n, ps := range game.League.Players {
 			cp := *ps
 			playersBackup[n] = &cp
 		}
+		teamsBackup = make(map[string]*gorillas.PlayerStats, len(game.League.Teams))
+		for n, ts := range game.League.Teams {
+			cp := *ts
+			teamsBackup[n] = &cp
+		}
 	}
 	if err := ebiten.RunGame(game); err != nil {
 		panic(fmt.Errorf("run game: %w", err))
 	}
+	if game.net != nil {
+		// let the other side know now rather than after the closing screens
+		game.net.Close()
+	}
+	if game.spectators != nil {
+		game.spectators.Close()
+	}
+	if game.watching != nil {
+		// the scores were the watched game's to keep
+		game.watching.Close()
+		return
+	}
 	if game.Closed {
 		return
 	}
@@ -297,6 +477,7 @@ func main() {
 		game.TotalWins = winsBackup
 		if game.League != nil {
 			game.League.Players = playersBackup
+			game.League.Teams = teamsBackup
 			game.League.Save()
 		}
 		if err := SparklePause([]string{"Game aborted"}, 0); err != nil {
@@ -305,7 +486,11 @@ func main() {
 		return
 	}
 	game.SaveScores()
-	if err := showStats(game.StatsString()); err != nil {
+	stats := game.StatsString()
+	if game.net != nil && game.net.Err() != nil {
+		stats += "\n" + game.net.Err().Error()
+	}
+	if err := showStats(stats); err != nil {
 		panic(fmt.Errorf("show stats: %w", err))
 	}
 	if game.League != nil {
@@ -314,5 +499,6 @@ func main() {
 		}
 	}
 	fmt.Println(game.StatsString())
+	fmt.Printf("Seed: %d\n", game.Seed)
 	showExtro()
 }
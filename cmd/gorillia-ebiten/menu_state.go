@@ -53,6 +53,15 @@ func (m *menuState) Update(g *Game) error {
 			case ebiten.KeyI:
 				g.State = newInstructionsState(m.sliding)
 				return nil
+			case ebiten.KeyJ:
+				g.State = newLANState()
+				return nil
+			case ebiten.KeyR:
+				g.State = newReplaysState(g)
+				return nil
+			case ebiten.KeyS:
+				g.State = newBestShotsState(g)
+				return nil
 			}
 		}
 	}
@@ -85,7 +94,13 @@ func (m *menuState) Draw(g *Game, screen *ebiten.Image) {
 		ebitenutil.DebugPrintAt(screen, line, (g.Width-len(line)*charW)/2, cy+4*charH)
 		line = "P/Start - Play Game"
 		ebitenutil.DebugPrintAt(screen, line, (g.Width-len(line)*charW)/2, cy+5*charH)
-		line = "Q/B - Quit"
+		line = "J - Join LAN Game"
 		ebitenutil.DebugPrintAt(screen, line, (g.Width-len(line)*charW)/2, cy+6*charH)
+		line = "R - Replays"
+		ebitenutil.DebugPrintAt(screen, line, (g.Width-len(line)*charW)/2, cy+7*charH)
+		line = "S - Best Shots"
+		ebitenutil.DebugPrintAt(screen, line, (g.Width-len(line)*charW)/2, cy+8*charH)
+		line = "Q/B - Quit"
+		ebitenutil.DebugPrintAt(screen, line, (g.Width-len(line)*charW)/2, cy+9*charH)
 	}
 }
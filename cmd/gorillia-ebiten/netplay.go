@@ -0,0 +1,128 @@
+//go:build !test
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"net"
+	"os"
+	"os/signal"
+
+	"github.com/arran4/gorillas"
+)
+
+// connect hosts a network game on host or joins the one at join before the
+// window opens, saying on the terminal what it is waiting for until the
+// other side answers or an interrupt gives up. It reports false when the
+// player gave up.
+func (g *Game) connect(host, join string) (bool, error) {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	var err error
+	if host != "" {
+		l, lerr := net.Listen("tcp", host)
+		if lerr != nil {
+			return false, lerr
+		}
+		defer l.Close()
+		fmt.Printf("Waiting for a player to join on %s...\n", l.Addr())
+		// and let players on the local network find the game
+		go gorillas.AdvertiseLAN(ctx, gorillas.LANBroadcast, gorillas.NewLANGame(g.Game, l))
+		g.net, err = gorillas.HostNet(ctx, l, g.Game)
+	} else {
+		fmt.Printf("Joining %s...\n", join)
+		g.net, err = gorillas.JoinNet(ctx, join, g.Game)
+	}
+	if ctx.Err() != nil {
+		if err == nil {
+			// the other side got in just before
+			g.net.Close()
+		}
+		g.net = nil
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	g.startNet(g.net)
+	return true, nil
+}
+
+// startNet plays the network game set up in session.
+func (g *Game) startNet(session *gorillas.NetSession) {
+	g.net = session
+	// lay the windows out afresh for the agreed city
+	g.decor = rand.New(rand.NewSource(g.Seed))
+	g.initBuildings()
+}
+
+// joinable returns a game like the one the window plays, for JoinNet to set
+// up as the host says while the window goes on with its own.
+func (g *Game) joinable() *gorillas.Game {
+	joined := gorillas.NewGameWithSeed(g.Width, g.Height, g.BuildingCount, g.Seed)
+	joined.Settings = g.Settings
+	joined.League = g.League
+	joined.ReplayDir = g.ReplayDir
+	joined.SetGorillaMask(g.GorillaMask)
+	// JoinNet introduces us by the first player's name
+	joined.Players[0] = g.Players[0]
+	return joined
+}
+
+// takeOver plays joined, a game set up in session away from the window's
+// goroutine, in place of the one the window has played so far. The bots
+// and spectators move on to it.
+func (g *Game) takeOver(joined *gorillas.Game, session *gorillas.NetSession) {
+	g.Game = joined
+	g.follow()
+	if g.bots != nil {
+		g.bots = gorillas.NewBots(g.Game)
+		for i, bot := range g.seated {
+			g.bots.Seat(i, bot)
+		}
+	}
+	if g.spectators != nil {
+		g.spectators.Close()
+		g.spectators = nil
+		if err := g.serveSpectators(g.spectateAddr); err != nil {
+			fmt.Fprintf(os.Stderr, "spectators: %v\n", err)
+		}
+	}
+	g.startNet(session)
+}
+
+// watch follows the game streamed at addr as a spectator, saying on the
+// terminal what it is waiting for until the game answers or an interrupt
+// gives up. It reports false when the player gave up.
+func (g *Game) watch(addr string) (bool, error) {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	fmt.Printf("Connecting to %s...\n", addr)
+	spectator, err := gorillas.WatchGame(ctx, addr, g.Game)
+	if ctx.Err() != nil {
+		if err == nil {
+			spectator.Close()
+		}
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	g.watching = spectator
+	g.decor = rand.New(rand.NewSource(g.Seed))
+	g.initBuildings()
+	return true, nil
+}
+
+// serveSpectators streams the game to spectators connecting on addr.
+func (g *Game) serveSpectators(addr string) error {
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	g.spectators = gorillas.ServeSpectators(l, g.Game)
+	g.spectateAddr = addr
+	return nil
+}
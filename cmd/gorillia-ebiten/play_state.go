@@ -35,6 +35,11 @@ func (playState) Update(g *Game) error {
 		}
 	}
 
+	if g.MatchOver() && !g.Banana.Active && !g.Explosion.Active {
+		g.State = newScoreState(g.MatchSummary() + "\n\n" + g.StatsString())
+		return nil
+	}
+
 	if g.abortPrompt {
 		for _, k := range inpututil.AppendJustPressedKeys(nil) {
 			switch k {
@@ -49,7 +54,38 @@ func (playState) Update(g *Game) error {
 		return nil
 	}
 	if !g.Banana.Active && !g.Explosion.Active {
-		if g.AI && g.Current == 1 {
+		if g.spectators != nil {
+			g.spectators.Update()
+		}
+		if g.watching != nil {
+			g.watchErr = g.watching.Poll()
+			if !g.Banana.Active && inpututil.IsKeyJustPressed(ebiten.KeyEscape) {
+				return ebiten.Termination
+			}
+			return nil
+		}
+		if g.net != nil {
+			played, err := g.net.Poll()
+			if err != nil {
+				fmt.Fprintln(os.Stderr, err)
+			}
+			if played {
+				// while the other side thinks, only Escape works
+				if !g.Banana.Active && err == nil && inpututil.IsKeyJustPressed(ebiten.KeyEscape) {
+					g.abortPrompt = true
+				}
+				return nil
+			}
+		}
+		if g.bots != nil {
+			if played, err := g.bots.Poll(); played {
+				if err != nil {
+					fmt.Fprintln(os.Stderr, err)
+				}
+				return nil
+			}
+		}
+		if g.AI && g.Current != 0 {
 			g.Game.AutoShot()
 			return nil
 		}
@@ -262,17 +298,7 @@ func (playState) Update(g *Game) error {
 			}
 		}
 	} else {
-		g.Step()
-		if g.Banana.Active && g.sunIntegrity > 0 {
-			r := float64(g.sunIntegrity) * sunRadius / sunMaxIntegrity
-			if g.Banana.X >= g.sunX-r && g.Banana.X <= g.sunX+r &&
-				g.Banana.Y >= g.sunY-r && g.Banana.Y <= g.sunY+r {
-				g.sunHitTicks = 10
-				if g.sunIntegrity > 0 {
-					g.sunIntegrity--
-				}
-			}
-		}
+		g.Step(time.Second / time.Duration(ebiten.TPS()))
 	}
 	if g.sunHitTicks > 0 {
 		g.sunHitTicks--
@@ -282,24 +308,24 @@ func (playState) Update(g *Game) error {
 
 func (playState) Draw(g *Game, screen *ebiten.Image) {
 	screen.Fill(color.RGBA{0, 0, 255, 255})
-	bw := float64(g.Width) / float64(g.Game.BuildingCount)
-	for i := 0; i < g.Game.BuildingCount; i++ {
-		h := g.Buildings[i].H
-		intH := int(h)
+	for i, b := range g.Buildings {
+		intH := int(b.H)
 		img := g.buildingImg[i]
 		img.Fill(color.RGBA{})
 		img.DrawImage(g.buildingBase[i], nil)
-		for _, d := range g.Buildings[i].Damage {
-			rx := int(d.X - float64(i)*bw)
+		for _, d := range b.Damage {
+			rx := int(d.X - b.X)
 			ry := int(d.Y - float64(g.Height-intH))
 			ebdraw.ClearCircle(img, rx, ry, d.R)
 		}
 		op := &ebiten.DrawImageOptions{}
-		op.GeoM.Translate(float64(i)*bw, float64(g.Height-intH))
+		op.GeoM.Translate(b.X, float64(g.Height-intH))
 		screen.DrawImage(img, op)
 	}
-	for i := range g.Gorillas {
-		g.drawGorilla(screen, i)
+	for i, gr := range g.Gorillas {
+		if !gr.Dead {
+			g.drawGorilla(screen, i)
+		}
 	}
 	if g.Banana.Active {
 		dir := 0
@@ -370,10 +396,15 @@ func (playState) Draw(g *Game, screen *ebiten.Image) {
 	} else {
 		powerStr = "[" + powerStr + "]"
 	}
-	info := fmt.Sprintf("Player %d (%s) - Angle:%s° Power:%s Wind:%+2.0f Score:%d-%d",
-		g.Current+1, g.Players[g.Current], angleStr, powerStr, g.Wind, g.Wins[0], g.Wins[1])
+	name := g.Players[g.Current]
+	if g.Teams != nil {
+		name += fmt.Sprintf(", Team %d", g.Side(g.Current)+1)
+	}
+	info := fmt.Sprintf("Player %d (%s) - Angle:%s° Power:%s Wind:%+2.0f Score:%s",
+		g.Current+1, name, angleStr, powerStr, g.Wind, g.ScoreString())
 	x := 0
-	if g.Current == 1 {
+	// players on the right of the city read their status on the right
+	if g.Gorillas[g.Current].X > float64(g.Width)/2 {
 		x = g.Width - len(info)*charW
 		if x < 0 {
 			x = 0
@@ -391,4 +422,17 @@ func (playState) Draw(g *Game, screen *ebiten.Image) {
 		y := g.Height / 3
 		ebitenutil.DebugPrintAt(screen, msg, x, y)
 	}
+	if g.net != nil && !g.abortPrompt {
+		msg := g.net.Status()
+		x := (g.Width - len(msg)*charW) / 2
+		ebitenutil.DebugPrintAt(screen, msg, x, charH)
+	}
+	if g.watching != nil {
+		msg := "Watching - press Esc to leave"
+		if g.watchErr != nil {
+			msg = "Watching: " + g.watchErr.Error() + " - press Esc to leave"
+		}
+		x := (g.Width - len(msg)*charW) / 2
+		ebitenutil.DebugPrintAt(screen, msg, x, charH)
+	}
 }
@@ -0,0 +1,131 @@
+//go:build !test
+
+package main
+
+import (
+	"fmt"
+	"image/color"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/arran4/gorillas"
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/ebitenutil"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+)
+
+// replaysState lists the recorded matches so one can be picked to watch.
+type replaysState struct {
+	paths   []string
+	labels  []string
+	sel     int
+	message string
+}
+
+func newReplaysState(g *Game) *replaysState {
+	s := &replaysState{}
+	paths, err := gorillas.ListReplays(g.ReplayDir)
+	if err != nil {
+		s.message = err.Error()
+	}
+	for _, p := range paths {
+		label := strings.TrimSuffix(filepath.Base(p), ".json")
+		if f, err := gorillas.LoadReplay(p); err == nil && f.Summary != "" {
+			label += "  " + f.Summary
+		}
+		s.paths = append(s.paths, p)
+		s.labels = append(s.labels, label)
+	}
+	if len(s.paths) == 0 && s.message == "" {
+		s.message = "No replays yet - finish a match to record one"
+	}
+	return s
+}
+
+func (s *replaysState) Update(g *Game) error {
+	for _, k := range inpututil.AppendJustPressedKeys(nil) {
+		switch k {
+		case ebiten.KeyEscape:
+			g.State = newMenuState(g.Settings.UseSound, g.Settings.UseSlidingText)
+		case ebiten.KeyUp:
+			if s.sel > 0 {
+				s.sel--
+			}
+		case ebiten.KeyDown:
+			if s.sel < len(s.paths)-1 {
+				s.sel++
+			}
+		case ebiten.KeyEnter, ebiten.KeySpace:
+			if len(s.paths) == 0 {
+				continue
+			}
+			f, err := gorillas.LoadReplay(s.paths[s.sel])
+			var r *gorillas.Replay
+			if err == nil {
+				r, err = gorillas.NewReplay(f)
+			}
+			if err != nil {
+				s.message = err.Error()
+				continue
+			}
+			g.State = newReplayState(g, r)
+		}
+	}
+	return nil
+}
+
+func (s *replaysState) Draw(g *Game, screen *ebiten.Image) {
+	screen.Fill(color.RGBA{0, 0, 0, 255})
+	title := "REPLAYS"
+	ebitenutil.DebugPrintAt(screen, title, (g.Width-len(title)*charW)/2, 2*charH)
+	for i, l := range s.labels {
+		if i == s.sel {
+			l = "> " + l
+		} else {
+			l = "  " + l
+		}
+		ebitenutil.DebugPrintAt(screen, l, 4*charW, (4+i)*charH)
+	}
+	if s.message != "" {
+		ebitenutil.DebugPrintAt(screen, s.message, (g.Width-len(s.message)*charW)/2, (5+len(s.labels))*charH)
+	}
+	help := "Up/Down choose, Enter watch, Esc back"
+	ebitenutil.DebugPrintAt(screen, help, (g.Width-len(help)*charW)/2, g.Height-2*charH)
+}
+
+// replayState plays a recorded match in place of the live game, handing the
+// live game back when the replay ends or Esc is pressed.
+type replayState struct {
+	replay *gorillas.Replay
+	live   *gorillas.Game
+}
+
+func newReplayState(g *Game, r *gorillas.Replay) *replayState {
+	s := &replayState{replay: r, live: g.Game}
+	r.Game.Settings.UseSound = g.Settings.UseSound
+	if r.Game.GorillaMask == nil {
+		r.Game.SetGorillaMask(g.GorillaMask)
+	}
+	g.Game = r.Game
+	g.Game.ResetHook = g.initBuildings
+	g.initBuildings()
+	return s
+}
+
+func (s *replayState) Update(g *Game) error {
+	if inpututil.IsKeyJustPressed(ebiten.KeyEscape) || s.replay.Done() {
+		g.Game = s.live
+		g.initBuildings()
+		g.State = newReplaysState(g)
+		return nil
+	}
+	s.replay.Step(time.Second / time.Duration(ebiten.TPS()))
+	return nil
+}
+
+func (s *replayState) Draw(g *Game, screen *ebiten.Image) {
+	playState{}.Draw(g, screen)
+	msg := fmt.Sprintf("REPLAY - %s - Esc to stop", s.replay.Status())
+	ebitenutil.DebugPrintAt(screen, msg, (g.Width-len(msg)*charW)/2, g.Height-charH)
+}
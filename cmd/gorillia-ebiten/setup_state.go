@@ -7,15 +7,23 @@ import (
 	"image/color"
 	"strconv"
 
+	"github.com/arran4/gorillas"
 	"github.com/hajimehoshi/ebiten/v2"
 	"github.com/hajimehoshi/ebiten/v2/ebitenutil"
 	"github.com/hajimehoshi/ebiten/v2/inpututil"
 )
 
-// setupState allows editing players, rounds and gravity before starting.
+// setupState allows editing players, teams, rounds, gravity and the AI
+// level before starting. The first seats fields hold the player names,
+// followed by rounds, gravity and the name of level, which Left and Right
+// pick. teams holds each seat's team label, zero for none.
 type setupState struct {
 	game          *Game
 	fields        []string
+	seats         int
+	teams         []int
+	level         gorillas.AILevel
+	message       string
 	players       []string
 	cur           int
 	editing       bool
@@ -28,7 +36,10 @@ type setupState struct {
 func newSetupState(g *Game) *setupState {
 	s := &setupState{
 		game:          g,
-		fields:        []string{g.Players[0], g.Players[1], strconv.Itoa(g.Settings.DefaultRoundQty), fmt.Sprintf("%.0f", g.Settings.DefaultGravity)},
+		fields:        append(append([]string(nil), g.Players...), strconv.Itoa(g.Settings.DefaultRoundQty), fmt.Sprintf("%.0f", g.Settings.DefaultGravity), g.Settings.AILevel.String()),
+		seats:         len(g.Players),
+		teams:         g.TeamLabels(),
+		level:         g.Settings.AILevel,
 		players:       g.League.Names(),
 		editingPlayer: -1,
 	}
@@ -37,7 +48,7 @@ func newSetupState(g *Game) *setupState {
 }
 
 func (s *setupState) updateAssignField() {
-	if s.cur < 2 {
+	if s.cur < s.seats {
 		s.assignField = s.cur
 	} else if s.cur < len(s.fields) {
 		s.assignField = -1
@@ -73,11 +84,10 @@ func (s *setupState) Update(g *Game) error {
 						s.game.League.AddPlayer(name)
 					} else {
 						s.game.League.RenamePlayer(s.oldName, name)
-						if s.fields[0] == s.oldName {
-							s.fields[0] = name
-						}
-						if s.fields[1] == s.oldName {
-							s.fields[1] = name
+						for i := 0; i < s.seats; i++ {
+							if s.fields[i] == s.oldName {
+								s.fields[i] = name
+							}
 						}
 					}
 					s.game.League.Save()
@@ -110,13 +120,7 @@ func (s *setupState) Update(g *Game) error {
 					if s.editingPlayer >= 0 {
 						s.players[s.editingPlayer] += string(r)
 					} else {
-						if s.cur >= 2 {
-							if (r >= '0' && r <= '9') || (s.cur == 3 && r == '.') {
-								s.fields[s.cur] += string(r)
-							}
-						} else {
-							s.fields[s.cur] += string(r)
-						}
+						s.typeField(r)
 					}
 				}
 			}
@@ -127,7 +131,9 @@ func (s *setupState) Update(g *Game) error {
 		// on the selected field or player.
 		if k != ebiten.KeyN && k != ebiten.KeyD && k != ebiten.KeyR {
 			if k == ebiten.KeyBackspace || keyToRune(k) != 0 {
-				if s.cur < len(s.fields) {
+				if s.cur == s.levelField() {
+					continue
+				} else if s.cur < len(s.fields) {
 					s.editing = true
 					s.editingPlayer = -1
 					if k == ebiten.KeyBackspace {
@@ -135,14 +141,7 @@ func (s *setupState) Update(g *Game) error {
 							s.fields[s.cur] = s.fields[s.cur][:len(s.fields[s.cur])-1]
 						}
 					} else {
-						r := keyToRune(k)
-						if s.cur >= 2 {
-							if (r >= '0' && r <= '9') || (s.cur == 3 && r == '.') {
-								s.fields[s.cur] += string(r)
-							}
-						} else {
-							s.fields[s.cur] += string(r)
-						}
+						s.typeField(keyToRune(k))
 					}
 					continue
 				} else if s.cur >= len(s.fields) && s.cur < len(s.fields)+len(s.players) {
@@ -164,12 +163,21 @@ func (s *setupState) Update(g *Game) error {
 
 		switch k {
 		case ebiten.KeyEscape:
-			r, _ := strconv.Atoi(s.fields[2])
-			gval, _ := strconv.ParseFloat(s.fields[3], 64)
-			s.game.Players = [2]string{s.fields[0], s.fields[1]}
+			r, _ := strconv.Atoi(s.fields[s.seats])
+			gval, _ := strconv.ParseFloat(s.fields[s.seats+1], 64)
+			if err := s.game.SetPlayers(s.fields[:s.seats]...); err != nil {
+				return err
+			}
+			if err := s.game.SetTeams(s.teams...); err != nil {
+				s.message = err.Error()
+				return nil
+			}
+			s.game.holdWind()
 			s.game.Settings.DefaultRoundQty = r
 			s.game.Settings.DefaultGravity = gval
+			s.game.Settings.AILevel = s.level
 			s.game.Gravity = gval
+			s.game.StartMatch()
 			s.game.State = playState{}
 			return nil
 		case ebiten.KeyQ:
@@ -185,16 +193,35 @@ func (s *setupState) Update(g *Game) error {
 		case ebiten.KeyDown, ebiten.KeyTab:
 			s.cur = (s.cur + 1) % (len(s.fields) + len(s.players))
 			s.updateAssignField()
+		case ebiten.KeyLeft, ebiten.KeyRight:
+			// cycle the seat through no team and teams 1 to seats/2
+			if s.cur < s.seats {
+				n := s.seats/2 + 1
+				step := 1
+				if k == ebiten.KeyLeft {
+					step = n - 1
+				}
+				s.teams[s.cur] = (s.teams[s.cur] + step) % n
+				s.message = ""
+			} else if s.cur == s.levelField() {
+				step := 1
+				if k == ebiten.KeyLeft {
+					step = -1
+				}
+				s.stepLevel(step)
+			}
 		case ebiten.KeyEnter:
 			if s.cur >= len(s.fields) && s.assignField >= 0 {
 				name := s.players[s.cur-len(s.fields)]
-				other := 1 - s.assignField
-				if s.fields[other] == name {
-					s.fields[other], s.fields[s.assignField] = s.fields[s.assignField], name
-				} else {
-					s.fields[s.assignField] = name
+				for other := 0; other < s.seats; other++ {
+					if other != s.assignField && s.fields[other] == name {
+						s.fields[other] = s.fields[s.assignField]
+					}
 				}
+				s.fields[s.assignField] = name
 				s.cur = s.assignField
+			} else if s.cur == s.levelField() {
+				s.stepLevel(1)
 			} else if s.cur < len(s.fields) {
 				s.editing = true
 				s.editingPlayer = -1
@@ -216,16 +243,31 @@ func (s *setupState) Update(g *Game) error {
 				s.game.League.DeletePlayer(name)
 				s.game.League.Save()
 				s.players = append(s.players[:idx], s.players[idx+1:]...)
-				if s.fields[0] == name {
-					s.fields[0] = ""
-				}
-				if s.fields[1] == name {
-					s.fields[1] = ""
+				for i := 0; i < s.seats; i++ {
+					if s.fields[i] == name {
+						s.fields[i] = ""
+					}
 				}
 				if s.cur >= len(s.fields)+len(s.players) {
 					s.cur--
 				}
 			}
+		case ebiten.KeyInsert:
+			if s.seats < gorillas.MaxPlayers {
+				name := fmt.Sprintf("Player %d", s.seats+1)
+				s.fields = append(s.fields[:s.seats], append([]string{name}, s.fields[s.seats:]...)...)
+				s.teams = append(s.teams, 0)
+				s.seats++
+				s.cur = s.seats - 1
+				s.updateAssignField()
+			}
+		case ebiten.KeyDelete:
+			if s.cur < s.seats && s.seats > gorillas.MinPlayers {
+				s.fields = append(s.fields[:s.cur], s.fields[s.cur+1:]...)
+				s.teams = append(s.teams[:s.cur], s.teams[s.cur+1:]...)
+				s.seats--
+				s.updateAssignField()
+			}
 		case ebiten.KeyR:
 			if s.cur >= len(s.fields) {
 				s.editing = true
@@ -238,13 +280,46 @@ func (s *setupState) Update(g *Game) error {
 	return nil
 }
 
+// levelField returns the index of the AI level in fields.
+func (s *setupState) levelField() int {
+	return s.seats + 2
+}
+
+// stepLevel moves the AI level n places stronger, wrapping round.
+func (s *setupState) stepLevel(n int) {
+	s.level = s.level.Step(n)
+	s.fields[s.levelField()] = s.level.String()
+}
+
+// typeField appends r to the selected field, keeping rounds and gravity
+// numeric.
+func (s *setupState) typeField(r rune) {
+	if s.cur >= s.seats {
+		if (r >= '0' && r <= '9') || (s.cur == s.seats+1 && r == '.') {
+			s.fields[s.cur] += string(r)
+		}
+		return
+	}
+	s.fields[s.cur] += string(r)
+}
+
 func (s *setupState) Draw(g *Game, screen *ebiten.Image) {
 	screen.Fill(color.RGBA{0, 0, 0, 255})
-	baseY := g.Height/2 - 2*charH
-	ebitenutil.DebugPrintAt(screen, "Game Setup (Esc to start)", 2*charW, baseY-2*charH)
-	labels := []string{"Player 1:", "Player 2:", "Rounds:", "Gravity:"}
+	baseY := g.Height/2 - (s.seats+3)*charH
+	ebitenutil.DebugPrintAt(screen, "Game Setup (Esc to start, Ins/Del to add/remove a seat, Left/Right for teams and AI level)", 2*charW, baseY-2*charH)
+	if s.message != "" {
+		ebitenutil.DebugPrintAt(screen, s.message, 2*charW, baseY-charH)
+	}
+	var labels []string
+	for i := 0; i < s.seats; i++ {
+		labels = append(labels, fmt.Sprintf("Player %d:", i+1))
+	}
+	labels = append(labels, "Rounds:", "Gravity:", "AI Level:")
 	for i, lbl := range labels {
 		line := fmt.Sprintf("%s %s", lbl, s.fields[i])
+		if i < s.seats && s.teams[i] > 0 {
+			line += fmt.Sprintf("  [Team %d]", s.teams[i])
+		}
 		prefix := "  "
 		if i == s.cur {
 			prefix = "> "
@@ -0,0 +1,113 @@
+//go:build !test
+
+package main
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/arran4/gorillas"
+	"github.com/arran4/gorillas/internal/gorillastest"
+	"github.com/gdamore/tcell/v2"
+)
+
+// newSnapshotGame returns a quiet game drawing to an 80x24 simulation
+// screen, keeping its scores and highlights out of the working directory.
+func newSnapshotGame(t *testing.T, seed int64) (*Game, tcell.SimulationScreen) {
+	t.Helper()
+	s := tcell.NewSimulationScreen("")
+	if err := s.Init(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(s.Fini)
+	s.SetSize(80, 24)
+	settings := gorillas.DefaultSettings()
+	settings.UseSound = false
+	// a calm day keeps the banana on the same path whatever the seed's wind
+	g := newGame(settings, gorillas.DefaultBuildingCount, 0, seed)
+	g.League = nil
+	g.ScoreFile = filepath.Join(t.TempDir(), "scores.json")
+	g.ShotsFile = filepath.Join(t.TempDir(), "shots.json")
+	if art, err := gorillas.LoadGorillaArt("../../assets/gorilla.txt"); err == nil {
+		g.gorillaArt = art
+	}
+	g.screen = s
+	return g, s
+}
+
+// screenText returns the characters on s, one line per row.
+func screenText(s tcell.SimulationScreen) string {
+	cells, w, h := s.GetContents()
+	var b strings.Builder
+	for y := 0; y < h; y++ {
+		line := make([]rune, w)
+		for x := range line {
+			line[x] = ' '
+			if r := cells[y*w+x].Runes; len(r) > 0 {
+				line[x] = r[0]
+			}
+		}
+		b.WriteString(strings.TrimRight(string(line), " "))
+		b.WriteByte('\n')
+	}
+	return b.String()
+}
+
+// snapshotStates set up the canonical screens kept in testdata.
+var snapshotStates = map[string]func(t *testing.T) *Game{
+	"city": func(t *testing.T) *Game {
+		g, _ := newSnapshotGame(t, 3)
+		return g
+	},
+	"damaged": func(t *testing.T) *Game {
+		g, _ := newSnapshotGame(t, 3)
+		for i := 2; i < 6; i++ {
+			b := &g.Buildings[i]
+			b.Damage = append(b.Damage, gorillas.DamageCircle{X: b.X + b.W/2, Y: float64(g.Height) - b.H, R: 30})
+		}
+		g.Sun.Integrity = 2
+		g.sunHitTicks = 1
+		g.Banana = gorillas.Banana{X: 300, Y: 180, VX: -2, VY: 1, Active: true}
+		return g
+	},
+	"explosion": func(t *testing.T) *Game {
+		g, _ := newSnapshotGame(t, 3)
+		gorillastest.Explode(t, g.Game, 60, 30, 1)
+		return g
+	},
+	"vector_explosion": func(t *testing.T) *Game {
+		g, _ := newSnapshotGame(t, 3)
+		g.Settings.UseVectorExplosions = true
+		gorillastest.Explode(t, g.Game, 60, 30, 1)
+		return g
+	},
+	"cga": func(t *testing.T) *Game {
+		g, _ := newSnapshotGame(t, 3)
+		g.Settings.ForceCGA = true
+		gorillastest.Explode(t, g.Game, 60, 30, 1)
+		return g
+	},
+	"abort": func(t *testing.T) *Game {
+		g, _ := newSnapshotGame(t, 3)
+		g.abortPrompt = true
+		return g
+	},
+}
+
+// TestDrawSnapshots draws each state and compares the screen with its text
+// snapshot in testdata. Run with -update to accept a deliberate change.
+func TestDrawSnapshots(t *testing.T) {
+	for name, state := range snapshotStates {
+		t.Run(name, func(t *testing.T) {
+			g := state(t)
+			g.draw()
+			got := screenText(g.screen.(tcell.SimulationScreen))
+			path := filepath.Join("testdata", name+".txt")
+			want := gorillastest.Golden(t, path, []byte(got))
+			if got != string(want) {
+				t.Errorf("screen differs from %s:\n%s", path, got)
+			}
+		})
+	}
+}
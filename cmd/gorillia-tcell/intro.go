@@ -166,7 +166,10 @@ func showInstructions(s tcell.Screen, sliding bool) {
 	SparklePause(s, 0)
 }
 
-func introScreen(s tcell.Screen, useSound, sliding bool) bool {
+// introScreen shows the menu until the player picks a game. It reports
+// false when they quit instead, and the address of the network game to
+// join when they picked one on the local network.
+func introScreen(s tcell.Screen, useSound, sliding bool, replayDir string) (bool, string) {
 	w, h := s.Size()
 	cx := w/2 - 10
 	cy := h/2 - 2
@@ -188,20 +191,30 @@ func introScreen(s tcell.Screen, useSound, sliding bool) bool {
 		drawString(s, w/2-9, cy+3, "V/X - View Intro")
 		drawString(s, w/2-9, cy+4, "I - Instructions")
 		drawString(s, w/2-9, cy+5, "P/Start - Play Game")
-		drawString(s, w/2-9, cy+6, "R - Replays")
-		drawString(s, w/2-9, cy+7, "Q/B - Quit")
+		drawString(s, w/2-9, cy+6, "J - Join LAN Game")
+		drawString(s, w/2-9, cy+7, "R - Replays")
+		drawString(s, w/2-9, cy+8, "S - Best Shots")
+		drawString(s, w/2-9, cy+9, "Q/B - Quit")
 		s.Show()
 		ev := s.PollEvent()
 		if key, ok := ev.(*tcell.EventKey); ok {
 			switch key.Rune() {
 			case 'q', 'Q':
-				return false
+				return false, ""
 			case 'p', 'P':
-				return true
+				return true, ""
+			case 'j', 'J':
+				if addr := showLANGames(s); addr != "" {
+					return true, addr
+				}
 			case 'v', 'V':
 				showIntroMovie(s, useSound, sliding)
 			case 'i', 'I':
 				showInstructions(s, sliding)
+			case 'r', 'R':
+				showReplays(s, replayDir, useSound)
+			case 's', 'S':
+				showBestShots(s, gorillas.DefaultShotsFile, useSound)
 			}
 		}
 	}
@@ -269,6 +282,12 @@ func showLeague(s tcell.Screen, l *gorillas.League) {
 	for _, st := range l.Standings() {
 		rows = append(rows, fmt.Sprintf("%-15s %6d %4d %8.1f", st.Name, st.Rounds, st.Wins, st.Accuracy))
 	}
+	if len(l.Teams) > 0 {
+		rows = append(rows, "", "Team             Rounds Wins Accuracy")
+		for _, st := range l.TeamStandings() {
+			rows = append(rows, fmt.Sprintf("%-15s %6d %4d %8.1f", st.Name, st.Rounds, st.Wins, st.Accuracy))
+		}
+	}
 	s.Clear()
 	w, h := s.Size()
 	y := h/2 - len(rows)/2
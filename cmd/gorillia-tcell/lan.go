@@ -0,0 +1,81 @@
+//go:build !test
+
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/arran4/gorillas"
+	"github.com/gdamore/tcell/v2"
+)
+
+// showLANGames lists the games hosted on the local network as their
+// announcements come and go, and returns the address of the one picked, or
+// "" when Escape goes back.
+func showLANGames(s tcell.Screen) string {
+	message := "Looking for games on the local network..."
+	b, err := gorillas.BrowseLAN(fmt.Sprintf(":%d", gorillas.LANPort))
+	if err != nil {
+		message = err.Error()
+	} else {
+		defer b.Close()
+	}
+	// redraw now and then so the list keeps up
+	stop := make(chan struct{})
+	defer close(stop)
+	go func() {
+		t := time.NewTicker(gorillas.LANAnnounceEvery / 2)
+		defer t.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-t.C:
+				s.PostEvent(tcell.NewEventInterrupt(nil))
+			}
+		}
+	}()
+	sel := 0
+	for {
+		var games []gorillas.LANGame
+		if b != nil {
+			games = b.Games()
+		}
+		sel = max(min(sel, len(games)-1), 0)
+		s.Clear()
+		w, h := s.Size()
+		drawString(s, (w-9)/2, 1, "LAN GAMES")
+		for i, game := range games {
+			prefix := "  "
+			if i == sel {
+				prefix = "> "
+			}
+			drawString(s, 2, 3+i, prefix+game.String())
+		}
+		if len(games) == 0 {
+			drawString(s, (w-len(message))/2, 4, message)
+		}
+		help := "Up/Down choose, Enter join, Esc back"
+		drawString(s, (w-len(help))/2, h-1, help)
+		s.Show()
+		key, ok := s.PollEvent().(*tcell.EventKey)
+		if !ok {
+			continue
+		}
+		switch key.Key() {
+		case tcell.KeyEscape:
+			return ""
+		case tcell.KeyUp:
+			if sel > 0 {
+				sel--
+			}
+		case tcell.KeyDown:
+			sel++
+		case tcell.KeyEnter:
+			if len(games) > 0 {
+				return games[sel].Addr
+			}
+		}
+	}
+}
@@ -10,43 +10,65 @@ import (
 	"math"
 	"math/rand"
 	"strconv"
+	"strings"
 	"time"
 	"unicode"
 
 	"github.com/arran4/gorillas"
+	imgdraw "github.com/arran4/gorillas/drawings/img"
+	_ "github.com/arran4/gorillas/sound"
 	"github.com/gdamore/tcell/v2"
 )
 
-type damageRect struct{ x, y, w, h int }
+// window is a lit window in world coordinates.
+type window struct{ x, y float64 }
 
 type building struct {
-	h       int
-	windows []int
-	damage  []damageRect
+	windows []window
 }
 
 type Game struct {
 	*gorillas.Game
-	buildings    []building
-	screen       tcell.Screen
-	sunX, sunY   int
-	sunHitTicks  int
-	sunIntegrity int
-	angleInput   string
-	powerInput   string
-	enteringAng  bool
-	enteringPow  bool
-	abortPrompt  bool
-	selAngle     bool
-	gorillaArt   [][]string
-	js           *joystick
-	lastDigit    time.Time
+	buildings   []building
+	screen      tcell.Screen
+	sunHitTicks int
+	angleInput  string
+	powerInput  string
+	enteringAng bool
+	enteringPow bool
+	abortPrompt bool
+	selAngle    bool
+	gorillaArt  [][]string
+	js          *joystick
+	lastDigit   time.Time
+	// decor drives cosmetic randomness such as window placement so it is
+	// reproducible from the seed without disturbing the game's own stream.
+	decor *rand.Rand
+	// wind is the wind given with -wind, NaN when the game rolls its own.
+	wind float64
+	// instant is the winning throw waiting to be shown again in slow motion.
+	instant *gorillas.ShotReplay
+	// bots plays the seats given to bot programs and botErrs keeps why any
+	// of them lost their seat, to show once the match is over.
+	bots    *gorillas.Bots
+	botErrs []error
+	// net is the other side of a network game, if this is one.
+	net *gorillas.NetSession
+	// spectators streams the game to anyone watching it, and watching
+	// follows a game streamed from elsewhere along with why it stopped.
+	spectators *gorillas.SpectatorServer
+	watching   *gorillas.Spectator
+	watchErr   error
 }
 
 const (
-	buildingWidth      = 8
-	sunMaxIntegrity    = 4
 	digitBufferTimeout = 3 * time.Second
+	// frameDuration is how often the game is drawn.
+	frameDuration = 50 * time.Millisecond
+	// animationSteps moves explosions, victory dances and messages on once
+	// a frame rather than once a step, so they last as long as they are
+	// drawn for.
+	animationSteps = int(frameDuration / gorillas.StepDuration)
 )
 
 func drawLine(s tcell.Screen, x0, y0, x1, y1 int, r rune) {
@@ -91,38 +113,104 @@ func abs(a int) int {
 	return a
 }
 
-func newGame(settings gorillas.Settings, buildings int, wind float64) *Game {
-	g := &Game{Game: gorillas.NewGame(80, 24, buildings)}
-	if !math.IsNaN(wind) {
-		g.Game.Wind = wind
+func newGame(settings gorillas.Settings, buildings int, wind float64, seed int64) *Game {
+	if seed == 0 {
+		seed = time.Now().UnixNano()
 	}
+	g := &Game{Game: gorillas.NewGameWithSeed(gorillas.WorldWidth, gorillas.WorldHeight, buildings, seed)}
+	g.decor = rand.New(rand.NewSource(seed))
+	g.wind = wind
+	g.holdWind()
 	g.Game.Settings = settings
+	g.AnimationSteps = animationSteps
+	g.StartMatch()
 	if art, err := gorillas.LoadGorillaArt("assets/gorilla.txt"); err == nil {
 		g.gorillaArt = art
 	} else {
 		g.gorillaArt = [][]string{{" O ", "/|\\", "/ \\"}}
 	}
+	// share the Ebiten sprite's hit shape so both ports play identically
+	g.SetGorillaMask(imgdraw.DefaultGorillaSprite(1))
 	g.LoadScores()
-	rand.Seed(time.Now().UnixNano())
+	g.LoadShots()
+	g.initBuildings()
+	if js, err := openJoystick(); err == nil {
+		g.js = js
+	}
+	g.Subscribe(gorillas.ListenerFunc(func(e gorillas.GameEvent) {
+		if e.Kind == gorillas.SunHit {
+			g.sunHitTicks = 10
+		}
+		if e.Kind == gorillas.RoundOver && g.Settings.InstantReplay {
+			g.instant = g.Game.InstantReplay()
+		}
+	}))
+	g.Game.ResetHook = g.initBuildings
+	return g
+}
+
+// holdWind puts back the wind given with -wind, which seating the players
+// or laying a map out rolls afresh. A map's own wind comes first.
+func (g *Game) holdWind() {
+	if !math.IsNaN(g.wind) && (g.Map == nil || g.Map.Wind == nil) {
+		g.Game.Wind = g.wind
+	}
+}
+
+// initBuildings scatters lit windows over the current skyline. Windows are
+// spaced two cells apart on a standard 80x24 terminal.
+func (g *Game) initBuildings() {
+	g.buildings = g.buildings[:0]
+	rowH := float64(g.Height) / 12
+	colW := float64(g.Width) / 40
 	for _, b := range g.Buildings {
-		var wins []int
-		top := g.Height - int(b.H) + 2
-		for y := g.Height - 2; y > top; y -= 2 {
-			if rand.Intn(3) != 0 {
-				wins = append(wins, y)
+		var wins []window
+		if b.Windows != nil {
+			for _, w := range b.Windows {
+				if w.Lit {
+					wins = append(wins, window{b.X + w.X, float64(g.Height) - b.H + w.Y})
+				}
+			}
+			g.buildings = append(g.buildings, building{windows: wins})
+			continue
+		}
+		top := float64(g.Height) - b.H + rowH/2
+		for y := float64(g.Height) - rowH/2; y > top; y -= rowH {
+			for x := b.X + colW/2; x < b.X+b.W-colW; x += colW {
+				if g.decor.Intn(3) != 0 {
+					wins = append(wins, window{x, y})
+				}
 			}
 		}
-		g.buildings = append(g.buildings, building{h: int(b.H), windows: wins})
+		g.buildings = append(g.buildings, building{windows: wins})
 	}
-	// position the sun in the horizontal centre
-	g.sunX = g.Width/2 - 1
-	g.sunY = 1
-	if js, err := openJoystick(); err == nil {
-		g.js = js
+}
+
+// toCell converts a world position into the terminal cell showing it.
+func (g *Game) toCell(x, y float64) (int, int) {
+	cols, rows := g.screen.Size()
+	return int(math.Floor(x * float64(cols) / float64(g.Width))), int(math.Floor(y * float64(rows) / float64(g.Height)))
+}
+
+// fromCell returns the world position at the centre of a terminal cell.
+func (g *Game) fromCell(cx, cy int) (float64, float64) {
+	cols, rows := g.screen.Size()
+	return (float64(cx) + 0.5) * float64(g.Width) / float64(cols), (float64(cy) + 0.5) * float64(g.Height) / float64(rows)
+}
+
+// fillCircle sets every cell whose centre lies within r world units of
+// (cx, cy) to ch.
+func (g *Game) fillCircle(cx, cy, r float64, ch rune) {
+	x0, y0 := g.toCell(cx-r, cy-r)
+	x1, y1 := g.toCell(cx+r, cy+r)
+	for y := y0; y <= y1; y++ {
+		for x := x0; x <= x1; x++ {
+			wx, wy := g.fromCell(x, y)
+			if (wx-cx)*(wx-cx)+(wy-cy)*(wy-cy) <= r*r {
+				g.screen.SetContent(x, y, ch, nil, tcell.StyleDefault)
+			}
+		}
 	}
-	g.sunIntegrity = sunMaxIntegrity
-	g.Game.ResetHook = func() { g.sunIntegrity = sunMaxIntegrity }
-	return g
 }
 
 var (
@@ -131,30 +219,33 @@ var (
 )
 
 func (g *Game) drawSun() {
-	if g.sunIntegrity <= 0 {
+	if g.Sun.Integrity <= 0 {
 		return
 	}
+	cx, cy := g.toCell(g.Sun.X, g.Sun.Y)
+	// the top row belongs to the status line
+	sunX, sunY := cx-1, max(cy-1, 1)
 	art := sunHappy
 	if g.sunHitTicks > 0 {
 		art = sunShock
 		g.sunHitTicks--
 	}
-	switch g.sunIntegrity {
+	switch g.Sun.Integrity {
 	case 1:
 		r := rune(art[1][1])
-		g.screen.SetContent(g.sunX+1, g.sunY+1, r, nil, tcell.StyleDefault)
+		g.screen.SetContent(sunX+1, sunY+1, r, nil, tcell.StyleDefault)
 	case 2:
 		line := art[1]
 		for dx, r := range line {
 			if r != ' ' {
-				g.screen.SetContent(g.sunX+dx, g.sunY+1, r, nil, tcell.StyleDefault)
+				g.screen.SetContent(sunX+dx, sunY+1, r, nil, tcell.StyleDefault)
 			}
 		}
 	case 3:
 		for dy, line := range art[:2] {
 			for dx, r := range line {
 				if r != ' ' {
-					g.screen.SetContent(g.sunX+dx, g.sunY+dy, r, nil, tcell.StyleDefault)
+					g.screen.SetContent(sunX+dx, sunY+dy, r, nil, tcell.StyleDefault)
 				}
 			}
 		}
@@ -162,7 +253,7 @@ func (g *Game) drawSun() {
 		for dy, line := range art {
 			for dx, r := range line {
 				if r != ' ' {
-					g.screen.SetContent(g.sunX+dx, g.sunY+dy, r, nil, tcell.StyleDefault)
+					g.screen.SetContent(sunX+dx, sunY+dy, r, nil, tcell.StyleDefault)
 				}
 			}
 		}
@@ -171,37 +262,34 @@ func (g *Game) drawSun() {
 
 func (g *Game) draw() {
 	g.screen.Clear()
-	for i := range g.buildings {
-		g.buildings[i].h = int(g.Buildings[i].H)
-		g.buildings[i].damage = g.buildings[i].damage[:0]
-		for _, d := range g.Buildings[i].Damage {
-			r := int(math.Round(d.R))
-			g.buildings[i].damage = append(g.buildings[i].damage, damageRect{
-				x: int(math.Round(d.X)) - r,
-				y: int(math.Round(d.Y)) - r,
-				w: 2 * r,
-				h: 2 * r,
-			})
-		}
-	}
-	for i, b := range g.buildings {
-		x := i*buildingWidth + 4
-		for y := g.Height - 1; y >= g.Height-b.h; y-- {
-			g.screen.SetContent(x, y, '#', nil, tcell.StyleDefault)
-		}
-		for _, wy := range b.windows {
-			g.screen.SetContent(x, wy, 'o', nil, tcell.StyleDefault)
-		}
-		for _, d := range b.damage {
-			for dx := 0; dx < d.w; dx++ {
-				for dy := 0; dy < d.h; dy++ {
-					g.screen.SetContent(d.x+dx, d.y+dy, ' ', nil, tcell.StyleDefault)
-				}
+	_, rows := g.screen.Size()
+	for i, b := range g.Buildings {
+		x0, y0 := g.toCell(b.X, float64(g.Height)-b.H)
+		x1, _ := g.toCell(b.X+b.W, 0)
+		if x1-x0 > 1 {
+			// leave a gap so neighbouring buildings stay distinct
+			x1--
+		}
+		for x := x0; x < x1; x++ {
+			for y := y0; y < rows; y++ {
+				g.screen.SetContent(x, y, '#', nil, tcell.StyleDefault)
 			}
 		}
+		if i < len(g.buildings) {
+			for _, w := range g.buildings[i].windows {
+				wx, wy := g.toCell(w.x, w.y)
+				g.screen.SetContent(wx, wy, 'o', nil, tcell.StyleDefault)
+			}
+		}
+		for _, d := range b.Damage {
+			g.fillCircle(d.X, d.Y, d.R, ' ')
+		}
+	}
+	for i, gr := range g.Gorillas {
+		if !gr.Dead {
+			g.drawGorilla(i)
+		}
 	}
-	g.drawGorilla(0)
-	g.drawGorilla(1)
 	if g.Banana.Active {
 		ch := 'o'
 		if math.Abs(g.Banana.VX) > math.Abs(g.Banana.VY) {
@@ -217,7 +305,8 @@ func (g *Game) draw() {
 				ch = 'v'
 			}
 		}
-		g.screen.SetContent(int(g.Banana.X), int(g.Banana.Y), ch, nil, tcell.StyleDefault)
+		bx, by := g.toCell(g.Banana.X, g.Banana.Y)
+		g.screen.SetContent(bx, by, ch, nil, tcell.StyleDefault)
 	}
 	if g.Explosion.Active {
 		char := '*'
@@ -233,23 +322,12 @@ func (g *Game) draw() {
 		if g.Settings.UseVectorExplosions && frame > 0 && frame-1 < len(g.Explosion.Vectors) {
 			pts := g.Explosion.Vectors[frame-1]
 			for i := 1; i < len(pts); i++ {
-				drawLine(g.screen, int(pts[i-1].X), int(pts[i-1].Y), int(pts[i].X), int(pts[i].Y), char)
+				x0, y0 := g.toCell(pts[i-1].X, pts[i-1].Y)
+				x1, y1 := g.toCell(pts[i].X, pts[i].Y)
+				drawLine(g.screen, x0, y0, x1, y1, char)
 			}
 		} else {
-			r := int(g.Explosion.Radii[frame])
-			ex := int(g.Explosion.X)
-			ey := int(g.Explosion.Y)
-			for dx := -r; dx <= r; dx++ {
-				for dy := -r; dy <= r; dy++ {
-					if dx*dx+dy*dy <= r*r {
-						x := ex + dx
-						y := ey + dy
-						if x >= 0 && x < g.Width && y >= 0 && y < g.Height {
-							g.screen.SetContent(x, y, char, nil, tcell.StyleDefault)
-						}
-					}
-				}
-			}
+			g.fillCircle(g.Explosion.X, g.Explosion.Y, g.Explosion.Radii[frame], char)
 		}
 	}
 	g.drawSun()
@@ -275,11 +353,17 @@ func (g *Game) draw() {
 	} else {
 		powerStr = "[" + powerStr + "]"
 	}
-	info := fmt.Sprintf("Player %d (%s) - Angle:%s° Power:%s Wind:%+2.0f Score:%d-%d",
-		g.Current+1, g.Players[g.Current], angleStr, powerStr, g.Wind, g.Wins[0], g.Wins[1])
+	name := g.Players[g.Current]
+	if g.Teams != nil {
+		name += fmt.Sprintf(", Team %d", g.Side(g.Current)+1)
+	}
+	info := fmt.Sprintf("Player %d (%s) - Angle:%s° Power:%s Wind:%+2.0f Score:%s",
+		g.Current+1, name, angleStr, powerStr, g.Wind, g.ScoreString())
+	cols, _ := g.screen.Size()
 	x := 0
-	if g.Current == 1 {
-		x = g.Width - len(info)
+	// players on the right of the city read their status on the right
+	if g.Gorillas[g.Current].X > float64(g.Width)/2 {
+		x = cols - len(info)
 		if x < 0 {
 			x = 0
 		}
@@ -287,10 +371,21 @@ func (g *Game) draw() {
 	drawString(g.screen, x, 0, info)
 	if g.abortPrompt {
 		msg := "Abort game? [Y/N]"
-		drawString(g.screen, (g.Width-len(msg))/2, 1, msg)
+		drawString(g.screen, (cols-len(msg))/2, 1, msg)
 	} else if g.LastEvent != gorillas.EventNone {
 		msg := g.LastEventMsg
-		drawString(g.screen, (g.Width-len(msg))/2, g.Height/3, msg)
+		drawString(g.screen, (cols-len(msg))/2, rows/3, msg)
+	}
+	if g.net != nil && !g.abortPrompt {
+		msg := g.net.Status()
+		drawString(g.screen, (cols-len(msg))/2, 1, msg)
+	}
+	if g.watching != nil {
+		msg := "Watching - press Esc to leave"
+		if g.watchErr != nil {
+			msg = "Watching: " + g.watchErr.Error() + " - press Esc to leave"
+		}
+		drawString(g.screen, (cols-len(msg))/2, 1, msg)
 	}
 	g.screen.Show()
 }
@@ -299,22 +394,23 @@ func (g *Game) drawWindArrow() {
 	if g.Wind == 0 {
 		return
 	}
-	length := int(math.Round(g.Wind * 3 * float64(g.Width) / 320))
+	cols, _ := g.screen.Size()
+	length := int(math.Round(g.Wind * 3 * float64(cols) / 320))
 	// Draw near the top instead of bottom for better visibility
 	y := 1
-	x := g.Width / 2
+	x := cols / 2
 	dir := 1
 	if length < 0 {
 		dir = -1
 	}
 	for i := dir; i != length; i += dir {
 		pos := x + i
-		if pos >= 0 && pos < g.Width {
+		if pos >= 0 && pos < cols {
 			g.screen.SetContent(pos, y, '-', nil, tcell.StyleDefault)
 		}
 	}
 	headX := x + length
-	if headX >= 0 && headX < g.Width {
+	if headX >= 0 && headX < cols {
 		head := '>'
 		if length < 0 {
 			head = '<'
@@ -329,8 +425,9 @@ func (g *Game) drawGorilla(idx int) {
 	}
 	frame := g.gorillaArt[0]
 	width := gorillas.FrameWidth(frame)
-	x := int(g.Gorillas[idx].X) - width/2
-	y := int(g.Gorillas[idx].Y) - len(frame)
+	cx, cy := g.toCell(g.Gorillas[idx].X, g.Gorillas[idx].Y)
+	x := cx - width/2
+	y := cy - len(frame)
 	style := tcell.StyleDefault
 	for dy, line := range frame {
 		for dx, r := range line {
@@ -341,10 +438,6 @@ func (g *Game) drawGorilla(idx int) {
 	}
 }
 
-func (g *Game) startVictoryDance(idx int) {
-	g.Dance = gorillas.NewDance(idx, []float64{-3, 0, -3, 0}, g.Gorillas[idx].Y)
-}
-
 func (g *Game) throw() {
 	g.Throw()
 }
@@ -352,29 +445,47 @@ func (g *Game) throw() {
 func (g *Game) run(s tcell.Screen, ai bool) error {
 	g.screen = s
 
-	ticker := time.NewTicker(50 * time.Millisecond)
-	prevExplosion := g.Explosion.Active
+	ticker := time.NewTicker(frameDuration)
+	last := time.Now()
 	for {
 		g.draw()
 		<-ticker.C
-		g.Step()
-		if !prevExplosion && g.Explosion.Active {
-			g.startVictoryDance(g.Current)
-		}
-		prevExplosion = g.Explosion.Active
-		if g.Banana.Active && g.sunIntegrity > 0 {
-			if int(g.Banana.X) >= g.sunX && int(g.Banana.X) < g.sunX+3 && int(g.Banana.Y) >= g.sunY && int(g.Banana.Y) < g.sunY+3 {
-				g.sunHitTicks = 10
-				if g.sunIntegrity > 0 {
-					g.sunIntegrity--
-				}
-			}
+		now := time.Now()
+		g.Step(now.Sub(last))
+		last = now
+		if g.instant != nil {
+			g.showInstantReplay()
+			last = time.Now()
+		}
+		if g.MatchOver() && !g.Explosion.Active && !g.Dance.Active {
+			return nil
 		}
 		if g.Banana.Active || g.Explosion.Active || g.Dance.Active {
 			continue
 		}
 
-		if g.js != nil {
+		if g.spectators != nil {
+			g.spectators.Update()
+		}
+
+		// while the other side of a network game thinks, only Escape works
+		waiting := false
+		if g.net != nil {
+			played, err := g.net.Poll()
+			if g.Banana.Active || err != nil {
+				continue
+			}
+			waiting = played
+		}
+		if g.watching != nil {
+			g.watchErr = g.watching.Poll()
+			if g.Banana.Active {
+				continue
+			}
+			waiting = true
+		}
+
+		if g.js != nil && !waiting {
 			g.js.poll()
 			if g.js.axis[0] < -10000 {
 				g.Angle += 0.5
@@ -393,12 +504,25 @@ func (g *Game) run(s tcell.Screen, ai bool) error {
 			}
 		}
 
-		if ai && g.Current == 1 {
+		if g.bots != nil && !waiting {
+			if played, err := g.bots.Poll(); played {
+				if err != nil {
+					g.botErrs = append(g.botErrs, err)
+				}
+				continue
+			}
+		}
+
+		if ai && g.Current != 0 && !waiting {
 			g.AutoShot()
 			continue
 		}
 
+		// show where the last throw left things before waiting
+		g.draw()
 		ev := s.PollEvent()
+		// waiting for input is not game time
+		last = time.Now()
 		if key, ok := ev.(*tcell.EventKey); ok {
 			if g.abortPrompt {
 				r := unicode.ToUpper(key.Rune())
@@ -411,6 +535,12 @@ func (g *Game) run(s tcell.Screen, ai bool) error {
 				}
 				continue
 			}
+			if g.watching != nil && key.Key() == tcell.KeyEscape {
+				return nil
+			}
+			if waiting && key.Key() != tcell.KeyEscape {
+				continue
+			}
 			now := time.Now()
 			switch key.Key() {
 			case tcell.KeyEscape:
@@ -507,11 +637,17 @@ func (g *Game) run(s tcell.Screen, ai bool) error {
 }
 
 // setupScreen presents an interactive form allowing the player names,
-// round count and gravity to be edited. It returns the updated values
-// once the user starts the game by pressing Enter on "Start" or
-// pressing Escape.
-func setupScreen(s tcell.Screen, league *gorillas.League, p1, p2 string, rounds int, gravity float64) (string, string, int, float64, bool) {
-	fields := []string{p1, p2, strconv.Itoa(rounds), fmt.Sprintf("%.0f", gravity)}
+// teams, round count, gravity and computer skill to be edited. It returns the
+// updated values once the user starts the game by pressing Enter on "Start"
+// or pressing Escape. Left and Right on a seat change its team, zero for
+// none, and on the AI level step through the levels.
+func setupScreen(s tcell.Screen, league *gorillas.League, names []string, teams []int, rounds int, gravity float64, level gorillas.AILevel) ([]string, []int, int, float64, gorillas.AILevel, bool) {
+	// the first seats fields are player names, then rounds, gravity and the
+	// AI level, which is picked rather than typed
+	fields := append(append([]string(nil), names...), strconv.Itoa(rounds), fmt.Sprintf("%.0f", gravity), level.String())
+	seats := len(names)
+	teams = append([]int(nil), teams...)
+	message := ""
 	players := league.Names()
 	cur := 0
 	editing := false
@@ -521,32 +657,43 @@ func setupScreen(s tcell.Screen, league *gorillas.League, p1, p2 string, rounds
 	assignField := 0
 
 	updateAssignField := func() {
-		if cur < 2 {
+		if cur < seats {
 			assignField = cur
 		} else if cur < len(fields) {
 			assignField = -1
 		}
 	}
 	updateAssignField()
-	labels := []string{"Player 1:", "Player 2:", "Rounds:", "Gravity:"}
 	selectedPlayer := -1
 	for {
 		s.Clear()
 		_, h := s.Size()
-		baseY := h/2 - 2
-		opts := []string{"New Player", "Rename Player", "Delete Player", "Start"}
+		baseY := h/2 - seats
+		var labels []string
+		for i := 0; i < seats; i++ {
+			labels = append(labels, fmt.Sprintf("Player %d:", i+1))
+		}
+		labels = append(labels, "Rounds:", "Gravity:", "AI Level:")
+		levelIdx := seats + 2
+		opts := []string{"New Player", "Rename Player", "Delete Player", "Add Seat", "Remove Seat", "Start"}
 		total := len(fields) + len(players) + len(opts)
 		newIdx := len(fields) + len(players)
 		renameIdx := newIdx + 1
 		deleteIdx := renameIdx + 1
-		startIdx := deleteIdx + 1
-		drawString(s, 2, baseY-2, "Game Setup")
+		addSeatIdx := deleteIdx + 1
+		removeSeatIdx := addSeatIdx + 1
+		startIdx := removeSeatIdx + 1
+		drawString(s, 2, baseY-2, "Game Setup (Left/Right picks a player's team or the AI level)")
+		drawString(s, 2, baseY-1, message)
 		for i, lbl := range labels {
 			style := tcell.StyleDefault
 			if i == cur {
 				style = style.Reverse(true)
 			}
 			line := fmt.Sprintf("%s [%s]", lbl, fields[i])
+			if i < seats && teams[i] > 0 {
+				line += fmt.Sprintf(" Team %d", teams[i])
+			}
 			for x, r := range line {
 				s.SetContent(2+x, baseY+i, r, nil, style)
 			}
@@ -597,11 +744,10 @@ func setupScreen(s tcell.Screen, league *gorillas.League, p1, p2 string, rounds
 							league.AddPlayer(name)
 						} else {
 							league.RenamePlayer(oldName, name)
-							if fields[0] == oldName {
-								fields[0] = name
-							}
-							if fields[1] == oldName {
-								fields[1] = name
+							for i := 0; i < seats; i++ {
+								if fields[i] == oldName {
+									fields[i] = name
+								}
 							}
 						}
 						league.Save()
@@ -638,7 +784,7 @@ func setupScreen(s tcell.Screen, league *gorillas.League, p1, p2 string, rounds
 						if editingPlayer >= 0 {
 							players[editingPlayer] += string(key.Rune())
 						} else {
-							if cur >= 2 {
+							if cur >= seats {
 								if key.Rune() >= '0' && key.Rune() <= '9' {
 									fields[cur] += string(key.Rune())
 								}
@@ -654,7 +800,9 @@ func setupScreen(s tcell.Screen, league *gorillas.League, p1, p2 string, rounds
 			// Automatically enter editing mode when typing or
 			// pressing backspace on a selected field or player.
 			if key.Key() == tcell.KeyBackspace || key.Key() == tcell.KeyBackspace2 || key.Rune() != 0 {
-				if cur < len(fields) {
+				if cur == levelIdx {
+					continue
+				} else if cur < len(fields) {
 					editing = true
 					editingPlayer = -1
 					if key.Key() == tcell.KeyBackspace || key.Key() == tcell.KeyBackspace2 {
@@ -663,7 +811,7 @@ func setupScreen(s tcell.Screen, league *gorillas.League, p1, p2 string, rounds
 						}
 					} else {
 						r := key.Rune()
-						if cur >= 2 {
+						if cur >= seats {
 							if r >= '0' && r <= '9' {
 								fields[cur] += string(r)
 							}
@@ -691,13 +839,35 @@ func setupScreen(s tcell.Screen, league *gorillas.League, p1, p2 string, rounds
 
 			switch key.Key() {
 			case tcell.KeyEsc:
-				r, _ := strconv.Atoi(fields[2])
-				g, _ := strconv.ParseFloat(fields[3], 64)
-				return fields[0], fields[1], r, g, true
+				if oneTeam(teams) {
+					message = "Everyone is on the same team"
+					continue
+				}
+				r, _ := strconv.Atoi(fields[seats])
+				g, _ := strconv.ParseFloat(fields[seats+1], 64)
+				return fields[:seats], teams, r, g, level, true
 			case tcell.KeyCtrlC:
-				r, _ := strconv.Atoi(fields[2])
-				g, _ := strconv.ParseFloat(fields[3], 64)
-				return fields[0], fields[1], r, g, false
+				r, _ := strconv.Atoi(fields[seats])
+				g, _ := strconv.ParseFloat(fields[seats+1], 64)
+				return fields[:seats], teams, r, g, level, false
+			case tcell.KeyLeft, tcell.KeyRight:
+				// cycle the seat through no team and teams 1 to seats/2
+				if cur < seats {
+					n := seats/2 + 1
+					step := 1
+					if key.Key() == tcell.KeyLeft {
+						step = n - 1
+					}
+					teams[cur] = (teams[cur] + step) % n
+					message = ""
+				} else if cur == levelIdx {
+					step := 1
+					if key.Key() == tcell.KeyLeft {
+						step = -1
+					}
+					level = level.Step(step)
+					fields[cur] = level.String()
+				}
 			case tcell.KeyUp:
 				if cur > 0 {
 					cur--
@@ -716,9 +886,13 @@ func setupScreen(s tcell.Screen, league *gorillas.League, p1, p2 string, rounds
 				}
 			case tcell.KeyEnter:
 				if cur == startIdx {
-					r, _ := strconv.Atoi(fields[2])
-					g, _ := strconv.ParseFloat(fields[3], 64)
-					return fields[0], fields[1], r, g, true
+					if oneTeam(teams) {
+						message = "Everyone is on the same team"
+						continue
+					}
+					r, _ := strconv.Atoi(fields[seats])
+					g, _ := strconv.ParseFloat(fields[seats+1], 64)
+					return fields[:seats], teams, r, g, level, true
 				} else if cur == newIdx {
 					players = append(players, "")
 					cur = len(fields) + len(players) - 1
@@ -740,11 +914,10 @@ func setupScreen(s tcell.Screen, league *gorillas.League, p1, p2 string, rounds
 						league.DeletePlayer(name)
 						league.Save()
 						players = append(players[:selectedPlayer], players[selectedPlayer+1:]...)
-						if fields[0] == name {
-							fields[0] = ""
-						}
-						if fields[1] == name {
-							fields[1] = ""
+						for i := 0; i < seats; i++ {
+							if fields[i] == name {
+								fields[i] = ""
+							}
 						}
 						if selectedPlayer >= len(players) {
 							selectedPlayer = len(players) - 1
@@ -753,15 +926,32 @@ func setupScreen(s tcell.Screen, league *gorillas.League, p1, p2 string, rounds
 							cur--
 						}
 					}
+				} else if cur == addSeatIdx {
+					if seats < gorillas.MaxPlayers {
+						fields = append(fields[:seats], append([]string{fmt.Sprintf("Player %d", seats+1)}, fields[seats:]...)...)
+						teams = append(teams, 0)
+						seats++
+						cur++
+					}
+				} else if cur == removeSeatIdx {
+					if seats > gorillas.MinPlayers {
+						fields = append(fields[:seats-1], fields[seats:]...)
+						teams = teams[:seats-1]
+						seats--
+						cur--
+					}
 				} else if cur >= len(fields) && cur < len(fields)+len(players) && assignField >= 0 {
 					name := players[cur-len(fields)]
-					other := 1 - assignField
-					if fields[other] == name {
-						fields[other], fields[assignField] = fields[assignField], name
-					} else {
-						fields[assignField] = name
+					for other := 0; other < seats; other++ {
+						if other != assignField && fields[other] == name {
+							fields[other] = fields[assignField]
+						}
 					}
+					fields[assignField] = name
 					cur = assignField
+				} else if cur == levelIdx {
+					level = level.Step(1)
+					fields[cur] = level.String()
 				} else if cur < len(fields) {
 					editing = true
 				} else {
@@ -785,11 +975,10 @@ func setupScreen(s tcell.Screen, league *gorillas.League, p1, p2 string, rounds
 						league.DeletePlayer(name)
 						league.Save()
 						players = append(players[:selectedPlayer], players[selectedPlayer+1:]...)
-						if fields[0] == name {
-							fields[0] = ""
-						}
-						if fields[1] == name {
-							fields[1] = ""
+						for i := 0; i < seats; i++ {
+							if fields[i] == name {
+								fields[i] = ""
+							}
 						}
 						if selectedPlayer >= len(players) {
 							selectedPlayer = len(players) - 1
@@ -812,6 +1001,17 @@ func setupScreen(s tcell.Screen, league *gorillas.League, p1, p2 string, rounds
 	}
 }
 
+// oneTeam reports whether every seat was put on the same team, leaving
+// nobody to play against.
+func oneTeam(teams []int) bool {
+	for _, t := range teams {
+		if t == 0 || t != teams[0] {
+			return false
+		}
+	}
+	return len(teams) > 0
+}
+
 func main() {
 	s, err := tcell.NewScreen()
 	if err != nil {
@@ -839,59 +1039,220 @@ func main() {
 	buildings := flag.Int("buildings", gorillas.DefaultBuildingCount, "building count")
 	p1 := flag.String("player1", "Player 1", "name of player 1")
 	p2 := flag.String("player2", "Player 2", "name of player 2")
+	players := flag.Int("players", gorillas.MinPlayers, "number of gorillas (2-8)")
+	names := flag.String("names", "", "comma separated player names, overriding -player1 and -player2")
+	teams := flag.String("teams", "", "comma separated team for each player, e.g. 1,2,1,2")
+	friendlyFire := flag.String("friendlyfire", settings.FriendlyFire.String(), "hits on teammates: self, off or ignored")
+	skyline := flag.String("skyline", settings.Skyline, "skyline generator: "+strings.Join(gorillas.SkylineNames(), ", "))
 	flag.BoolVar(&settings.UseSound, "sound", settings.UseSound, "enable sound")
 	flag.BoolVar(&settings.WinnerFirst, "winnerfirst", settings.WinnerFirst, "winner starts next round")
-	ai := flag.Bool("ai", false, "enable computer opponent")
+	flag.BoolVar(&settings.InstantReplay, "instantreplay", settings.InstantReplay, "replay each round's winning throw in slow motion")
+	ai := flag.Bool("ai", false, "computer plays every gorilla but the first")
+	aiLevel := flag.String("ai-level", settings.AILevel.String(), "how well the computer aims: easy, normal, hard or perfect")
+	p1Bot := flag.String("player1-bot", "", "bot program that plays player 1, e.g. ./mybot")
+	p2Bot := flag.String("player2-bot", "", "bot program that plays player 2")
+	botTimeout := flag.Duration("bot-timeout", gorillas.DefaultBotTimeout, "how long a bot may think about a turn")
+	seed := flag.Int64("seed", 0, "random seed for the match (0 picks one)")
+	matchMode := flag.String("match", settings.MatchMode.String(), "match mode: bestof or firstto")
+	mapFile := flag.String("map", "", "JSON city map to play on instead of a random city")
+	replayDir := flag.String("replays", gorillas.DefaultReplayDir, "directory finished matches are recorded to (empty to disable)")
+	hostAddr := flag.String("host", "", "host a network game on this address, e.g. :4646")
+	joinAddr := flag.String("join", "", "join the network game at this address, e.g. example.com:4646")
+	spectators := flag.String("spectators", "", "let spectators watch the game on this address, e.g. :4648")
+	watchAddr := flag.String("watch", "", "watch the game streamed at this address, e.g. example.com:4648")
 	flag.Parse()
 	settings.DefaultGravity = *gravity
 	settings.DefaultRoundQty = *rounds
+	if m, err := gorillas.ParseMatchMode(*matchMode); err == nil {
+		settings.MatchMode = m
+	} else {
+		s.Fini()
+		log.Fatalf("-match: %v", err)
+	}
+	if _, err := gorillas.SkylineByName(*skyline); err == nil {
+		settings.Skyline = *skyline
+	} else {
+		s.Fini()
+		log.Fatalf("-skyline: %v", err)
+	}
+	if f, err := gorillas.ParseFriendlyFire(*friendlyFire); err == nil {
+		settings.FriendlyFire = f
+	} else {
+		s.Fini()
+		log.Fatalf("-friendlyfire: %v", err)
+	}
+	if l, err := gorillas.ParseAILevel(*aiLevel); err == nil {
+		settings.AILevel = l
+	} else {
+		s.Fini()
+		log.Fatalf("-ai-level: %v", err)
+	}
+	labels, err := gorillas.ParseTeams(*teams)
+	if err != nil {
+		s.Fini()
+		log.Fatalf("-teams: %v", err)
+	}
+	var cityMap *gorillas.CityMap
+	if *mapFile != "" {
+		if cityMap, err = gorillas.LoadMap(*mapFile); err != nil {
+			s.Fini()
+			log.Fatalf("-map: %v", err)
+		}
+	}
+
+	if *watchAddr != "" {
+		if *hostAddr != "" || *joinAddr != "" || *spectators != "" {
+			s.Fini()
+			log.Fatal("-watch: a spectator cannot also -host, -join or serve -spectators")
+		}
+		watchGame(s, settings, *watchAddr)
+		return
+	}
 
 	if settings.ShowIntro {
 		showIntroMovie(s, settings.UseSound, settings.UseSlidingText)
 	}
 
-	if !introScreen(s, settings.UseSound, settings.UseSlidingText) {
+	play, lanGame := introScreen(s, settings.UseSound, settings.UseSlidingText, *replayDir)
+	if !play {
 		return
 	}
+	if lanGame != "" {
+		*hostAddr, *joinAddr = "", lanGame
+	}
 
+	list := []string{*p1, *p2}
+	if *names != "" {
+		list = strings.Split(*names, ",")
+		*players = max(*players, len(list))
+	}
+	if *players < gorillas.MinPlayers || *players > gorillas.MaxPlayers {
+		s.Fini()
+		log.Fatalf("-players: need %d to %d players, got %d", gorillas.MinPlayers, gorillas.MaxPlayers, *players)
+	}
+	netGame := *hostAddr != "" || *joinAddr != ""
+	if *hostAddr != "" && *joinAddr != "" {
+		s.Fini()
+		log.Fatal("-host and -join: pick one")
+	}
+	if netGame && (*players != gorillas.MinPlayers || *teams != "") {
+		s.Fini()
+		log.Fatal("-host and -join: network games are for two players without teams")
+	}
+	labels = append(labels, make([]int, max(*players-len(labels), 0))...)[:*players]
 	league := gorillas.LoadLeague("gorillas.lge")
-	var ok bool
-	*p1, *p2, *rounds, *gravity, ok = setupScreen(s, league, *p1, *p2, *rounds, *gravity)
-	if !ok {
-		return
+	seated, level := gorillas.PlayerNames(*players, list...), settings.AILevel
+	// the host's flags settle a network game, so there is nothing to set up
+	if !netGame {
+		var ok bool
+		seated, labels, *rounds, *gravity, level, ok = setupScreen(s, league, seated, labels, *rounds, *gravity, level)
+		if !ok {
+			return
+		}
 	}
+	settings.AILevel = level
 	settings.DefaultGravity = *gravity
 	settings.DefaultRoundQty = *rounds
 
-	g := newGame(settings, *buildings, *wind)
-	g.Players = [2]string{*p1, *p2}
+	g := newGame(settings, *buildings, *wind, *seed)
+	g.ReplayDir = *replayDir
+	if err := g.SetPlayers(gorillas.PlayerNames(len(seated), seated...)...); err != nil {
+		s.Fini()
+		log.Fatalf("setup: %v", err)
+	}
+	if err := g.SetTeams(labels...); err != nil {
+		s.Fini()
+		log.Fatalf("setup: %v", err)
+	}
+	if cityMap != nil {
+		if err := g.ApplyMap(cityMap); err != nil {
+			s.Fini()
+			log.Fatalf("-map: %v", err)
+		}
+	}
+	g.holdWind()
+	g.bots = gorillas.NewBots(g.Game)
+	defer g.bots.Close()
+	for i, command := range []string{*p1Bot, *p2Bot} {
+		if command == "" {
+			continue
+		}
+		// the bot's stderr would scribble over the screen
+		bot, err := gorillas.StartBot(command, nil)
+		if err != nil {
+			s.Fini()
+			log.Fatalf("-player%d-bot: %v", i+1, err)
+		}
+		bot.Timeout = *botTimeout
+		g.bots.Seat(i, bot)
+	}
+	if netGame {
+		ok, err := g.connect(s, *hostAddr, *joinAddr)
+		if err != nil {
+			s.Fini()
+			log.Fatalf("-host and -join: %v", err)
+		}
+		if !ok {
+			return
+		}
+	}
+	if *spectators != "" {
+		if err := g.serveSpectators(s, *spectators); err != nil {
+			s.Fini()
+			log.Fatalf("-spectators: %v", err)
+		}
+	}
 	g.League = league
-	winsBackup := g.TotalWins
-	var playersBackup map[string]*gorillas.PlayerStats
+	winsBackup := append([]int(nil), g.TotalWins...)
+	var playersBackup, teamsBackup map[string]*gorillas.PlayerStats
 	if g.League != nil {
 		playersBackup = make(map[string]*gorillas.PlayerStats, len(g.League.Players))
 		for n, ps := range g.League.Players {
 			cp := *ps
 			playersBackup[n] = &cp
 		}
+		teamsBackup = make(map[string]*gorillas.PlayerStats, len(g.League.Teams))
+		for n, ts := range g.League.Teams {
+			cp := *ts
+			teamsBackup[n] = &cp
+		}
 	}
 	if err := g.run(s, *ai); err != nil {
 		panic(fmt.Errorf("run game: %w", err))
 	}
+	if g.net != nil {
+		// let the other side know now rather than after the closing screens
+		g.net.Close()
+	}
+	if g.spectators != nil {
+		g.spectators.Close()
+	}
 	if g.Aborted {
 		g.TotalWins = winsBackup
 		if g.League != nil {
 			g.League.Players = playersBackup
+			g.League.Teams = teamsBackup
 			g.League.Save()
 		}
 		showGameAborted(s)
 		return
 	}
 	g.SaveScores()
-	showStats(s, g.StatsString())
+	stats := g.StatsString()
+	if summary := g.MatchSummary(); summary != "" {
+		stats = summary + "\n\n" + stats
+	}
+	for _, err := range g.botErrs {
+		stats += "\n" + err.Error()
+	}
+	if g.net != nil && g.net.Err() != nil {
+		stats += "\n" + g.net.Err().Error()
+	}
+	showStats(s, stats)
 	if g.League != nil {
 		showLeague(s, g.League)
 	}
 	fmt.Println(g.StatsString())
+	fmt.Printf("Seed: %d\n", g.Seed)
 	showExtro(s)
 }
@@ -0,0 +1,35 @@
+//go:build !test
+
+package main
+
+import (
+	"math"
+	"testing"
+
+	"github.com/arran4/gorillas"
+)
+
+func TestWindFlagSurvivesSeatingPlayers(t *testing.T) {
+	settings := gorillas.DefaultSettings()
+	settings.UseSound = false
+	for _, wind := range []float64{7, 0} {
+		g := newGame(settings, gorillas.DefaultBuildingCount, wind, 1)
+		g.League = nil
+		if err := g.SetPlayers("Ann", "Bob", "Cy"); err != nil {
+			t.Fatal(err)
+		}
+		g.holdWind()
+		if g.Wind != wind {
+			t.Fatalf("-wind %v: the players started in a wind of %v", wind, g.Wind)
+		}
+	}
+
+	// without the flag the seed's wind stands
+	g := newGame(settings, gorillas.DefaultBuildingCount, math.NaN(), 1)
+	g.League = nil
+	g.Wind = 3
+	g.holdWind()
+	if g.Wind != 3 {
+		t.Fatalf("the game's own wind became %v", g.Wind)
+	}
+}
@@ -0,0 +1,158 @@
+//go:build !test
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"math/rand"
+	"net"
+
+	"github.com/arran4/gorillas"
+	"github.com/gdamore/tcell/v2"
+)
+
+// waitFor shows msg while start runs in the background, until it returns
+// or Escape gives up. Giving up cancels start's context and closes whatever
+// start managed to set up anyway. It reports false when the player gave up.
+func waitFor(s tcell.Screen, msg string, start func(ctx context.Context) (io.Closer, error)) (bool, error) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	type started struct {
+		c   io.Closer
+		err error
+	}
+	done := make(chan started, 1)
+	go func() {
+		c, err := start(ctx)
+		done <- started{c, err}
+		s.PostEvent(tcell.NewEventInterrupt(nil))
+	}()
+	for {
+		s.Clear()
+		cols, rows := s.Size()
+		drawString(s, (cols-len(msg))/2, rows/2, msg)
+		hint := "Press Esc to give up"
+		drawString(s, (cols-len(hint))/2, rows/2+2, hint)
+		s.Show()
+		switch ev := s.PollEvent().(type) {
+		case *tcell.EventInterrupt:
+			r := <-done
+			return r.err == nil, r.err
+		case *tcell.EventKey:
+			if ev.Key() == tcell.KeyEscape {
+				cancel()
+				if r := <-done; r.err == nil {
+					// the other side got in just before
+					r.c.Close()
+				}
+				return false, nil
+			}
+		}
+	}
+}
+
+// wakeOn posts an interrupt to s whenever notify receives, and once more
+// when it is closed, so the run loop polls again.
+func wakeOn(s tcell.Screen, notify <-chan struct{}) {
+	go func() {
+		for range notify {
+			s.PostEvent(tcell.NewEventInterrupt(nil))
+		}
+		s.PostEvent(tcell.NewEventInterrupt(nil))
+	}()
+}
+
+// connect hosts a network game on host or joins the one at join, showing
+// what it is waiting for until the other side answers or Escape gives up.
+// It reports false when the player gave up.
+func (g *Game) connect(s tcell.Screen, host, join string) (bool, error) {
+	msg := "Joining " + join + "..."
+	var l net.Listener
+	if host != "" {
+		var err error
+		if l, err = net.Listen("tcp", host); err != nil {
+			return false, err
+		}
+		defer l.Close()
+		msg = "Waiting for a player to join on " + l.Addr().String() + "..."
+	}
+	var session *gorillas.NetSession
+	ok, err := waitFor(s, msg, func(ctx context.Context) (io.Closer, error) {
+		var err error
+		if l != nil {
+			// and let players on the local network find the game
+			go gorillas.AdvertiseLAN(ctx, gorillas.LANBroadcast, gorillas.NewLANGame(g.Game, l))
+			session, err = gorillas.HostNet(ctx, l, g.Game)
+		} else {
+			session, err = gorillas.JoinNet(ctx, join, g.Game)
+		}
+		return session, err
+	})
+	if !ok {
+		return false, err
+	}
+	g.net = session
+	// lay the windows out afresh for the agreed city
+	g.decor = rand.New(rand.NewSource(g.Seed))
+	g.initBuildings()
+	// wake the run loop whenever the other side sends something
+	wakeOn(s, g.net.Notify())
+	return true, nil
+}
+
+// watch follows the game streamed at addr as a spectator, showing what it
+// is waiting for until the game answers or Escape gives up. It reports
+// false when the player gave up.
+func (g *Game) watch(s tcell.Screen, addr string) (bool, error) {
+	var spectator *gorillas.Spectator
+	ok, err := waitFor(s, "Connecting to "+addr+"...", func(ctx context.Context) (io.Closer, error) {
+		var err error
+		spectator, err = gorillas.WatchGame(ctx, addr, g.Game)
+		return spectator, err
+	})
+	if !ok {
+		return false, err
+	}
+	g.watching = spectator
+	g.decor = rand.New(rand.NewSource(g.Seed))
+	g.initBuildings()
+	wakeOn(s, g.watching.Notify())
+	return true, nil
+}
+
+// watchGame follows the game streamed at addr until it ends or Escape
+// leaves, then shows how the match stood.
+func watchGame(s tcell.Screen, settings gorillas.Settings, addr string) {
+	g := newGame(settings, gorillas.DefaultBuildingCount, math.NaN(), 0)
+	ok, err := g.watch(s, addr)
+	if err != nil {
+		s.Fini()
+		log.Fatalf("-watch: %v", err)
+	}
+	if !ok {
+		return
+	}
+	defer g.watching.Close()
+	if err := g.run(s, false); err != nil {
+		panic(fmt.Errorf("watch game: %w", err))
+	}
+	if g.MatchOver() {
+		showStats(s, g.MatchSummary()+"\n\n"+g.StatsString())
+	}
+}
+
+// serveSpectators streams the game to spectators connecting on addr.
+func (g *Game) serveSpectators(s tcell.Screen, addr string) error {
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	g.spectators = gorillas.ServeSpectators(l, g.Game)
+	// send the game to newcomers even while waiting for a key
+	wakeOn(s, g.spectators.Notify())
+	return nil
+}
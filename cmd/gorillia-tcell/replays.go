@@ -0,0 +1,226 @@
+//go:build !test
+
+package main
+
+import (
+	"fmt"
+	"math/rand"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+
+	gorillas "github.com/arran4/gorillas"
+	imgdraw "github.com/arran4/gorillas/drawings/img"
+)
+
+// showReplays lists the matches recorded in dir and plays the chosen one,
+// returning to the list afterwards until Escape is pressed.
+func showReplays(s tcell.Screen, dir string, useSound bool) {
+	paths, err := gorillas.ListReplays(dir)
+	message := ""
+	if err != nil {
+		message = err.Error()
+	} else if len(paths) == 0 {
+		message = "No replays yet - finish a match to record one"
+	}
+	labels := make([]string, len(paths))
+	for i, p := range paths {
+		labels[i] = strings.TrimSuffix(filepath.Base(p), ".json")
+		if f, err := gorillas.LoadReplay(p); err == nil && f.Summary != "" {
+			labels[i] += "  " + f.Summary
+		}
+	}
+	sel := 0
+	for {
+		s.Clear()
+		w, h := s.Size()
+		drawString(s, (w-7)/2, 1, "REPLAYS")
+		for i, l := range labels {
+			prefix := "  "
+			if i == sel {
+				prefix = "> "
+			}
+			drawString(s, 2, 3+i, prefix+l)
+		}
+		if message != "" {
+			drawString(s, (w-len(message))/2, 4+len(labels), message)
+		}
+		help := "Up/Down choose, Enter watch, Esc back"
+		drawString(s, (w-len(help))/2, h-1, help)
+		s.Show()
+		key, ok := s.PollEvent().(*tcell.EventKey)
+		if !ok {
+			continue
+		}
+		switch key.Key() {
+		case tcell.KeyEscape:
+			return
+		case tcell.KeyUp:
+			if sel > 0 {
+				sel--
+			}
+		case tcell.KeyDown:
+			if sel < len(paths)-1 {
+				sel++
+			}
+		case tcell.KeyEnter:
+			if len(paths) == 0 {
+				continue
+			}
+			f, err := gorillas.LoadReplay(paths[sel])
+			var r *gorillas.Replay
+			if err == nil {
+				r, err = gorillas.NewReplay(f)
+			}
+			if err != nil {
+				message = err.Error()
+				continue
+			}
+			r.Game.Settings.UseSound = useSound
+			watchReplay(s, r)
+		}
+	}
+}
+
+// playbackGame wraps a game being played back so it can be drawn on s.
+func playbackGame(s tcell.Screen, game *gorillas.Game, seed int64) *Game {
+	g := &Game{Game: game, screen: s, decor: rand.New(rand.NewSource(seed))}
+	if art, err := gorillas.LoadGorillaArt("assets/gorilla.txt"); err == nil {
+		g.gorillaArt = art
+	} else {
+		g.gorillaArt = [][]string{{" O ", "/|\\", "/ \\"}}
+	}
+	if g.GorillaMask == nil {
+		g.SetGorillaMask(imgdraw.DefaultGorillaSprite(1))
+	}
+	g.AnimationSteps = animationSteps
+	g.initBuildings()
+	g.Game.ResetHook = g.initBuildings
+	return g
+}
+
+// watchReplay draws r until every throw has landed or Escape is pressed.
+func watchReplay(s tcell.Screen, r *gorillas.Replay) {
+	g := playbackGame(s, r.Game, r.File.Seed)
+
+	ticker := time.NewTicker(frameDuration)
+	defer ticker.Stop()
+	last := time.Now()
+	for !r.Done() {
+		g.draw()
+		cols, rows := s.Size()
+		msg := fmt.Sprintf("REPLAY - %s - Esc to stop", r.Status())
+		drawString(s, (cols-len(msg))/2, rows-1, msg)
+		s.Show()
+		<-ticker.C
+		for s.HasPendingEvent() {
+			if key, ok := s.PollEvent().(*tcell.EventKey); ok && key.Key() == tcell.KeyEscape {
+				return
+			}
+		}
+		now := time.Now()
+		r.Step(now.Sub(last))
+		last = now
+	}
+}
+
+// showBestShots lists the highlight reel kept in file and throws the chosen
+// shot again, returning to the list afterwards until Escape is pressed.
+func showBestShots(s tcell.Screen, file string, useSound bool) {
+	reel, err := gorillas.LoadHighlights(file)
+	message := ""
+	if err != nil {
+		message = err.Error()
+	} else if len(reel) == 0 {
+		message = "No best shots yet - win a round to make the reel"
+	}
+	sel := 0
+	for {
+		s.Clear()
+		w, h := s.Size()
+		drawString(s, (w-10)/2, 1, "BEST SHOTS")
+		for i, hl := range reel {
+			prefix := "  "
+			if i == sel {
+				prefix = "> "
+			}
+			drawString(s, 2, 3+i, prefix+hl.String())
+		}
+		if message != "" {
+			drawString(s, (w-len(message))/2, 4+len(reel), message)
+		}
+		help := "Up/Down choose, Enter watch, Esc back"
+		drawString(s, (w-len(help))/2, h-1, help)
+		s.Show()
+		key, ok := s.PollEvent().(*tcell.EventKey)
+		if !ok {
+			continue
+		}
+		switch key.Key() {
+		case tcell.KeyEscape:
+			return
+		case tcell.KeyUp:
+			if sel > 0 {
+				sel--
+			}
+		case tcell.KeyDown:
+			if sel < len(reel)-1 {
+				sel++
+			}
+		case tcell.KeyEnter:
+			if len(reel) == 0 {
+				continue
+			}
+			r, err := gorillas.NewHighlightReplay(reel[sel])
+			if err != nil {
+				message = err.Error()
+				continue
+			}
+			r.Game.Settings.UseSound = useSound
+			g := playbackGame(s, r.Game, reel[sel].Shot.Seed)
+			g.instant = r
+			g.showShotReplay("BEST SHOT - press any key to stop")
+		}
+	}
+}
+
+// showInstantReplay plays the pending instant replay over the live game,
+// tracing the banana's path, until it ends or a key is pressed.
+func (g *Game) showInstantReplay() {
+	g.showShotReplay("INSTANT REPLAY - press any key to skip")
+}
+
+// showShotReplay plays g.instant in place of g's game, tracing the banana's
+// path under msg, until it ends or a key is pressed.
+func (g *Game) showShotReplay(msg string) {
+	r := g.instant
+	g.instant = nil
+	live := g.Game
+	defer func() { g.Game = live }()
+	g.Game = r.Game
+
+	ticker := time.NewTicker(frameDuration)
+	defer ticker.Stop()
+	last := time.Now()
+	for !r.Done() {
+		g.draw()
+		for _, p := range r.Trail {
+			x, y := g.toCell(p.X, p.Y)
+			g.screen.SetContent(x, y, '.', nil, tcell.StyleDefault)
+		}
+		cols, rows := g.screen.Size()
+		drawString(g.screen, (cols-len(msg))/2, rows-1, msg)
+		g.screen.Show()
+		<-ticker.C
+		for g.screen.HasPendingEvent() {
+			if _, ok := g.screen.PollEvent().(*tcell.EventKey); ok {
+				return
+			}
+		}
+		now := time.Now()
+		r.Step(now.Sub(last))
+		last = now
+	}
+}
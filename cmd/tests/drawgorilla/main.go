@@ -6,6 +6,7 @@ import (
 	"image/draw"
 	"image/gif"
 	"image/png"
+	"math/rand"
 	"os"
 
 	imgdraw "github.com/arran4/gorillas/drawings/img"
@@ -31,7 +32,7 @@ func makeFrame(scale float64, width, height int, arms int) *image.Paletted {
 	if bH < 1 {
 		bH = 1
 	}
-	building := imgdraw.CreateBuildingSprite(float64(bW), float64(bH), color.RGBA{100, 100, 100, 255})
+	building := imgdraw.CreateBuildingSprite(float64(bW), float64(bH), color.RGBA{100, 100, 100, 255}, rand.New(rand.NewSource(1)))
 	bx := int(x - float64(bW)/2 + 0.5)
 	by := height - bH
 	draw.Draw(img, image.Rect(bx, by, bx+bW, by+bH), building, image.Point{}, draw.Over)
@@ -33,9 +33,8 @@ func main() {
 	}
 	fmt.Printf("Created %s\n", stateFile)
 
-	// Run the headless renderer
-	// We use "go run ./cmd/gorillia-ebiten" to run the main package
-	cmd := exec.Command("go", "run", "./cmd/gorillia-ebiten", "-render-state", stateFile, "-output-image", "verify_output.png")
+	// Run the software renderer, which needs no GPU, display or sound
+	cmd := exec.Command("go", "run", "./cmd/gorillas-render", "-o", "verify_output.png", stateFile)
 	cmd.Stdout = os.Stdout
 	cmd.Stderr = os.Stderr
 	fmt.Println("Running renderer...")
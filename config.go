@@ -23,6 +23,11 @@ import (
 //			     GORILLAS_WINNER_FIRST - 'true' if round winner starts the next round.
 //			GORILLAS_VARIABLE_WIND - 'true' to mimic BASIC wind changes each round.
 //		     GORILLAS_WIND_FLUCT - 'true' to vary wind slightly each throw.
+//			GORILLAS_MATCH_MODE - 'bestof' or 'firstto' to choose how a match is won.
+//			GORILLAS_FRIENDLY_FIRE - 'self', 'off' or 'ignored' for hits on teammates.
+//			GORILLAS_SKYLINE - name of the skyline generator, such as 'valley'.
+//			GORILLAS_INSTANT_REPLAY - 'true' to replay each winning throw in slow motion.
+//			GORILLAS_AI_LEVEL - 'easy', 'normal', 'hard' or 'perfect' for computer players.
 func loadSettingsFile(path string, s *Settings) {
 	f, err := os.Open(path)
 	if err != nil {
@@ -126,6 +131,30 @@ func loadSettingsFile(path string, s *Settings) {
 			} else if strings.EqualFold(val, "NO") {
 				s.WindFluctuations = false
 			}
+		case "MATCHMODE":
+			if m, err := ParseMatchMode(val); err == nil {
+				s.MatchMode = m
+			}
+		case "FRIENDLYFIRE":
+			if f, err := ParseFriendlyFire(val); err == nil {
+				s.FriendlyFire = f
+			}
+		case "SKYLINE":
+			if _, err := SkylineByName(val); err == nil {
+				s.Skyline = val
+			}
+		case "INSTANTREPLAY":
+			if b, err := strconv.ParseBool(val); err == nil {
+				s.InstantReplay = b
+			} else if strings.EqualFold(val, "YES") {
+				s.InstantReplay = true
+			} else if strings.EqualFold(val, "NO") {
+				s.InstantReplay = false
+			}
+		case "AILEVEL":
+			if l, err := ParseAILevel(val); err == nil {
+				s.AILevel = l
+			}
 		}
 	}
 }
@@ -205,5 +234,31 @@ func LoadSettings() Settings {
 			s.WindFluctuations = b
 		}
 	}
+	if v, ok := os.LookupEnv("GORILLAS_MATCH_MODE"); ok {
+		if m, err := ParseMatchMode(v); err == nil {
+			s.MatchMode = m
+		}
+	}
+	if v, ok := os.LookupEnv("GORILLAS_FRIENDLY_FIRE"); ok {
+		if f, err := ParseFriendlyFire(v); err == nil {
+			s.FriendlyFire = f
+		}
+	}
+	if v, ok := os.LookupEnv("GORILLAS_SKYLINE"); ok {
+		if _, err := SkylineByName(v); err == nil {
+			s.Skyline = strings.TrimSpace(v)
+		}
+	}
+	if v, ok := os.LookupEnv("GORILLAS_INSTANT_REPLAY"); ok {
+		v = strings.TrimSpace(v)
+		if b, err := strconv.ParseBool(v); err == nil {
+			s.InstantReplay = b
+		}
+	}
+	if v, ok := os.LookupEnv("GORILLAS_AI_LEVEL"); ok {
+		if l, err := ParseAILevel(v); err == nil {
+			s.AILevel = l
+		}
+	}
 	return s
 }
@@ -82,7 +82,12 @@ func TestLoadSettingsFile(t *testing.T) {
 		"WinnerFirst=yes\n" +
 		"VariableWind=yes\n" +
 		"WindFluctuations=yes\n" +
-		"UseVectorExplosions=yes\n")
+		"UseVectorExplosions=yes\n" +
+		"MatchMode=first-to\n" +
+		"FriendlyFire=off\n" +
+		"Skyline=canyon\n" +
+		"InstantReplay=yes\n" +
+		"AILevel=hard\n")
 	if err := os.WriteFile(ini, data, 0644); err != nil {
 		t.Fatal(err)
 	}
@@ -124,4 +129,19 @@ func TestLoadSettingsFile(t *testing.T) {
 	if !s.UseVectorExplosions {
 		t.Errorf("expected UseVectorExplosions=true")
 	}
+	if s.MatchMode != MatchFirstTo {
+		t.Errorf("expected MatchMode=firstto got %v", s.MatchMode)
+	}
+	if s.FriendlyFire != FriendlyFireOff {
+		t.Errorf("expected FriendlyFire=off got %v", s.FriendlyFire)
+	}
+	if s.Skyline != "canyon" {
+		t.Errorf("expected Skyline=canyon got %q", s.Skyline)
+	}
+	if !s.InstantReplay {
+		t.Errorf("expected InstantReplay=true")
+	}
+	if s.AILevel != AIHard {
+		t.Errorf("expected AILevel=hard got %v", s.AILevel)
+	}
 }
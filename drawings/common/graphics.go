@@ -1,15 +1,14 @@
 package drawcommon
 
 import (
+	"image"
 	"image/color"
 	"image/draw"
 	"math"
-
-	gorillas "github.com/arran4/gorillas"
 )
 
-// drawLine renders a straight line between two points using simple pixel plots.
-func drawLine(img draw.Image, x1, y1, x2, y2 float64, clr color.Color) {
+// DrawLine renders a straight line between two points using simple pixel plots.
+func DrawLine(img draw.Image, x1, y1, x2, y2 float64, clr color.Color) {
 	dx := x2 - x1
 	dy := y2 - y1
 	n := int(math.Max(math.Abs(dx), math.Abs(dy)))
@@ -24,18 +23,6 @@ func drawLine(img draw.Image, x1, y1, x2, y2 float64, clr color.Color) {
 	}
 }
 
-// DrawVectorLines joins a series of points with lines of the specified colour.
-func DrawVectorLines(img draw.Image, pts []gorillas.VectorPoint, clr color.Color) {
-	if len(pts) == 0 {
-		return
-	}
-	prev := pts[0]
-	for _, p := range pts[1:] {
-		drawLine(img, prev.X, prev.Y, p.X, p.Y, clr)
-		prev = p
-	}
-}
-
 // DrawFilledRect fills a rectangle between two points.
 func DrawFilledRect(img draw.Image, x1, y1, x2, y2 float64, clr color.Color) {
 	if x2 < x1 {
@@ -90,7 +77,7 @@ func DrawArc(img draw.Image, cx, cy, r float64, startDeg, endDeg float64, clr co
 	for a := startDeg + step; a <= endDeg; a += step {
 		x := cx + r*math.Cos(a*math.Pi/180)
 		y := cy - r*math.Sin(a*math.Pi/180)
-		drawLine(img, prevX, prevY, x, y, clr)
+		DrawLine(img, prevX, prevY, x, y, clr)
 		prevX, prevY = x, y
 	}
 }
@@ -125,7 +112,7 @@ func DrawBASSun(img draw.Image, cx, cy, r float64, shocked bool, clr color.Color
 		y1 := cy - r*math.Sin(ang)
 		x2 := cx + (r+rayLen)*math.Cos(ang)
 		y2 := cy - (r+rayLen)*math.Sin(ang)
-		drawLine(img, x1, y1, x2, y2, clr)
+		DrawLine(img, x1, y1, x2, y2, clr)
 	}
 	scale := r / 12
 	eyeX := 3 * scale
@@ -143,12 +130,12 @@ func DrawBASSun(img draw.Image, cx, cy, r float64, shocked bool, clr color.Color
 func DrawBASGorilla(img draw.Image, x, y, scale float64, arms int, clr color.Color) {
 	S := func(v float64) float64 { return v * scale }
 	DrawFilledCircle(img, x, y+S(3.5), S(4.5), clr)
-	drawLine(img, x-S(3), y+S(2), x+S(2), y+S(2), color.Black)
+	DrawLine(img, x-S(3), y+S(2), x+S(2), y+S(2), color.Black)
 	for i := -2.0; i <= -1.0; i++ {
 		DrawFilledRect(img, x+S(i), y+S(4), x+S(i)+1, y+S(4)+1, color.Black)
 		DrawFilledRect(img, x+S(i+3), y+S(4), x+S(i+3)+1, y+S(4)+1, color.Black)
 	}
-	drawLine(img, x-S(3), y+S(7), x+S(2), y+S(7), clr)
+	DrawLine(img, x-S(3), y+S(7), x+S(2), y+S(7), clr)
 	// body uses stacked rectangles like the BASIC original
 	DrawFilledRect(img, x-S(8), y+S(8), x+S(6.9), y+S(14), clr)
 	DrawFilledRect(img, x-S(6), y+S(14), x+S(4.9), y+S(20), clr)
@@ -174,6 +161,16 @@ func DrawBASGorilla(img draw.Image, x, y, scale float64, arms int, clr color.Col
 	}
 }
 
+// GorillaSprite returns a basic gorilla sprite rendered at the provided
+// scale. The game hits gorillas where its opaque pixels are.
+func GorillaSprite(scale float64) *image.RGBA {
+	size := int(30 * scale)
+	img := image.NewRGBA(image.Rect(0, 0, size, size))
+	clr := color.RGBA{150, 75, 0, 255}
+	DrawBASGorilla(img, 15*scale, scale, scale, ArmsDown, clr)
+	return img
+}
+
 // ClearRect clears a rectangle region on the image by setting pixels transparent.
 func ClearRect(img draw.Image, x, y, w, h int) {
 	for dx := 0; dx < w; dx++ {
@@ -13,7 +13,14 @@ import (
 
 // DrawVectorLines joins a series of points with lines of the specified colour.
 func DrawVectorLines(img *ebiten.Image, pts []gorillas.VectorPoint, clr color.Color) {
-	drawcommon.DrawVectorLines(img, pts, clr)
+	if len(pts) == 0 {
+		return
+	}
+	prev := pts[0]
+	for _, p := range pts[1:] {
+		drawcommon.DrawLine(img, prev.X, prev.Y, p.X, p.Y, clr)
+		prev = p
+	}
 }
 
 // DrawFilledRect fills a rectangle between two points.
@@ -131,8 +138,9 @@ func DefaultGorillaSprite(scale float64) *ebiten.Image {
 	return img
 }
 
-// CreateBuildingSprite produces a simple building with random windows.
-func CreateBuildingSprite(w, h float64, clr color.Color) *ebiten.Image {
+// CreateBuildingSprite produces a simple building with windows lit at random
+// using rng.
+func CreateBuildingSprite(w, h float64, clr color.Color, rng *rand.Rand) *ebiten.Image {
 	iw := int(w)
 	ih := int(h)
 	img := ebiten.NewImage(iw, ih)
@@ -140,7 +148,7 @@ func CreateBuildingSprite(w, h float64, clr color.Color) *ebiten.Image {
 	winClr := color.RGBA{255, 255, 0, 255}
 	for x := 3; x < iw-3; x += 6 {
 		for y := ih - 3; y > 3; y -= 6 {
-			if rand.Intn(3) != 0 {
+			if rng.Intn(3) != 0 {
 				for dx := 0; dx < 3; dx++ {
 					for dy := 0; dy < 3; dy++ {
 						img.Set(x+dx, y+dy, winClr)
@@ -152,6 +160,26 @@ func CreateBuildingSprite(w, h float64, clr color.Color) *ebiten.Image {
 	return img
 }
 
+// CreateMapBuildingSprite produces a building with the lit windows a map
+// file placed on it, measured from its top left corner.
+func CreateMapBuildingSprite(w, h float64, clr color.Color, windows []gorillas.Window) *ebiten.Image {
+	img := ebiten.NewImage(int(w), int(h))
+	img.Fill(clr)
+	winClr := color.RGBA{255, 255, 0, 255}
+	for _, win := range windows {
+		if !win.Lit {
+			continue
+		}
+		x, y := int(win.X), int(win.Y)
+		for dx := 0; dx < 3; dx++ {
+			for dy := 0; dy < 3; dy++ {
+				img.Set(x+dx, y+dy, winClr)
+			}
+		}
+	}
+	return img
+}
+
 // ClearRect clears a rectangle region on the image by setting pixels transparent.
 func ClearRect(img *ebiten.Image, x, y, w, h int) {
 	drawcommon.ClearRect(img, x, y, w, h)
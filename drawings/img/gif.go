@@ -0,0 +1,117 @@
+package imgdraw
+
+import (
+	"errors"
+	"image"
+	"image/color"
+	"image/color/palette"
+	"image/draw"
+	"image/gif"
+	"io"
+	"time"
+
+	gorillas "github.com/arran4/gorillas"
+)
+
+// GIFFrameSteps is how many physics steps pass between the frames of an
+// exported GIF, giving about 30 frames a second.
+const GIFFrameSteps = 2
+
+// gifFrameDelay is how long each frame shows, in hundredths of a second.
+const gifFrameDelay = 3
+
+// gifHoldDelay is how long the final frame stays up before the GIF loops.
+const gifHoldDelay = 150
+
+// MaxGIFFrames stops an export that never finishes from filling the disk.
+const MaxGIFFrames = 20000
+
+// Playback plays a game forward, as gorillas.Replay and gorillas.ShotReplay
+// do.
+type Playback interface {
+	Step(dt time.Duration)
+	Done() bool
+}
+
+// Animation collects rendered frames of a game into an animated GIF. Each
+// frame carries its own palette so explosion colours come out exactly.
+type Animation struct {
+	GIF gif.GIF
+}
+
+// AddFrame renders g as the next frame.
+func (a *Animation) AddFrame(g *gorillas.Game) {
+	a.GIF.Image = append(a.GIF.Image, paletted(Render(g)))
+	a.GIF.Delay = append(a.GIF.Delay, gifFrameDelay)
+}
+
+// Record adds a frame of g every GIFFrameSteps steps of p until p is done,
+// then holds the last frame for a moment.
+func (a *Animation) Record(g *gorillas.Game, p Playback) error {
+	return a.RecordUntil(g, p, func() bool { return false })
+}
+
+// RecordUntil adds a frame of g every GIFFrameSteps steps of p until stop
+// reports true or p is done, then holds the last frame for a moment. The
+// step that ends the recording is left out, since it may already have
+// raised the next round's city.
+func (a *Animation) RecordUntil(g *gorillas.Game, p Playback, stop func() bool) error {
+	a.AddFrame(g)
+	for !stop() && !p.Done() {
+		if len(a.GIF.Image) >= MaxGIFFrames {
+			return errors.New("gif: playback is too long to export")
+		}
+		p.Step(GIFFrameSteps * gorillas.StepDuration)
+		if stop() || p.Done() {
+			break
+		}
+		a.AddFrame(g)
+	}
+	a.GIF.Delay[len(a.GIF.Delay)-1] = gifHoldDelay
+	return nil
+}
+
+// Encode writes the animation as a looping GIF.
+func (a *Animation) Encode(w io.Writer) error {
+	if len(a.GIF.Image) == 0 {
+		return errors.New("gif: no frames to write")
+	}
+	return gif.EncodeAll(w, &a.GIF)
+}
+
+// EncodeGIF plays p to the end, writing each frame of g to w as a GIF.
+func EncodeGIF(w io.Writer, g *gorillas.Game, p Playback) error {
+	var a Animation
+	if err := a.Record(g, p); err != nil {
+		return err
+	}
+	return a.Encode(w)
+}
+
+// paletted converts img to a paletted image, using exactly the colours it
+// holds when there are few enough and the Plan 9 palette otherwise.
+func paletted(img *image.RGBA) *image.Paletted {
+	b := img.Bounds()
+	index := map[color.RGBA]uint8{}
+	var pal color.Palette
+	for i := 0; i+3 < len(img.Pix); i += 4 {
+		c := color.RGBA{img.Pix[i], img.Pix[i+1], img.Pix[i+2], img.Pix[i+3]}
+		if _, ok := index[c]; ok {
+			continue
+		}
+		if len(pal) == 256 {
+			out := image.NewPaletted(b, palette.Plan9)
+			draw.Draw(out, b, img, b.Min, draw.Src)
+			return out
+		}
+		index[c] = uint8(len(pal))
+		pal = append(pal, c)
+	}
+	out := image.NewPaletted(b, pal)
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			out.SetColorIndex(x, y, index[img.RGBAAt(x, y)])
+		}
+	}
+	return out
+}
@@ -0,0 +1,61 @@
+package imgdraw
+
+import (
+	"bytes"
+	"image/color"
+	"image/gif"
+	"path/filepath"
+	"testing"
+)
+
+func TestEncodeGIFOfAThrow(t *testing.T) {
+	g := newRenderGame(t)
+	g.ScoreFile = filepath.Join(t.TempDir(), "scores.json")
+	g.Settings.UseSound = false
+	g.Settings.InstantReplay = true
+	// a calm, weak throw blows up on the next roof
+	g.Wind = 0
+	g.Angle, g.Power = 60, 30
+	g.Throw()
+	r := g.InstantReplay()
+	if r == nil {
+		t.Fatal("expected a replay of the throw")
+	}
+
+	var buf bytes.Buffer
+	if err := EncodeGIF(&buf, r.Game, r); err != nil {
+		t.Fatal(err)
+	}
+	anim, err := gif.DecodeAll(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(anim.Image) < 10 {
+		t.Fatalf("expected the whole flight, got %d frames", len(anim.Image))
+	}
+	if b := anim.Image[0].Bounds(); b.Dx() != g.Width || b.Dy() != g.Height {
+		t.Fatalf("frames are %v, want %dx%d", b, g.Width, g.Height)
+	}
+	if anim.Delay[len(anim.Delay)-1] <= anim.Delay[0] {
+		t.Fatal("the last frame should be held")
+	}
+	red := color.RGBA{255, 0, 0, 255}
+	found := false
+	for _, frame := range anim.Image {
+		for _, c := range frame.Palette {
+			if color.RGBAModel.Convert(c) == red {
+				found = true
+			}
+		}
+	}
+	if !found {
+		t.Fatal("expected the explosion's red frame in the GIF")
+	}
+}
+
+func TestEncodeGIFWithoutFrames(t *testing.T) {
+	var a Animation
+	if err := a.Encode(&bytes.Buffer{}); err == nil {
+		t.Fatal("expected an error for an empty animation")
+	}
+}
@@ -0,0 +1,118 @@
+package imgdraw
+
+import (
+	"bytes"
+	"image"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+
+	gorillas "github.com/arran4/gorillas"
+	"github.com/arran4/gorillas/internal/gorillastest"
+)
+
+// goldenChannelTolerance is how far a colour channel may drift before a
+// pixel counts as changed, and goldenPixelTolerance the share of changed
+// pixels a golden image accepts.
+const (
+	goldenChannelTolerance = 8
+	goldenPixelTolerance   = 0.001
+)
+
+// goldenStates are the canonical games the golden images in testdata show.
+var goldenStates = map[string]func(t *testing.T) *gorillas.Game{
+	"city": func(t *testing.T) *gorillas.Game {
+		g := gorillas.NewGameWithSeed(gorillas.WorldWidth, gorillas.WorldHeight, gorillas.DefaultBuildingCount, 3)
+		g.League = nil
+		return g
+	},
+	"damaged": func(t *testing.T) *gorillas.Game {
+		g := newRenderGame(t)
+		for i, x := range []float64{220, 350, 480} {
+			b := &g.Buildings[int(x)/100]
+			b.Damage = append(b.Damage, gorillas.DamageCircle{X: x, Y: 405, R: 12 + float64(i)*8})
+		}
+		g.Sun.Integrity = 2
+		g.Banana = gorillas.Banana{X: 300, Y: 180, VX: -2, VY: 1, Active: true}
+		return g
+	},
+	"explosion": func(t *testing.T) *gorillas.Game {
+		g := newRenderGame(t)
+		g.Settings.UseSound = false
+		g.Wind = 0
+		gorillastest.Explode(t, g, 60, 30, 1)
+		return g
+	},
+	"vector_explosion": func(t *testing.T) *gorillas.Game {
+		g := newRenderGame(t)
+		g.Settings.UseSound = false
+		g.Settings.UseVectorExplosions = true
+		g.Wind = 0
+		gorillastest.Explode(t, g, 60, 30, 2)
+		return g
+	},
+	"cga": func(t *testing.T) *gorillas.Game {
+		g := newRenderGame(t)
+		g.Settings.UseSound = false
+		g.Settings.ForceCGA = true
+		g.Wind = 0
+		gorillastest.Explode(t, g, 60, 30, 1)
+		return g
+	},
+}
+
+// TestGoldenImages renders each golden state and compares it with its PNG in
+// testdata. Run with -update to accept a deliberate change in the picture.
+func TestGoldenImages(t *testing.T) {
+	for name, state := range goldenStates {
+		t.Run(name, func(t *testing.T) {
+			got := Render(state(t))
+			var b bytes.Buffer
+			if err := png.Encode(&b, got); err != nil {
+				t.Fatal(err)
+			}
+			path := filepath.Join("testdata", name+".png")
+			want, err := png.Decode(bytes.NewReader(gorillastest.Golden(t, path, b.Bytes())))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.Bounds() != want.Bounds() {
+				t.Fatalf("image is %v, want %v", got.Bounds(), want.Bounds())
+			}
+			changed := changedPixels(got, want)
+			if float64(changed) > goldenPixelTolerance*float64(got.Bounds().Dx()*got.Bounds().Dy()) {
+				// keep the new picture after the test so it can be looked at
+				dir, err := os.MkdirTemp("", "gorillas-golden-")
+				if err != nil {
+					t.Fatal(err)
+				}
+				out := filepath.Join(dir, name+".png")
+				if err := os.WriteFile(out, b.Bytes(), 0644); err != nil {
+					t.Fatal(err)
+				}
+				t.Errorf("%d pixels differ from %s; the new picture would be %s", changed, path, out)
+			}
+		})
+	}
+}
+
+// changedPixels counts the pixels where a and b differ by more than
+// goldenChannelTolerance in any channel.
+func changedPixels(a, b image.Image) int {
+	n := 0
+	r := a.Bounds()
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		for x := r.Min.X; x < r.Max.X; x++ {
+			ar, ag, ab, aa := a.At(x, y).RGBA()
+			br, bg, bb, ba := b.At(x, y).RGBA()
+			for _, d := range [][2]uint32{{ar, br}, {ag, bg}, {ab, bb}, {aa, ba}} {
+				if diff := int(d[0]>>8) - int(d[1]>>8); diff > goldenChannelTolerance || -diff > goldenChannelTolerance {
+					n++
+					break
+				}
+			}
+		}
+	}
+	return n
+}
@@ -12,7 +12,14 @@ import (
 
 // DrawVectorLines joins a series of points with lines of the specified colour.
 func DrawVectorLines(img draw.Image, pts []gorillas.VectorPoint, clr color.Color) {
-	drawcommon.DrawVectorLines(img, pts, clr)
+	if len(pts) == 0 {
+		return
+	}
+	prev := pts[0]
+	for _, p := range pts[1:] {
+		drawcommon.DrawLine(img, prev.X, prev.Y, p.X, p.Y, clr)
+		prev = p
+	}
 }
 
 // DrawFilledRect fills a rectangle between two points.
@@ -122,15 +129,12 @@ func CreateGorillaSprite(mask []string, clr color.Color) *image.RGBA {
 
 // DefaultGorillaSprite returns a basic gorilla sprite rendered at the provided scale.
 func DefaultGorillaSprite(scale float64) *image.RGBA {
-	size := int(30 * scale)
-	img := image.NewRGBA(image.Rect(0, 0, size, size))
-	clr := color.RGBA{150, 75, 0, 255}
-	DrawBASGorilla(img, 15*scale, scale, scale, ArmsDown, clr)
-	return img
+	return drawcommon.GorillaSprite(scale)
 }
 
-// CreateBuildingSprite produces a simple building with random windows.
-func CreateBuildingSprite(w, h float64, clr color.Color) *image.RGBA {
+// CreateBuildingSprite produces a simple building with windows lit at random
+// using rng.
+func CreateBuildingSprite(w, h float64, clr color.Color, rng *rand.Rand) *image.RGBA {
 	iw := int(w)
 	ih := int(h)
 	img := image.NewRGBA(image.Rect(0, 0, iw, ih))
@@ -142,7 +146,7 @@ func CreateBuildingSprite(w, h float64, clr color.Color) *image.RGBA {
 	winClr := color.RGBA{255, 255, 0, 255}
 	for x := 3; x < iw-3; x += 6 {
 		for y := ih - 3; y > 3; y -= 6 {
-			if rand.Intn(3) != 0 {
+			if rng.Intn(3) != 0 {
 				for dx := 0; dx < 3; dx++ {
 					for dy := 0; dy < 3; dy++ {
 						img.Set(x+dx, y+dy, winClr)
@@ -154,6 +158,26 @@ func CreateBuildingSprite(w, h float64, clr color.Color) *image.RGBA {
 	return img
 }
 
+// CreateMapBuildingSprite produces a building with the lit windows a map
+// file placed on it, measured from its top left corner.
+func CreateMapBuildingSprite(w, h float64, clr color.Color, windows []gorillas.Window) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, int(w), int(h)))
+	draw.Draw(img, img.Bounds(), image.NewUniform(clr), image.Point{}, draw.Src)
+	winClr := color.RGBA{255, 255, 0, 255}
+	for _, win := range windows {
+		if !win.Lit {
+			continue
+		}
+		x, y := int(win.X), int(win.Y)
+		for dx := 0; dx < 3; dx++ {
+			for dy := 0; dy < 3; dy++ {
+				img.Set(x+dx, y+dy, winClr)
+			}
+		}
+	}
+	return img
+}
+
 // ClearRect clears a rectangle region on the image by setting pixels transparent.
 func ClearRect(img draw.Image, x, y, w, h int) {
 	drawcommon.ClearRect(img, x, y, w, h)
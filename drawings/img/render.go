@@ -0,0 +1,173 @@
+package imgdraw
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+	"math/rand"
+
+	xdraw "golang.org/x/image/draw"
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/basicfont"
+	"golang.org/x/image/math/fixed"
+
+	gorillas "github.com/arran4/gorillas"
+)
+
+// bananaScale and gorillaScale match the sprite sizes the Ebiten port draws.
+const (
+	bananaScale  = 4
+	gorillaScale = 1
+)
+
+// Render draws g into a new image the size of its world, laid out like the
+// Ebiten port's play screen. It needs no GPU or display.
+func Render(g *gorillas.Game) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, g.Width, g.Height))
+	DrawGame(img, g)
+	return img
+}
+
+// DrawGame paints the sky, buildings with their damage, gorillas, banana,
+// explosion, sun, wind arrow and status line of g onto img. Building colours
+// and windows the game leaves open are chosen from g.Seed, as the Ebiten
+// port does, so the same state always renders the same picture.
+func DrawGame(img draw.Image, g *gorillas.Game) {
+	draw.Draw(img, img.Bounds(), image.NewUniform(color.RGBA{0, 0, 255, 255}), image.Point{}, draw.Src)
+	drawBuildings(img, g)
+	gorilla := DefaultGorillaSprite(gorillaScale)
+	for _, gr := range g.Gorillas {
+		if gr.Dead {
+			continue
+		}
+		b := gorilla.Bounds()
+		at := image.Pt(int(gr.X-float64(b.Dx())/2), int(gr.Y-float64(b.Dy())))
+		draw.Draw(img, b.Add(at), gorilla, image.Point{}, draw.Over)
+	}
+	if g.Banana.Active {
+		drawBanana(img, g.Banana)
+	}
+	if e := g.Explosion; e.Active && e.Frame < len(e.Radii) {
+		clr := color.RGBA{255, 255, 0, 255}
+		if e.Frame < len(e.Colors) {
+			clr = e.Colors[e.Frame]
+		}
+		if g.Settings.UseVectorExplosions && e.Frame > 0 && e.Frame-1 < len(e.Vectors) {
+			DrawVectorLines(img, e.Vectors[e.Frame-1], clr)
+		} else {
+			DrawFilledCircle(img, e.X, e.Y, e.Radii[e.Frame], clr)
+		}
+	}
+	if g.Sun.Integrity > 0 {
+		DrawBASSun(img, g.Sun.X, g.Sun.Y, g.Sun.Radius(), false, color.RGBA{255, 255, 0, 255})
+	}
+	drawWindArrow(img, g)
+	drawStatus(img, g)
+}
+
+// drawBuildings draws each building's sprite with its craters cut out.
+func drawBuildings(img draw.Image, g *gorillas.Game) {
+	decor := rand.New(rand.NewSource(g.Seed))
+	for _, b := range g.Buildings {
+		// leave a one pixel gap between neighbouring buildings
+		w := math.Max(b.W-1, 1)
+		clr := b.Color
+		if clr.A == 0 {
+			clr = color.RGBA{uint8(decor.Intn(200)), uint8(decor.Intn(200)), uint8(decor.Intn(200)), 255}
+		}
+		var sprite *image.RGBA
+		if b.Windows != nil {
+			sprite = CreateMapBuildingSprite(w, b.H, clr, b.Windows)
+		} else {
+			sprite = CreateBuildingSprite(w, b.H, clr, decor)
+		}
+		top := g.Height - int(b.H)
+		for _, d := range b.Damage {
+			ClearCircle(sprite, int(d.X-b.X), int(d.Y-float64(top)), d.R)
+		}
+		at := image.Pt(int(b.X), top)
+		draw.Draw(img, sprite.Bounds().Add(at), sprite, image.Point{}, draw.Over)
+	}
+}
+
+// drawBanana draws the banana sprite turned the way it is travelling.
+func drawBanana(img draw.Image, b gorillas.Banana) {
+	left, right, up, down := CreateBananaSprites()
+	sprite := up
+	switch {
+	case math.Abs(b.VX) > math.Abs(b.VY) && b.VX < 0:
+		sprite = left
+	case math.Abs(b.VX) > math.Abs(b.VY):
+		sprite = right
+	case b.VY >= 0:
+		sprite = down
+	}
+	w, h := sprite.Bounds().Dx()*bananaScale, sprite.Bounds().Dy()*bananaScale
+	x, y := int(b.X-float64(w)/2), int(b.Y-float64(h)/2)
+	xdraw.NearestNeighbor.Scale(img, image.Rect(x, y, x+w, y+h), sprite, sprite.Bounds(), draw.Over, nil)
+}
+
+// drawWindArrow draws the wind arrow near the top of the screen.
+func drawWindArrow(img draw.Image, g *gorillas.Game) {
+	if g.Wind == 0 {
+		return
+	}
+	length := g.Wind * 3 * float64(g.Width) / 320
+	y := float64(g.Height) / 40
+	x := float64(g.Width) / 2
+	end := x + length
+	head := 5.0
+	if length < 0 {
+		head = -head
+	}
+	clr := color.RGBA{255, 255, 0, 255}
+	DrawVectorLines(img, []gorillas.VectorPoint{{X: x, Y: y}, {X: end, Y: y}}, clr)
+	DrawVectorLines(img, []gorillas.VectorPoint{{X: end - head, Y: y - 3}, {X: end, Y: y}, {X: end - head, Y: y + 3}}, clr)
+}
+
+// drawStatus writes the current player's line along the top and the latest
+// shot message across the middle.
+func drawStatus(img draw.Image, g *gorillas.Game) {
+	if g.Current < 0 || g.Current >= len(g.Gorillas) {
+		return
+	}
+	name := fmt.Sprintf("Player %d", g.Current+1)
+	if g.Current < len(g.Players) {
+		name = g.Players[g.Current]
+	}
+	if g.Teams != nil {
+		name += fmt.Sprintf(", Team %d", g.Side(g.Current)+1)
+	}
+	info := fmt.Sprintf("%s - Angle:%3.0f° Power:%3.0f Wind:%+2.0f Score:%s",
+		name, g.Angle, g.Power, g.Wind, g.ScoreString())
+	x := 0
+	// players on the right of the city read their status on the right
+	if g.Gorillas[g.Current].X > float64(g.Width)/2 {
+		x = max(g.Width-textWidth(info), 0)
+	}
+	DrawText(img, info, x, 0, color.White)
+	if g.LastEvent != gorillas.EventNone && g.LastEventMsg != "" {
+		DrawText(img, g.LastEventMsg, (g.Width-textWidth(g.LastEventMsg))/2, g.Height/3, color.White)
+	}
+}
+
+// textFace is the bitmap font the renderer writes text in.
+var textFace = basicfont.Face7x13
+
+// textWidth returns how many pixels wide s is when drawn with DrawText.
+func textWidth(s string) int {
+	return font.MeasureString(textFace, s).Round()
+}
+
+// DrawText writes s with its top left corner at x, y.
+func DrawText(img draw.Image, s string, x, y int, clr color.Color) {
+	d := font.Drawer{
+		Dst:  img,
+		Src:  image.NewUniform(clr),
+		Face: textFace,
+		Dot:  fixed.P(x, y+textFace.Ascent),
+	}
+	d.DrawString(s)
+}
@@ -0,0 +1,68 @@
+package imgdraw
+
+import (
+	"bytes"
+	"image/color"
+	"testing"
+
+	gorillas "github.com/arran4/gorillas"
+)
+
+// newRenderGame returns a game on a plain city of red buildings without
+// windows so individual pixels are easy to predict.
+func newRenderGame(t *testing.T) *gorillas.Game {
+	t.Helper()
+	g := gorillas.NewGameWithSeed(gorillas.WorldWidth, gorillas.WorldHeight, gorillas.DefaultBuildingCount, 1)
+	g.League = nil
+	city := &gorillas.CityMap{Wind: gorillas.Fixed(5)}
+	for i := 0; i < 8; i++ {
+		city.Buildings = append(city.Buildings, gorillas.MapBuilding{
+			X: float64(i) * 100, Width: 100, Height: 200, Colour: "#c00000",
+			Windows: []gorillas.Window{{X: 1, Y: 1}},
+		})
+	}
+	if err := g.ApplyMap(city); err != nil {
+		t.Fatal(err)
+	}
+	return g
+}
+
+func TestRenderDrawsTheCity(t *testing.T) {
+	g := newRenderGame(t)
+	g.Buildings[3].Damage = append(g.Buildings[3].Damage, gorillas.DamageCircle{X: 350, Y: 400, R: 10})
+	g.Explosion = gorillas.Explosion{X: 550, Y: 300, Radii: []float64{20}, Colors: []color.RGBA{{0, 255, 0, 255}}, Active: true}
+	img := Render(g)
+
+	sky := color.RGBA{0, 0, 255, 255}
+	for name, want := range map[string]struct {
+		x, y int
+		clr  color.RGBA
+	}{
+		"sky":       {20, 200, sky},
+		"building":  {150, 500, color.RGBA{0xc0, 0, 0, 255}},
+		"gap":       {199, 500, sky},
+		"crater":    {350, 400, sky},
+		"explosion": {550, 300, color.RGBA{0, 255, 0, 255}},
+	} {
+		if got := img.RGBAAt(want.x, want.y); got != want.clr {
+			t.Errorf("%s at %d,%d is %v, want %v", name, want.x, want.y, got, want.clr)
+		}
+	}
+	gr := g.Gorillas[0]
+	if img.RGBAAt(int(gr.X), int(gr.Y)-10) == sky {
+		t.Error("expected a gorilla standing on its building")
+	}
+}
+
+func TestRenderIsRepeatable(t *testing.T) {
+	g := gorillas.NewGameWithSeed(gorillas.WorldWidth, gorillas.WorldHeight, gorillas.DefaultBuildingCount, 7)
+	g.League = nil
+	g.Banana = gorillas.Banana{X: 400, Y: 100, VX: 3, Active: true}
+	a, b := Render(g), Render(g)
+	if !bytes.Equal(a.Pix, b.Pix) {
+		t.Fatal("rendering the same state twice should give the same picture")
+	}
+	if a.RGBAAt(400, 100) != (color.RGBA{255, 255, 0, 255}) {
+		t.Fatalf("expected the banana at 400,100, got %v", a.RGBAAt(400, 100))
+	}
+}
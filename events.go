@@ -0,0 +1,141 @@
+package gorillas
+
+// EventKind identifies what happened in a GameEvent.
+type EventKind int
+
+const (
+	// ThrowStarted is published when a banana leaves a gorilla's hands.
+	ThrowStarted EventKind = iota
+	// BananaMoved is published after every step the banana stays in flight.
+	BananaMoved
+	// BuildingHit is published when the banana explodes against a building.
+	BuildingHit
+	// SunHit is published when the banana passes into the sun.
+	SunHit
+	// GorillaKilled is published when the thrower hits another gorilla.
+	GorillaKilled
+	// SelfKill is published when the thrower is caught in their own blast,
+	// or knocks out a teammate under FriendlyFireSelf.
+	SelfKill
+	// WeakShot is published when a miss falls well short of the target.
+	WeakShot
+	// Backwards is published when a miss was thrown away from the target.
+	Backwards
+	// RoundOver is published once a round has a winner.
+	RoundOver
+	// MatchOver is published after the round that decides the match.
+	MatchOver
+)
+
+var eventKindNames = [...]string{
+	ThrowStarted:  "ThrowStarted",
+	BananaMoved:   "BananaMoved",
+	BuildingHit:   "BuildingHit",
+	SunHit:        "SunHit",
+	GorillaKilled: "GorillaKilled",
+	SelfKill:      "SelfKill",
+	WeakShot:      "WeakShot",
+	Backwards:     "Backwards",
+	RoundOver:     "RoundOver",
+	MatchOver:     "MatchOver",
+}
+
+// String returns the name of the event kind.
+func (k EventKind) String() string {
+	if k >= 0 && int(k) < len(eventKindNames) {
+		return eventKindNames[k]
+	}
+	return "EventKind(?)"
+}
+
+// GameEvent describes a single thing that happened during play. Fields that
+// do not apply to an event's Kind are left at their zero value, except
+// Target and Winner which are -1 when unused.
+type GameEvent struct {
+	Kind EventKind
+	// Player is the gorilla whose turn it was.
+	Player int
+	// Target is the gorilla that was killed.
+	Target int
+	// Winner is the side that took the round or match, -1 for a drawn
+	// match. Sides are players unless the game has teams, see Game.Side.
+	Winner int
+	// X and Y give where the event happened in world units.
+	X, Y float64
+	// Angle and Power are the inputs of the throw.
+	Angle, Power float64
+	// Shots counts the throws the winning side took during the round.
+	Shots int
+}
+
+// Listener receives events published by a Game.
+type Listener interface {
+	HandleEvent(GameEvent)
+}
+
+// ListenerFunc adapts an ordinary function to the Listener interface.
+type ListenerFunc func(GameEvent)
+
+// HandleEvent calls f(e).
+func (f ListenerFunc) HandleEvent(e GameEvent) { f(e) }
+
+// Subscribe registers l to receive every event the game publishes from now
+// on, including after Reset. Listeners are called synchronously, in the order
+// they subscribed. The returned function removes the subscription.
+func (g *Game) Subscribe(l Listener) (unsubscribe func()) {
+	g.nextListener++
+	id := g.nextListener
+	g.listeners = append(g.listeners, subscription{id, l})
+	return func() {
+		for i, s := range g.listeners {
+			if s.id == id {
+				g.listeners = append(g.listeners[:i:i], g.listeners[i+1:]...)
+				return
+			}
+		}
+	}
+}
+
+type subscription struct {
+	id int
+	l  Listener
+}
+
+// event returns a GameEvent of kind k for the current player.
+func (g *Game) event(k EventKind) GameEvent {
+	return GameEvent{Kind: k, Player: g.Current, Target: -1, Winner: -1}
+}
+
+func (g *Game) publish(e GameEvent) {
+	for _, s := range g.listeners {
+		s.l.HandleEvent(e)
+	}
+}
+
+// keepScore persists the league table, scores and any new highlight at the
+// end of each round and the match's replay once it is decided, unless g is
+// only throwing again what was played elsewhere.
+func (g *Game) keepScore(e GameEvent) {
+	if g.replaying {
+		// a game thrown again elsewhere keeps no score of its own
+		return
+	}
+	if e.Kind == MatchOver {
+		g.saveRecording()
+	}
+	if e.Kind != RoundOver {
+		return
+	}
+	if g.League != nil {
+		if g.Teams != nil {
+			g.League.RecordTeamRound(g.teamRoster(), e.Winner, e.Shots)
+		} else {
+			g.League.RecordMatchRound(g.Players, e.Winner, e.Shots)
+		}
+		g.League.Save()
+	}
+	g.SaveScores()
+	if e.Winner == g.Side(e.Player) {
+		g.recordHighlight(e.Player)
+	}
+}
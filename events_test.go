@@ -0,0 +1,167 @@
+package gorillas
+
+import (
+	"math"
+	"reflect"
+	"testing"
+)
+
+func recordKinds(g *Game) *[]EventKind {
+	var kinds []EventKind
+	g.Subscribe(ListenerFunc(func(e GameEvent) {
+		if e.Kind != BananaMoved {
+			kinds = append(kinds, e.Kind)
+		}
+	}))
+	return &kinds
+}
+
+func TestEventsForDirectHit(t *testing.T) {
+	g := newTestGame()
+	g.Angle = 0
+	g.Power = 20
+	g.Current = 0
+	startX := g.Gorillas[0].X
+	startY := g.Gorillas[0].Y
+	g.Gorillas[1] = Gorilla{X: startX + 10, Y: startY}
+	lowerCity(g, startY)
+
+	var over GameEvent
+	g.Subscribe(ListenerFunc(func(e GameEvent) {
+		if e.Kind == RoundOver {
+			over = e
+		}
+	}))
+	kinds := recordKinds(g)
+	g.Throw()
+	g.Step(StepDuration)
+
+	want := []EventKind{ThrowStarted, GorillaKilled, RoundOver}
+	if !reflect.DeepEqual(*kinds, want) {
+		t.Fatalf("events = %v, want %v", *kinds, want)
+	}
+	if over.Winner != 0 || over.Player != 0 || over.Shots != 1 {
+		t.Fatalf("unexpected round over event %+v", over)
+	}
+}
+
+func TestEventsForSelfKill(t *testing.T) {
+	g := newTestGame()
+	lowerCity(g, float64(g.Height))
+	g.Angle = -90
+	g.Power = 20
+	g.Current = 0
+
+	kinds := recordKinds(g)
+	g.Throw()
+	g.Step(StepDuration)
+
+	want := []EventKind{ThrowStarted, SelfKill, RoundOver}
+	if !reflect.DeepEqual(*kinds, want) {
+		t.Fatalf("events = %v, want %v", *kinds, want)
+	}
+}
+
+func TestEventsForBuildingHit(t *testing.T) {
+	g := newTestGame()
+	g.Buildings[2].H = float64(g.Height) - g.Gorillas[0].Y + 5
+	g.RebuildHitMap()
+	g.Angle = 0
+	g.Power = 20
+	g.Current = 0
+
+	kinds := recordKinds(g)
+	g.Throw()
+	g.Step(StepDuration)
+
+	if len(*kinds) < 2 || (*kinds)[1] != BuildingHit {
+		t.Fatalf("expected a building hit after the throw, got %v", *kinds)
+	}
+}
+
+func TestMatchOverEvent(t *testing.T) {
+	g := newTestGame()
+	g.Settings.DefaultRoundQty = 1
+	g.StartMatch()
+	var winner = -2
+	g.Subscribe(ListenerFunc(func(e GameEvent) {
+		if e.Kind == MatchOver {
+			winner = e.Winner
+		}
+	}))
+	g.Current = 0
+	g.handleGorillaKill(1)
+	if winner != 0 {
+		t.Fatalf("match over winner = %d, want 0", winner)
+	}
+}
+
+func TestSunHitCountsEachPassOnce(t *testing.T) {
+	g := newTestGame()
+	lowerCity(g, float64(g.Height))
+	g.Angle = 90
+	g.Power = 40
+	g.Current = 0
+	g.Sun = Sun{X: g.Gorillas[0].X, Y: g.Gorillas[0].Y - 30, R: 8, Integrity: SunMaxIntegrity}
+
+	hits := 0
+	g.Subscribe(ListenerFunc(func(e GameEvent) {
+		if e.Kind == SunHit {
+			hits++
+		}
+	}))
+	g.Throw()
+	for i := 0; i < 200 && g.Banana.Active; i++ {
+		g.step()
+	}
+	// straight up and back down passes through the sun twice
+	if hits != 2 {
+		t.Fatalf("sun hits = %d, want 2", hits)
+	}
+	if g.Sun.Integrity != SunMaxIntegrity-2 {
+		t.Fatalf("sun integrity = %d", g.Sun.Integrity)
+	}
+	if r := g.Sun.Radius(); math.Abs(r-4) > 1e-9 {
+		t.Fatalf("sun radius = %f, want 4", r)
+	}
+}
+
+func TestSubscriptionsSurviveResetAndUnsubscribe(t *testing.T) {
+	g := newTestGame()
+	lowerCity(g, float64(g.Height))
+	n := 0
+	stop := g.Subscribe(ListenerFunc(func(e GameEvent) {
+		if e.Kind == ThrowStarted {
+			n++
+		}
+	}))
+	g.Reset()
+	g.Throw()
+	if n != 1 {
+		t.Fatalf("listener should survive Reset, got %d throws", n)
+	}
+	stop()
+	g.Banana.Active = false
+	g.Throw()
+	if n != 1 {
+		t.Fatalf("listener should stop after unsubscribe, got %d throws", n)
+	}
+}
+
+func TestShotSearchPublishesNothing(t *testing.T) {
+	g := newTestGame()
+	n := 0
+	g.Subscribe(ListenerFunc(func(GameEvent) { n++ }))
+	g.FindShot()
+	if n != 0 {
+		t.Fatalf("shot search published %d events", n)
+	}
+}
+
+func TestShotSearchLeavesTheMatchAlone(t *testing.T) {
+	g := newTestGame()
+	g.FindShot()
+	if g.Match.Played != 0 || g.Match.Wins[0] != 0 || g.Match.Wins[1] != 0 {
+		t.Fatalf("shot search scored the live match: %+v", g.Match)
+	}
+}
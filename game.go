@@ -1,12 +1,24 @@
 package gorillas
 
 import (
+	"context"
+	"encoding/binary"
 	"encoding/json"
 	"fmt"
+	"hash/fnv"
+	"image"
 	"image/color"
 	"math"
 	"math/rand"
 	"os"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	drawcommon "github.com/arran4/gorillas/drawings/common"
 )
 
 type DamageCircle struct {
@@ -17,10 +29,15 @@ type Building struct {
 	X, W, H float64
 	Damage  []DamageCircle
 	Color   color.RGBA
+	// Windows holds the windows a map file drew on the building. Frontends
+	// choose their own when it is empty.
+	Windows []Window `json:",omitempty"`
 }
 
 type Gorilla struct {
 	X, Y float64
+	// Dead marks a gorilla knocked out of the current round.
+	Dead bool `json:",omitempty"`
 }
 
 type Banana struct {
@@ -42,6 +59,15 @@ type Settings struct {
 	WinnerFirst         bool
 	VariableWind        bool
 	WindFluctuations    bool
+	MatchMode           MatchMode
+	// Skyline names the SkylineGenerator that raises each new city.
+	Skyline      string
+	FriendlyFire FriendlyFire
+	// InstantReplay asks frontends to show the throw that decides each round
+	// again in slow motion before the next city is built.
+	InstantReplay bool
+	// AILevel sets how well computer players aim.
+	AILevel AILevel
 }
 
 type Explosion struct {
@@ -63,8 +89,17 @@ const (
 	EventSelf
 )
 
-// EventMessage returns the display text for a given ShotEvent.
+// EventMessage returns the display text for a given ShotEvent, choosing
+// between its variants with the global math/rand source.
+//
+// Deprecated: Game.LastEventMsg holds the text of the latest ShotEvent,
+// chosen from the game's seed so every copy of a game shows the same one.
 func EventMessage(e ShotEvent) string {
+	return eventMessage(rand.Intn, e)
+}
+
+// eventMessage picks the text for e using intn to choose between variants.
+func eventMessage(intn func(int) int, e ShotEvent) string {
 	switch e {
 	case EventWeak:
 		msgs := []string{
@@ -72,7 +107,7 @@ func EventMessage(e ShotEvent) string {
 			"Now that was feeble.",
 			"You can do better than that!",
 		}
-		return msgs[rand.Intn(len(msgs))]
+		return msgs[intn(len(msgs))]
 	case EventBackwards:
 		return "Don't throw it that way!"
 	case EventSelf:
@@ -81,6 +116,22 @@ func EventMessage(e ShotEvent) string {
 	return ""
 }
 
+// SunMaxIntegrity is the number of hits the sun takes before it is gone.
+const SunMaxIntegrity = 4
+
+// Sun is the face watching over the city. Every hit shrinks it.
+type Sun struct {
+	X, Y float64
+	// R is the radius of the undamaged sun.
+	R         float64
+	Integrity int
+}
+
+// Radius returns the current size of the sun in world units.
+func (s Sun) Radius() float64 {
+	return s.R * float64(s.Integrity) / SunMaxIntegrity
+}
+
 // Dance holds temporary state for the winner's victory animation.
 type Dance struct {
 	idx    int
@@ -104,10 +155,18 @@ func NewDance(idx int, frames []float64, baseY float64) Dance {
 	return d
 }
 
-// ShotRecord stores the angle and power for a single throw.
+// ShotRecord stores a single throw along with everything needed to throw it
+// again: who threw it in which round of the match, and the wind, gravity,
+// seed and skyline it was thrown under.
 type ShotRecord struct {
-	Angle float64 `json:"angle"`
-	Power float64 `json:"power"`
+	Angle   float64 `json:"angle"`
+	Power   float64 `json:"power"`
+	Player  int     `json:"player"`
+	Round   int     `json:"round"`
+	Wind    float64 `json:"wind"`
+	Gravity float64 `json:"gravity"`
+	Seed    int64   `json:"seed,omitempty"`
+	Skyline string  `json:"skyline,omitempty"`
 }
 
 func DefaultSettings() Settings {
@@ -123,6 +182,10 @@ func DefaultSettings() Settings {
 		WinnerFirst:         false,
 		VariableWind:        false,
 		WindFluctuations:    false,
+		MatchMode:           MatchBestOf,
+		Skyline:             DefaultSkyline,
+		FriendlyFire:        FriendlyFireSelf,
+		AILevel:             AINormal,
 	}
 }
 
@@ -138,6 +201,14 @@ func (g *Game) LoadScores() {
 			fmt.Fprintf(os.Stderr, "load scores: %v\n", err)
 		}
 	}
+	g.TotalWins = resizeInts(g.TotalWins, g.Sides())
+}
+
+// resizeInts returns s truncated or zero padded to n entries.
+func resizeInts(s []int, n int) []int {
+	out := make([]int, n)
+	copy(out, s)
+	return out
 }
 
 // SaveScores writes the accumulated win totals to disk.
@@ -154,27 +225,27 @@ func (g *Game) SaveScores() {
 	}
 }
 
-// LoadShots reads the shot history from disk.
+// LoadShots reads the highlight reel from disk.
 func (g *Game) LoadShots() {
 	file := g.ShotsFile
 	if file == "" {
-		file = defaultShotsFile
+		file = DefaultShotsFile
 	}
-	b, err := os.ReadFile(file)
-	if err == nil {
-		if err := json.Unmarshal(b, &g.ShotHistory); err != nil {
-			fmt.Fprintf(os.Stderr, "load shots: %v\n", err)
-		}
+	reel, err := LoadHighlights(file)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return
 	}
+	g.Highlights = reel
 }
 
-// SaveShots writes the shot history to disk.
+// SaveShots writes the highlight reel to disk.
 func (g *Game) SaveShots() {
 	file := g.ShotsFile
 	if file == "" {
-		file = defaultShotsFile
+		file = DefaultShotsFile
 	}
-	b, err := json.Marshal(g.ShotHistory)
+	b, err := json.Marshal(g.Highlights)
 	if err == nil {
 		if err := os.WriteFile(file, b, 0644); err != nil {
 			fmt.Fprintf(os.Stderr, "save shots: %v\n", err)
@@ -184,41 +255,101 @@ func (g *Game) SaveShots() {
 
 // StatsString returns a printable summary of wins this session and overall.
 func (g *Game) StatsString() string {
-	session := fmt.Sprintf("Session - P1:%d P2:%d", g.Wins[0], g.Wins[1])
-	total := fmt.Sprintf("Overall - P1:%d P2:%d", g.TotalWins[0], g.TotalWins[1])
+	label := "P"
+	if g.Teams != nil {
+		label = "T"
+	}
+	session := "Session -" + playerScores(label, g.Wins)
+	total := "Overall -" + playerScores(label, g.TotalWins)
 	if g.League != nil {
 		return session + "\n" + total + "\n\n" + g.League.String()
 	}
 	return session + "\n" + total
 }
 
+// ScoreString returns the session wins of every side joined by dashes,
+// such as "2-1".
+func (g *Game) ScoreString() string {
+	return joinScores(g.Wins)
+}
+
+// playerScores formats wins as " P1:a P2:b ...", using label in place of P.
+func playerScores(label string, wins []int) string {
+	var sb strings.Builder
+	for i, w := range wins {
+		fmt.Fprintf(&sb, " %s%d:%d", label, i+1, w)
+	}
+	return sb.String()
+}
+
 type Game struct {
 	Width, Height int
 	Buildings     []Building
-	Gorillas      [2]Gorilla
+	Gorillas      []Gorilla
 	Banana        Banana
 	Explosion     Explosion
 	Dance         Dance
+	Sun           Sun
 	Settings      Settings
 	Angle         float64
 	Power         float64
-	Angles        [2]float64
-	Powers        [2]float64
+	Angles        []float64
+	Powers        []float64
 	Current       int
-	Wins          [2]int
-	TotalWins     [2]int
-	Shots         [2]int
-	LastAngle     [2]float64
-	LastPower     [2]float64
-	Players       [2]string
-	League        *League `json:"-"`
-	ScoreFile     string
-	ShotsFile     string
-	ShotHistory   []ShotRecord
+	Wins          []int
+	TotalWins     []int
+	Shots         []int
+	LastAngle     []float64
+	LastPower     []float64
+	Players       []string
+	// Teams holds the side each seat plays for, numbered from zero. It is
+	// nil when every gorilla plays for itself.
+	Teams     []int
+	League    *League `json:"-"`
+	ScoreFile string
+	// ShotsFile is where the highlight reel is kept between games.
+	ShotsFile   string
+	ShotHistory []ShotRecord
+	// Highlights is the reel of the best round winning shots, best first.
+	Highlights    []Highlight
 	Wind          float64
 	BuildingCount int
 	Gravity       float64
 	HitMap        *HitMap `json:"-"`
+	// Seed is the value Rand was created with. Replaying a match with the
+	// same seed and inputs reproduces it exactly.
+	Seed int64
+	// Rand drives every random decision made by the game.
+	Rand *rand.Rand `json:"-"`
+	// aiRand makes the computer players' hands slip; see aiRng.
+	aiRand *rand.Rand
+	// aims holds what each computer player has learnt this round.
+	aims []aim
+	// Match tracks the rounds played towards DefaultRoundQty.
+	Match *Match
+	// Map, when set, is the arena every round is played in instead of a
+	// randomly generated city. See ApplyMap.
+	Map *CityMap `json:",omitempty"`
+	// Recording holds every throw of the current match along with the city
+	// of each round, ready to be played back with NewReplay.
+	Recording *ReplayFile `json:"-"`
+	// ReplayDir, when set, is the directory each finished match's Recording
+	// is saved to.
+	ReplayDir string
+	// GorillaMask, when set, is the shape used for gorilla hit detection in
+	// place of a small circle. Its bottom centre sits on each gorilla.
+	GorillaMask image.Image `json:"-"`
+
+	// accumulator holds real time passed to Step that has not yet been
+	// simulated.
+	accumulator time.Duration
+	// AnimationSteps is how many steps each frame of an explosion, a victory
+	// dance and the message countdown lasts. Zero or one moves them on every
+	// step; a frontend that draws less often raises it so they stay on screen
+	// as long as they always have. The banana flies the same either way.
+	AnimationSteps int `json:"-"`
+	// animationStep counts the steps since the animations last moved on.
+	animationStep int
 
 	// LastEvent records the outcome of the most recent shot.
 	LastEvent ShotEvent
@@ -228,101 +359,237 @@ type Game struct {
 	LastEventMsg string
 
 	lastStartX float64
+	lastStartY float64
 	lastOtherX float64
 	lastVX     float64
 	ResetHook  func() `json:"-"`
 
 	// roundOver indicates whether the current explosion ends the round.
 	roundOver bool
+	// lastThrow holds the game as it stood just before the latest throw so
+	// InstantReplay can throw it again. It is only kept with
+	// Settings.InstantReplay.
+	lastThrow *Game
+	// replaying marks an InstantReplay copy, which stops once its banana
+	// lands rather than moving on to a new round.
+	replaying bool
+	// flight follows the banana of the latest throw.
+	flight flight
+	// inSun is set while the banana overlaps the sun so each pass counts once.
+	inSun bool
+
+	listeners    []subscription
+	nextListener int
 
 	// Aborted indicates whether the game was aborted mid-play.
 	Aborted bool
 }
 
 const DefaultBuildingCount = 10
+
+// MinPlayers and MaxPlayers bound the number of gorillas in a game.
+const (
+	MinPlayers = 2
+	MaxPlayers = 8
+)
+
+// WorldWidth and WorldHeight give the size of the playing field in world
+// units. Frontends scale the world to their own pixels or cells so the same
+// inputs produce the same trajectories everywhere.
+const (
+	WorldWidth  = 800
+	WorldHeight = 600
+)
+
+// StepDuration is the simulated time covered by a single physics step.
+const StepDuration = time.Second / 60
+
+// maxStepBacklog caps how much time one call to Step may simulate so a
+// stalled frontend does not fast-forward a throw.
+const maxStepBacklog = 250 * time.Millisecond
+
 const defaultScoreFile = "gorillas_scores.json"
-const defaultShotsFile = "gorillas_shots.json"
+
+// DefaultShotsFile is where the highlight reel is kept unless ShotsFile
+// says otherwise.
+const DefaultShotsFile = "gorillas_shots.json"
+
 const defaultLeagueFile = "gorillas.lge"
 const groundBounceFactor = 0.4
 const groundBounceThreshold = 5.0
 const eventDisplayTicks = 40
 
+// NewGame creates a game using a seed drawn from the global math/rand source.
 func NewGame(width, height, buildingCount int) *Game {
+	return NewGameWithSeed(width, height, buildingCount, rand.Int63())
+}
+
+// NewGameWithSeed creates a game whose skyline, wind and every later random
+// event are derived from seed.
+func NewGameWithSeed(width, height, buildingCount int, seed int64) *Game {
+	g := newGame(width, height, buildingCount, MinPlayers, DefaultSettings(), nil, rand.New(rand.NewSource(seed)))
+	g.League = LoadLeague(defaultLeagueFile)
+	g.Seed = seed
+	g.Subscribe(ListenerFunc(g.keepScore))
+	return g
+}
+
+// NewHeadlessGame creates a game derived from seed and played under settings
+// by a program with no screen, such as a simulation or a server. It makes
+// no sound, touches no files and keeps no league unless League is set, and
+// hits its gorillas where the ports draw them.
+func NewHeadlessGame(width, height, buildingCount int, seed int64, settings Settings) *Game {
+	settings.UseSound = false
+	settings.UseSlidingText = false
+	g := newGame(width, height, buildingCount, MinPlayers, settings, nil, rand.New(rand.NewSource(seed)))
+	g.Seed = seed
+	g.League = nil
+	g.ScoreFile, g.ShotsFile = os.DevNull, os.DevNull
+	g.SetGorillaMask(drawcommon.GorillaSprite(1))
+	g.Subscribe(ListenerFunc(g.keepScore))
+	return g
+}
+
+func newGame(width, height, buildingCount, players int, settings Settings, m *CityMap, rng *rand.Rand) *Game {
 	if buildingCount <= 0 {
 		buildingCount = DefaultBuildingCount
 	}
-	if buildingCount < 4 {
-		buildingCount = 4
+	// every gorilla needs a roof of its own away from the edges
+	if buildingCount < players+2 {
+		buildingCount = players + 2
 	}
-	g := &Game{Width: width, Height: height, Angle: 45, Power: 50, ScoreFile: defaultScoreFile, ShotsFile: defaultShotsFile, BuildingCount: buildingCount, Aborted: false}
+	g := &Game{Width: width, Height: height, Angle: 45, Power: 50, ScoreFile: defaultScoreFile, ShotsFile: DefaultShotsFile, BuildingCount: buildingCount, Rand: rng, Aborted: false}
 	g.roundOver = true
-	g.Angles = [2]float64{45, 45}
-	g.Powers = [2]float64{50, 50}
-	g.League = LoadLeague(defaultLeagueFile)
-	g.Players = [2]string{"Player 1", "Player 2"}
-	g.Settings = DefaultSettings()
+	g.Gorillas = make([]Gorilla, players)
+	g.Angles = make([]float64, players)
+	g.Powers = make([]float64, players)
+	for i := range g.Angles {
+		g.Angles[i] = 45
+		g.Powers[i] = 50
+	}
+	g.Players = PlayerNames(players)
+	g.Wins = make([]int, players)
+	g.TotalWins = make([]int, players)
+	g.Shots = make([]int, players)
+	g.LastAngle = make([]float64, players)
+	g.LastPower = make([]float64, players)
+	g.Settings = settings
 	g.Gravity = g.Settings.DefaultGravity
-	g.Wind = basicWind()
-	bw := float64(width) / float64(g.BuildingCount)
-
-	// create a sloping skyline similar to the original BASIC version
-	slope := rand.Intn(6) + 1
-	newHt := float64(height) * 0.2
-	if slope == 2 || slope == 6 {
-		newHt = float64(height) * 0.6
-	}
-	htInc := float64(height) / 35
-
-	for i := 0; i < g.BuildingCount; i++ {
-		x := float64(i) * bw
-		switch slope {
-		case 1:
-			newHt += htInc
-		case 2:
-			newHt -= htInc
-		case 3, 5:
-			if x > float64(width)/2 {
-				newHt -= 2 * htInc
-			} else {
-				newHt += 2 * htInc
-			}
-		case 4:
-			if x > float64(width)/2 {
-				newHt += 2 * htInc
-			} else {
-				newHt -= 2 * htInc
-			}
+	g.Sun = Sun{X: float64(width) / 2, Y: float64(height) / 15, R: float64(height) / 15, Integrity: SunMaxIntegrity}
+	g.Map = m
+	if m != nil {
+		g.StartMatch()
+		g.buildMap(m, rng)
+	} else {
+		g.Wind = basicWind(rng)
+		g.StartMatch()
+		g.Buildings = layoutBuildings(rng, g.BuildingCount, float64(width))
+		skyline, err := SkylineByName(settings.Skyline)
+		if err != nil {
+			skyline, _ = SkylineByName(DefaultSkyline)
 		}
+		skyline.Heights(rng, g.Buildings, float64(width), float64(height))
+		g.placeGorillas()
+	}
 
-		h := newHt + rand.Float64()*float64(height)/6 - float64(height)/12
-		if h < float64(height)*0.15 {
-			h = float64(height) * 0.15
-		}
-		if h > float64(height)*0.6 {
-			h = float64(height) * 0.6
+	g.RebuildHitMap()
+
+	return g
+}
+
+// defBWidth is DefBWidth from MakeCityScape: the original draws each building
+// FNRan(DefBWidth) + DefBWidth pixels wide on its 640 pixel screen.
+const defBWidth = 37
+
+// layoutBuildings splits a city width units wide into count buildings whose
+// widths vary in the same proportions as the original's, so a wide building
+// is at most twice as wide as a narrow one.
+func layoutBuildings(r *rand.Rand, count int, width float64) []Building {
+	ws := make([]float64, count)
+	total := 0.0
+	for i := range ws {
+		ws[i] = float64(fnRan(r, defBWidth) + defBWidth)
+		total += ws[i]
+	}
+	bs := make([]Building, count)
+	x := 0.0
+	for i, w := range ws {
+		bs[i] = Building{X: x, W: w * width / total}
+		x += bs[i].W
+	}
+	// absorb rounding so the last building meets the edge of the city
+	bs[count-1].W = width - bs[count-1].X
+	return bs
+}
+
+// placeGorillas spreads the gorillas evenly over the skyline, leaving the
+// outermost buildings empty as in the two player original.
+func (g *Game) placeGorillas() {
+	n := len(g.Gorillas)
+	for i := range g.Gorillas {
+		idx := 1
+		if n > 1 {
+			idx += int(math.Round(float64(i*(len(g.Buildings)-3)) / float64(n-1)))
 		}
+		b := g.Buildings[idx]
+		g.Gorillas[i] = Gorilla{X: b.X + b.W/2, Y: float64(g.Height) - b.H}
+	}
+}
 
-		g.Buildings = append(g.Buildings, Building{
-			X: x,
-			W: bw,
-			H: h,
-		})
+// SetPlayers seats between MinPlayers and MaxPlayers named players and lays
+// out a new city with a gorilla for each of them. Session wins and the
+// current match start over.
+func (g *Game) SetPlayers(names ...string) error {
+	if len(names) < MinPlayers || len(names) > MaxPlayers {
+		return fmt.Errorf("need %d to %d players, got %d", MinPlayers, MaxPlayers, len(names))
 	}
-	g.Gorillas[0] = Gorilla{g.Buildings[1].X + bw/2, float64(height) - g.Buildings[1].H}
-	g.Gorillas[1] = Gorilla{g.Buildings[g.BuildingCount-2].X + bw/2, float64(height) - g.Buildings[g.BuildingCount-2].H}
+	g.Players = append([]string(nil), names...)
+	g.Teams = nil
+	g.Wins = make([]int, len(names))
+	g.TotalWins = resizeInts(g.TotalWins, len(names))
+	g.Reset()
+	g.setCurrent(0)
+	g.StartMatch()
+	return nil
+}
 
-	g.HitMap = NewHitMap(width, height)
+// PlayerNames returns count names taken from names in order, using
+// "Player N" for any that are missing or blank.
+func PlayerNames(count int, names ...string) []string {
+	out := make([]string, count)
+	for i := range out {
+		if i < len(names) && strings.TrimSpace(names[i]) != "" {
+			out[i] = strings.TrimSpace(names[i])
+		} else {
+			out[i] = fmt.Sprintf("Player %d", i+1)
+		}
+	}
+	return out
+}
+
+// RebuildHitMap redraws the HitMap from the current buildings, their damage
+// and the gorillas. Call it after changing the city layout directly.
+func (g *Game) RebuildHitMap() {
+	g.HitMap = NewHitMap(g.Width, g.Height)
 	for _, b := range g.Buildings {
 		x1 := int(b.X)
 		x2 := int(b.X + b.W)
-		y1 := height - int(b.H)
-		g.HitMap.AddBuilding(x1, y1, x2, height)
+		y1 := g.Height - int(b.H)
+		g.HitMap.AddBuilding(x1, y1, x2, g.Height)
+		for _, d := range b.Damage {
+			g.HitMap.ClearBuildingArea(int(math.Round(d.X)), int(math.Round(d.Y)), int(math.Ceil(d.R)))
+		}
 	}
 	for i, gr := range g.Gorillas {
-		g.HitMap.DrawGorilla(int(gr.X), int(gr.Y), i, 4)
+		if gr.Dead {
+			continue
+		}
+		if g.GorillaMask != nil {
+			g.HitMap.DrawGorillaImage(int(gr.X), int(gr.Y), i, g.GorillaMask)
+		} else {
+			g.HitMap.DrawGorilla(int(gr.X), int(gr.Y), i, 4)
+		}
 	}
-
-	return g
 }
 
 func (g *Game) Reset() {
@@ -331,42 +598,159 @@ func (g *Game) Reset() {
 	file := g.ScoreFile
 	shotsFile := g.ShotsFile
 	players := g.Players
+	teams := g.Teams
 	league := g.League
 	settings := g.Settings
 	gravity := g.Gravity
 	hook := g.ResetHook
-	*g = *NewGame(g.Width, g.Height, g.BuildingCount)
+	mask := g.GorillaMask
+	animationSteps := g.AnimationSteps
+	match := g.Match
+	seed := g.Seed
+	aiRand := g.aiRand
+	history := g.ShotHistory
+	highlights := g.Highlights
+	recording, replayDir := g.Recording, g.ReplayDir
+	listeners, nextListener := g.listeners, g.nextListener
+	*g = *newGame(g.Width, g.Height, g.BuildingCount, len(players), settings, g.Map, g.rng())
 	g.Wins = wins
 	g.TotalWins = totals
 	g.ScoreFile = file
 	g.ShotsFile = shotsFile
 	g.Players = players
+	g.Teams = teams
 	g.League = league
 	g.Settings = settings
-	g.Gravity = gravity
+	if g.Map == nil || g.Map.Gravity == nil {
+		g.Gravity = gravity
+	}
 	g.ResetHook = hook
+	g.AnimationSteps = animationSteps
+	g.Match = match
+	g.Seed = seed
+	g.aiRand = aiRand
+	g.ShotHistory = history
+	g.Highlights = highlights
+	g.Recording, g.ReplayDir = recording, replayDir
+	g.listeners, g.nextListener = listeners, nextListener
+	if mask != nil {
+		g.SetGorillaMask(mask)
+	}
 	if g.ResetHook != nil {
 		g.ResetHook()
 	}
 }
 
+// SetGorillaMask replaces the gorilla hit shape with the opaque pixels of img
+// so collisions match the sprite a frontend draws.
+func (g *Game) SetGorillaMask(img image.Image) {
+	g.GorillaMask = img
+	if g.HitMap == nil {
+		return
+	}
+	for i, gr := range g.Gorillas {
+		g.HitMap.ClearGorilla(int(gr.X), int(gr.Y), i, 4)
+		if gr.Dead {
+			continue
+		}
+		if img != nil {
+			g.HitMap.DrawGorillaImage(int(gr.X), int(gr.Y), i, img)
+		} else {
+			g.HitMap.DrawGorilla(int(gr.X), int(gr.Y), i, 4)
+		}
+	}
+}
+
 func (g *Game) setCurrent(idx int) {
 	g.Current = idx
 	g.Angle = g.Angles[idx]
 	g.Power = g.Powers[idx]
 }
 
-func fnRan(x int) int {
-	return rand.Intn(x) + 1
+// nextPlayer returns the first gorilla after idx still standing, in turn
+// order.
+func (g *Game) nextPlayer(idx int) int {
+	order := g.turnOrder()
+	pos := 0
+	for i, j := range order {
+		if j == idx {
+			pos = i
+		}
+	}
+	n := len(order)
+	for k := 1; k <= n; k++ {
+		if j := order[(pos+k)%n]; !g.Gorillas[j].Dead {
+			return j
+		}
+	}
+	return idx
+}
+
+// nextTurn passes the banana to the next gorilla still standing.
+func (g *Game) nextTurn() {
+	g.setCurrent(g.nextPlayer(g.Current))
 }
 
-func basicWind() float64 {
-	w := float64(fnRan(10) - 5)
-	if fnRan(3) == 1 {
+// alive returns the indexes of the gorillas still standing.
+func (g *Game) alive() []int {
+	var idx []int
+	for i, gr := range g.Gorillas {
+		if !gr.Dead {
+			idx = append(idx, i)
+		}
+	}
+	return idx
+}
+
+// facing returns 1 for a gorilla on the left half of the city, which throws
+// to the right, and -1 for one on the right half.
+func (g *Game) facing(idx int) float64 {
+	if g.Gorillas[idx].X < float64(g.Width)/2 {
+		return 1
+	}
+	return -1
+}
+
+// targetX returns the position of the opponent a throw with horizontal
+// velocity vx was aimed at: the nearest one standing in that direction, or
+// the nearest overall when nobody is.
+func (g *Game) targetX(vx float64) float64 {
+	from := g.Gorillas[g.Current].X
+	best, bestAhead := math.Inf(1), false
+	x := from
+	for i, gr := range g.Gorillas {
+		if gr.Dead || g.Side(i) == g.Side(g.Current) {
+			continue
+		}
+		d := math.Abs(gr.X - from)
+		ahead := (gr.X-from)*vx > 0
+		if (ahead && !bestAhead) || (ahead == bestAhead && d < best) {
+			best, bestAhead, x = d, ahead, gr.X
+		}
+	}
+	return x
+}
+
+// rng returns the game's random source, recreating it from Seed when the
+// game was loaded from JSON.
+func (g *Game) rng() *rand.Rand {
+	if g.Rand == nil {
+		g.Rand = rand.New(rand.NewSource(g.Seed))
+	}
+	return g.Rand
+}
+
+func fnRan(r *rand.Rand, x int) int {
+	return r.Intn(x) + 1
+}
+
+func basicWind(r *rand.Rand) float64 {
+	w := float64(fnRan(r, 10) - 5)
+	if fnRan(r, 3) == 1 {
 		if w > 0 {
-			w += float64(fnRan(10))
+			w += float64(fnRan(r, 10))
 		} else {
-			w -= float64(fnRan(10))
+			w -= float64(fnRan(r, 10))
 		}
 	}
 	return w
@@ -401,6 +785,65 @@ func (g *Game) pointInDamage(idx int, x, y float64) bool {
 	return false
 }
 
+// buildingAt returns the index of the building spanning x, or -1.
+func (g *Game) buildingAt(x float64) int {
+	i := sort.Search(len(g.Buildings), func(i int) bool {
+		b := g.Buildings[i]
+		return b.X+b.W > x
+	})
+	if i >= len(g.Buildings) || x < g.Buildings[i].X {
+		return -1
+	}
+	return i
+}
+
+// solidAt reports whether an undamaged part of a building occupies x,y.
+func (g *Game) solidAt(x, y float64) bool {
+	if y < 0 || y >= float64(g.Height) {
+		return false
+	}
+	idx := g.buildingAt(x)
+	if idx < 0 {
+		return false
+	}
+	if g.HitMap != nil {
+		if g.HitMap.At(int(math.Floor(x)), int(math.Floor(y))) != hitMapBuilding {
+			return false
+		}
+	} else if y <= float64(g.Height)-g.Buildings[idx].H {
+		return false
+	}
+	return !g.pointInDamage(idx, x, y)
+}
+
+// buildingHitBetween sweeps the line from (x1,y1) to (x2,y2) against the
+// buildings. It returns the fraction of the line travelled before the first
+// contact, or -1 if the line is clear.
+func (g *Game) buildingHitBetween(x1, y1, x2, y2 float64) float64 {
+	steps := int(math.Ceil(math.Max(math.Abs(x2-x1), math.Abs(y2-y1))))
+	if steps < 1 {
+		steps = 1
+	}
+	for i := 1; i <= steps; i++ {
+		t := float64(i) / float64(steps)
+		if !g.solidAt(x1+(x2-x1)*t, y1+(y2-y1)*t) {
+			continue
+		}
+		// narrow down to the edge between the last clear sample and this one
+		lo, hi := float64(i-1)/float64(steps), t
+		for j := 0; j < 8; j++ {
+			mid := (lo + hi) / 2
+			if g.solidAt(x1+(x2-x1)*mid, y1+(y2-y1)*mid) {
+				hi = mid
+			} else {
+				lo = mid
+			}
+		}
+		return hi
+	}
+	return -1
+}
+
 func (g *Game) explosionBase() float64 {
 	base := g.Settings.NewExplosionRadius
 	if base <= 0 {
@@ -414,91 +857,161 @@ func (g *Game) explosionBase() float64 {
 
 func (g *Game) handleGorillaKill(idx int) {
 	shooter := g.Current
-	winner := shooter
 	event := EventNone
-	if idx == shooter {
-		winner = (shooter + 1) % 2
+	friendly := g.teammates(idx, shooter) && g.Settings.FriendlyFire == FriendlyFireSelf
+	if idx == shooter || friendly {
 		event = EventSelf
 		g.LastEvent = event
 		g.LastEventTicks = eventDisplayTicks
-		g.LastEventMsg = EventMessage(event)
+		g.LastEventMsg = eventMessage(g.rng().Intn, event)
 	}
-	g.Wins[winner]++
-	g.TotalWins[winner]++
-	if g.League != nil {
-		g.League.RecordRound(g.Players[0], g.Players[1], winner, g.Shots[shooter])
-		g.League.Save()
+	g.Gorillas[idx].Dead = true
+	kill := g.event(GorillaKilled)
+	if event == EventSelf {
+		kill.Kind = SelfKill
 	}
-	g.Shots = [2]int{}
-	g.SaveScores()
+	kill.Target = idx
+	kill.X, kill.Y = g.Gorillas[idx].X, g.Gorillas[idx].Y
+	g.publish(kill)
 	if g.HitMap != nil {
-		gr := g.Gorillas[idx]
-		g.HitMap.ClearGorilla(int(gr.X), int(gr.Y), idx, 4)
+		g.HitMap.RemoveGorilla(idx)
 	}
-	g.startVictoryDance(winner)
-	g.setCurrent(winner)
 	if g.Settings.UseSound && event != EventNone {
 		PlayBeep()
 	}
+	sides := g.standingSides()
+	if len(sides) > 1 {
+		// the round goes on without them
+		g.roundOver = false
+		return
+	}
+	side := g.Side(shooter)
+	if len(sides) == 1 {
+		side = sides[0]
+	}
+	// the winner dances: the thrower if they are still standing on the
+	// winning side, otherwise its first survivor
+	winner := shooter
+	if g.Side(shooter) != side || g.Gorillas[shooter].Dead {
+		for _, i := range g.TeamMembers(side) {
+			if !g.Gorillas[i].Dead {
+				winner = i
+				break
+			}
+		}
+	}
+	shots := 0
+	for _, i := range g.TeamMembers(side) {
+		shots += g.Shots[i]
+	}
+	g.Wins[side]++
+	g.TotalWins[side]++
+	g.Match.RecordRound(side)
+	over := g.event(RoundOver)
+	over.Winner = side
+	over.Shots = shots
+	g.publish(over)
+	if g.MatchOver() {
+		e := g.event(MatchOver)
+		e.Winner = g.Winner()
+		g.publish(e)
+	}
+	g.Shots = make([]int, len(g.Gorillas))
+	g.startVictoryDance(winner)
+	g.setCurrent(winner)
 	g.roundOver = true
 }
 
-func (g *Game) killGorillaIfInRadius(x, y, r float64) bool {
+// killGorillasInRadius knocks out every standing gorilla within r of x,y,
+// stopping once the round has been decided. It reports whether any died.
+func (g *Game) killGorillasInRadius(x, y, r float64) bool {
+	var hit []int
 	if g.HitMap != nil {
-		idx := g.HitMap.GorillaHitInCircle(int(math.Round(x)), int(math.Round(y)), int(math.Ceil(r)))
-		if idx >= 0 {
-			g.handleGorillaKill(idx)
-			return true
+		hit = g.HitMap.GorillasInCircle(int(math.Round(x)), int(math.Round(y)), int(math.Ceil(r)))
+	} else {
+		for i, gr := range g.Gorillas {
+			dx := gr.X - x
+			dy := gr.Y - y
+			if !gr.Dead && dx*dx+dy*dy <= r*r {
+				hit = append(hit, i)
+			}
 		}
-		return false
 	}
-	for i, gr := range g.Gorillas {
-		dx := gr.X - x
-		dy := gr.Y - y
-		if dx*dx+dy*dy <= r*r {
-			g.handleGorillaKill(i)
-			return true
+	killed := false
+	for _, idx := range hit {
+		if idx >= len(g.Gorillas) || g.Gorillas[idx].Dead || g.friendlySafe(idx) {
+			continue
+		}
+		g.handleGorillaKill(idx)
+		killed = true
+		if g.roundOver {
+			break
 		}
 	}
-	return false
+	return killed
+}
+
+// friendlySafe reports whether friendly fire is off and gorilla idx plays on
+// the thrower's team.
+func (g *Game) friendlySafe(idx int) bool {
+	return g.Settings.FriendlyFire == FriendlyFireOff && g.teammates(idx, g.Current)
+}
+
+// launchClearance returns how far a banana travels from the thrower before
+// it can hit them on the way up.
+func (g *Game) launchClearance() float64 {
+	// the fallback hit box reaches 10 units above and below a gorilla
+	clearance := 10.0
+	if g.GorillaMask != nil {
+		b := g.GorillaMask.Bounds()
+		clearance = math.Max(clearance, math.Max(float64(b.Dx()), float64(b.Dy())))
+	}
+	return clearance
 }
 
 // gorillaHitBetween checks if the line from (x1,y1) to (x2,y2) intersects any
-// gorilla. It returns the index of the gorilla hit or -1.
-func (g *Game) gorillaHitBetween(x1, y1, x2, y2 float64) int {
+// gorilla. It returns the index of the gorilla hit, or -1, along with the
+// fraction of the line travelled before the hit.
+func (g *Game) gorillaHitBetween(x1, y1, x2, y2 float64) (int, float64) {
 	steps := int(math.Ceil(math.Max(math.Abs(x2-x1), math.Abs(y2-y1))))
 	if steps < 1 {
 		steps = 1
 	}
 	vx := x2 - x1
 	vy := y2 - y1
-	forward := (g.Current == 0 && vx > 0) || (g.Current == 1 && vx < 0)
+	forward := vx*g.facing(g.Current) > 0
+	clearance := g.launchClearance()
 	for i := 1; i <= steps; i++ {
 		t := float64(i) / float64(steps)
 		x := x1 + (x2-x1)*t
 		y := y1 + (y2-y1)*t
+		// a forward, upward throw passes through the thrower's own shape
+		// while it is still leaving the gorilla's hands
+		launching := forward && vy <= 0 && math.Hypot(x-g.lastStartX, y-g.lastStartY) <= clearance
 		if g.HitMap != nil {
 			idx := g.HitMap.GorillaHitAt(int(math.Round(x)), int(math.Round(y)))
-			if idx >= 0 {
-				if idx == g.Current && forward && vy <= 0 && i <= 5 {
+			if idx >= 0 && !g.Gorillas[idx].Dead && !g.friendlySafe(idx) {
+				if idx == g.Current && launching {
 					continue
 				}
-				return idx
+				return idx, t
 			}
 		}
 		for j, gr := range g.Gorillas {
-			if math.Abs(gr.X-x) < 5 && math.Abs(gr.Y-y) < 10 {
-				if j == g.Current && forward && vy <= 0 && i <= 5 {
+			if !gr.Dead && !g.friendlySafe(j) && math.Abs(gr.X-x) < 5 && math.Abs(gr.Y-y) < 10 {
+				if j == g.Current && launching {
 					continue
 				}
-				return j
+				return j, t
 			}
 		}
 	}
-	return -1
+	return -1, 0
 }
 
-func (g *Game) startExplosion(x, y float64) {
+// startExplosion blows up a banana at x,y and reports whether the blast
+// caught any gorillas.
+func (g *Game) startExplosion(x, y float64) bool {
 	base := g.explosionBase()
 	if g.Settings.UseSound {
 		PlayExplosionMelody()
@@ -539,12 +1052,13 @@ func (g *Game) startExplosion(x, y float64) {
 			maxR = r
 		}
 	}
-	if g.killGorillaIfInRadius(x, y, maxR) {
-		// handleGorillaKill sets roundOver and other state
-	} else {
+	killed := g.killGorillasInRadius(x, y, maxR)
+	if !killed {
 		g.roundOver = false
 	}
+	// handleGorillaKill sets roundOver and other state for a kill
 	g.recordExplosionDamage(x, y, base)
+	return killed
 }
 
 func (g *Game) startGorillaExplosion(idx int) {
@@ -588,7 +1102,6 @@ func (g *Game) startGorillaExplosion(idx int) {
 		}
 	}
 	g.Explosion.Active = true
-	g.roundOver = true
 	g.recordExplosionDamage(g.Explosion.X, g.Explosion.Y, base)
 }
 
@@ -618,7 +1131,7 @@ func (g *Game) Throw() {
 		PlayBeep()
 	}
 	if g.Settings.WindFluctuations {
-		g.Wind += float64(rand.Intn(5) - 2)
+		g.Wind += float64(g.rng().Intn(5) - 2)
 		if g.Wind > 10 {
 			g.Wind = 10
 		} else if g.Wind < -10 {
@@ -629,51 +1142,89 @@ func (g *Game) Throw() {
 	g.LastPower[g.Current] = g.Power
 	g.Angles[g.Current] = g.Angle
 	g.Powers[g.Current] = g.Power
+	if g.Settings.InstantReplay && !g.replaying {
+		g.lastThrow = g.snapshot()
+	}
 	g.Shots[g.Current]++
+	g.recordThrow()
+	g.startFlight()
 	start := g.Gorillas[g.Current]
 	g.lastStartX = start.X
-	g.lastOtherX = g.Gorillas[(g.Current+1)%2].X
+	g.lastStartY = start.Y
 	radians := g.Angle * math.Pi / 180
 	speed := g.Power / 2
 	g.Banana.X = start.X
 	g.Banana.Y = start.Y
-	if g.Current == 1 {
-		g.Banana.VX = -math.Cos(radians) * speed
-	} else {
-		g.Banana.VX = math.Cos(radians) * speed
-	}
+	g.Banana.VX = g.facing(g.Current) * math.Cos(radians) * speed
 	g.Banana.VY = -math.Sin(radians) * speed
+	g.lastOtherX = g.targetX(g.Banana.VX)
 	g.lastVX = g.Banana.VX
 	g.LastEvent = EventNone
 	g.LastEventTicks = 0
 	g.LastEventMsg = ""
 	g.Banana.Active = true
+	g.accumulator = 0
+	g.inSun = false
+	e := g.event(ThrowStarted)
+	e.X, e.Y = g.Banana.X, g.Banana.Y
+	e.Angle, e.Power = g.Angle, g.Power
+	g.publish(e)
 }
 
-func (g *Game) Step() ShotEvent {
-	g.stepVictoryDance()
-	if g.LastEventTicks > 0 {
-		g.LastEventTicks--
-		if g.LastEventTicks == 0 {
-			g.LastEvent = EventNone
-			g.LastEventMsg = ""
+// Step advances the game by dt of real time. The world is integrated in fixed
+// StepDuration increments, carrying any remainder over to the next call, so
+// trajectories do not depend on how often a frontend calls Step. It returns
+// the last special event raised during the steps taken.
+func (g *Game) Step(dt time.Duration) ShotEvent {
+	g.accumulator += dt
+	if g.accumulator > maxStepBacklog {
+		g.accumulator = maxStepBacklog
+	}
+	ev := EventNone
+	for g.accumulator >= StepDuration {
+		g.accumulator -= StepDuration
+		if e := g.step(); e != EventNone {
+			ev = e
+		}
+	}
+	return ev
+}
+
+// step advances the simulation by exactly one StepDuration.
+func (g *Game) step() ShotEvent {
+	animate := g.animationDue()
+	if animate {
+		g.stepVictoryDance()
+		if g.LastEventTicks > 0 {
+			g.LastEventTicks--
+			if g.LastEventTicks == 0 {
+				g.LastEvent = EventNone
+				g.LastEventMsg = ""
+			}
 		}
 	}
 	if g.Explosion.Active {
+		if !animate {
+			return EventNone
+		}
 		if g.Explosion.Frame < len(g.Explosion.Radii)-1 {
 			g.Explosion.Frame++
 		} else {
 			g.Explosion.Active = false
-			if g.roundOver {
+			if g.roundOver && !g.replaying {
+				if g.MatchOver() {
+					// leave the final city on screen for the frontend
+					return EventNone
+				}
 				cur := g.Current
 				g.Reset()
-				if g.Settings.VariableWind {
-					g.Wind = basicWind()
+				if g.Settings.VariableWind && (g.Map == nil || g.Map.Wind == nil) {
+					g.Wind = basicWind(g.rng())
 				}
 				if g.Settings.WinnerFirst {
 					g.setCurrent(cur)
 				} else {
-					g.setCurrent((cur + 1) % 2)
+					g.setCurrent(g.nextPlayer(cur))
 				}
 			}
 		}
@@ -683,6 +1234,7 @@ func (g *Game) Step() ShotEvent {
 	if !g.Banana.Active {
 		return EventNone
 	}
+	g.flight.steps++
 	oldX := g.Banana.X
 	oldY := g.Banana.Y
 	g.Banana.X += g.Banana.VX
@@ -695,128 +1247,332 @@ func (g *Game) Step() ShotEvent {
 		if g.Banana.VY > groundBounceThreshold {
 			g.Banana.Y = float64(g.Height)
 			g.Banana.VY = -g.Banana.VY * groundBounceFactor
+			g.flight.bounces++
 		} else {
 			g.Banana.Active = false
 			g.evaluateMiss()
-			g.setCurrent((g.Current + 1) % 2)
+			g.nextTurn()
 			return g.LastEvent
 		}
 	}
-	if hit := g.gorillaHitBetween(oldX, oldY, g.Banana.X, g.Banana.Y); hit >= 0 {
+	hit, hitT := g.gorillaHitBetween(oldX, oldY, g.Banana.X, g.Banana.Y)
+	wallT := g.buildingHitBetween(oldX, oldY, g.Banana.X, g.Banana.Y)
+	if hit >= 0 && (wallT < 0 || hitT <= wallT) {
 		g.Banana.Active = false
 		g.handleGorillaKill(hit)
 		g.startGorillaExplosion(hit)
+		if !g.roundOver {
+			g.nextTurn()
+		}
 		return g.LastEvent
 	}
-	for i, gr := range g.Gorillas {
-		hit := false
-		if g.HitMap != nil {
-			if g.HitMap.GorillaHitAt(int(math.Round(g.Banana.X)), int(math.Round(g.Banana.Y))) == i {
-				hit = true
-			}
-		}
-		if !hit && math.Abs(gr.X-g.Banana.X) < 5 && math.Abs(gr.Y-g.Banana.Y) < 10 {
-			hit = true
-		}
-		if hit {
-			g.Banana.Active = false
-			shooter := g.Current
-			winner := shooter
-			event := EventNone
-			if i == shooter {
-				winner = (shooter + 1) % 2
-				event = EventSelf
-				g.LastEvent = event
-				g.LastEventTicks = eventDisplayTicks
-				g.LastEventMsg = EventMessage(event)
-			}
-			g.Wins[winner]++
-			g.TotalWins[winner]++
-			if g.League != nil {
-				g.League.RecordRound(g.Players[0], g.Players[1], winner, g.Shots[shooter])
-				g.League.Save()
-			}
-			g.Shots = [2]int{}
-			g.SaveScores()
-			g.startGorillaExplosion(i)
-			g.startVictoryDance(winner)
-			g.setCurrent(winner)
-			if g.Settings.UseSound && event != EventNone {
-				PlayBeep()
-			}
-			return event
+	if wallT >= 0 {
+		// explode where the banana first touched the building, not where
+		// the step would have carried it
+		g.Banana.X = oldX + (g.Banana.X-oldX)*wallT
+		g.Banana.Y = oldY + (g.Banana.Y-oldY)*wallT
+		g.Banana.Active = false
+		e := g.event(BuildingHit)
+		e.X, e.Y = g.Banana.X, g.Banana.Y
+		g.publish(e)
+		killed := g.startExplosion(g.Banana.X, g.Banana.Y)
+		if g.roundOver {
+			return g.LastEvent
 		}
-	}
-	bw := float64(g.Width) / float64(g.BuildingCount)
-	idx := int(g.Banana.X / bw)
-	if idx >= 0 && idx < g.BuildingCount && g.Banana.Y < float64(g.Height) &&
-		g.Banana.Y > float64(g.Height)-g.Buildings[idx].H {
-		if !g.pointInDamage(idx, g.Banana.X, g.Banana.Y) {
-			g.Banana.Active = false
-			g.startExplosion(g.Banana.X, g.Banana.Y)
-			if g.roundOver {
-				return g.LastEvent
-			}
+		if !killed {
 			g.evaluateMiss()
-			g.setCurrent((g.Current + 1) % 2)
-			return g.LastEvent
 		}
+		g.nextTurn()
+		return g.LastEvent
 	}
 	if g.Banana.Y > float64(g.Height) || g.Banana.X < 0 || g.Banana.X >= float64(g.Width) {
 		g.Banana.Active = false
 		g.evaluateMiss()
-		g.setCurrent((g.Current + 1) % 2)
+		g.nextTurn()
+		return g.LastEvent
 	}
+	g.checkSun(oldX, oldY)
+	e := g.event(BananaMoved)
+	e.X, e.Y = g.Banana.X, g.Banana.Y
+	g.publish(e)
 	return g.LastEvent
 }
+
+// animationDue reports whether this step moves the animations on, which
+// happens every AnimationSteps steps.
+func (g *Game) animationDue() bool {
+	if g.AnimationSteps <= 1 {
+		return true
+	}
+	g.animationStep = (g.animationStep + 1) % g.AnimationSteps
+	return g.animationStep == 0
+}
+
+// checkSun shrinks the sun the first time a banana passes into it on its way
+// from (oldX, oldY).
+func (g *Game) checkSun(oldX, oldY float64) {
+	// find the point of this step's path closest to the sun
+	dx, dy := g.Banana.X-oldX, g.Banana.Y-oldY
+	t := 0.0
+	if l := dx*dx + dy*dy; l > 0 {
+		t = math.Max(0, math.Min(1, ((g.Sun.X-oldX)*dx+(g.Sun.Y-oldY)*dy)/l))
+	}
+	d := math.Hypot(oldX+dx*t-g.Sun.X, oldY+dy*t-g.Sun.Y)
+	inside := g.Sun.Integrity > 0 && d <= g.Sun.Radius()
+	if inside && !g.inSun {
+		g.Sun.Integrity--
+		e := g.event(SunHit)
+		e.X, e.Y = g.Banana.X, g.Banana.Y
+		g.publish(e)
+	}
+	g.inSun = inside
+}
+
+// Clone returns a deep copy of g for simulating throws. The copy shares no
+// mutable state with g and is cut off from everything outside it: it has no
+// listeners, league, reset hook, recording, replay directory or highlight
+// reel, so playing it on does no file I/O, and its sound is off. Its Rand,
+// and the AI's, start afresh from Seed, leaving g's streams untouched. Map and
+// GorillaMask are only read, so they stay shared.
+func (g *Game) Clone() *Game {
+	c := *g
+	c.Buildings = make([]Building, len(g.Buildings))
+	for i, b := range g.Buildings {
+		b.Damage = append([]DamageCircle(nil), b.Damage...)
+		b.Windows = append([]Window(nil), b.Windows...)
+		c.Buildings[i] = b
+	}
+	c.Gorillas = append([]Gorilla(nil), g.Gorillas...)
+	c.Explosion.Radii = append([]float64(nil), g.Explosion.Radii...)
+	c.Explosion.Colors = append([]color.RGBA(nil), g.Explosion.Colors...)
+	c.Explosion.Vectors = append([][]VectorPoint(nil), g.Explosion.Vectors...)
+	c.Dance.frames = append([]float64(nil), g.Dance.frames...)
+	c.Angles = append([]float64(nil), g.Angles...)
+	c.Powers = append([]float64(nil), g.Powers...)
+	c.Wins = append([]int(nil), g.Wins...)
+	c.TotalWins = append([]int(nil), g.TotalWins...)
+	c.Shots = append([]int(nil), g.Shots...)
+	c.LastAngle = append([]float64(nil), g.LastAngle...)
+	c.LastPower = append([]float64(nil), g.LastPower...)
+	c.Players = append([]string(nil), g.Players...)
+	if g.Teams != nil {
+		c.Teams = append([]int(nil), g.Teams...)
+	}
+	if g.Match != nil {
+		m := *g.Match
+		m.Wins = append([]int(nil), g.Match.Wins...)
+		c.Match = &m
+	}
+	if g.HitMap != nil {
+		c.HitMap = g.HitMap.Clone()
+	}
+	c.flight.craters = append([]int(nil), g.flight.craters...)
+	c.flight.out = append([]int(nil), g.flight.out...)
+	c.Rand = rand.New(rand.NewSource(g.Seed))
+	c.aiRand = nil
+	c.aims = append([]aim(nil), g.aims...)
+	c.Settings.UseSound = false
+	c.League = nil
+	c.ShotHistory, c.Recording = nil, nil
+	c.Highlights = nil
+	c.ReplayDir = ""
+	c.ResetHook = nil
+	c.listeners, c.nextListener = nil, 0
+	c.lastThrow = nil
+	return &c
+}
+
+// StateHash returns a digest of everything that decides how play goes on:
+// the city and its craters, the gorillas, the banana, whose turn it is, the
+// wind, gravity, sun and the match score. Two games started from the same
+// seed and fed the same throws have the same hash, which lets a network game
+// notice the two sides drifting apart. The victory dance, explosion frames,
+// messages and the scores kept on disk only decorate the screen and are left
+// out.
+func (g *Game) StateHash() uint64 {
+	var b []byte
+	num := func(vs ...float64) {
+		for _, v := range vs {
+			b = binary.LittleEndian.AppendUint64(b, math.Float64bits(v))
+		}
+	}
+	count := func(vs ...int) {
+		for _, v := range vs {
+			b = binary.LittleEndian.AppendUint64(b, uint64(v))
+		}
+	}
+	flag := func(v bool) {
+		if v {
+			count(1)
+		} else {
+			count(0)
+		}
+	}
+	count(int(g.Seed), g.Width, g.Height, g.Current, g.Sun.Integrity)
+	num(g.Wind, g.Gravity)
+	count(len(g.Buildings))
+	for _, bd := range g.Buildings {
+		num(bd.X, bd.W, bd.H)
+		count(len(bd.Damage))
+		for _, d := range bd.Damage {
+			num(d.X, d.Y, d.R)
+		}
+	}
+	count(len(g.Gorillas))
+	for _, gr := range g.Gorillas {
+		num(gr.X, gr.Y)
+		flag(gr.Dead)
+	}
+	num(g.Banana.X, g.Banana.Y, g.Banana.VX, g.Banana.VY)
+	flag(g.Banana.Active)
+	count(g.Shots...)
+	if g.Match != nil {
+		count(g.Match.Played)
+		count(g.Match.Wins...)
+	}
+	h := fnv.New64a()
+	h.Write(b)
+	return h.Sum64()
+}
+
+// testShot reports whether a throw at angle and power, made on a clone of g,
+// knocks out an opponent without hurting the thrower's side.
 func (g *Game) testShot(angle, power float64) bool {
-	sim := *g
+	hit, hurt := g.tryShot(angle, power)
+	return hit && !hurt
+}
+
+// tryShot throws at angle and power on a clone of g and reports whether an
+// opponent and whether the thrower's own side was knocked out.
+func (g *Game) tryShot(angle, power float64) (hit, hurt bool) {
+	sim := g.Clone()
+	sim.Settings.InstantReplay = false
 	sim.Angle = angle
 	sim.Power = power
 	sim.Throw()
-	for i := 0; i < 500 && (sim.Banana.Active || sim.Explosion.Active); i++ {
-		sim.Step()
+	for i := 0; i < 500 && sim.Banana.Active; i++ {
+		sim.step()
 	}
-	return sim.Wins[g.Current] > g.Wins[g.Current]
+	for i, gr := range sim.Gorillas {
+		if !gr.Dead || g.Gorillas[i].Dead {
+			continue
+		}
+		if g.Side(i) == g.Side(g.Current) {
+			hurt = true
+		} else {
+			hit = true
+		}
+	}
+	return hit, hurt
+}
+
+// The shot search tries every angle from shotMinAngle to shotMaxAngle in
+// whole degrees, and for each every power from shotMinPower to shotMaxPower
+// in steps of shotPowerStep.
+const (
+	shotMinAngle  = 15
+	shotMaxAngle  = 75
+	shotMinPower  = 20
+	shotMaxPower  = 100
+	shotPowerStep = 2
+)
+
+// shotGrid returns the number of throws the shot search may try, and shotAt
+// the angle and power of the ith, in the order they are preferred.
+func shotGrid() int {
+	return (shotMaxAngle - shotMinAngle + 1) * ((shotMaxPower-shotMinPower)/shotPowerStep + 1)
+}
+
+func shotAt(i int) (angle, power float64) {
+	powers := (shotMaxPower-shotMinPower)/shotPowerStep + 1
+	return float64(shotMinAngle + i/powers), float64(shotMinPower + i%powers*shotPowerStep)
 }
 
-// FindShot searches for an angle and power likely to hit the opponent.
+// FindShot searches for an angle and power likely to knock out an opponent,
+// falling back to 45 and 50 when no throw does. See FindShotContext.
 func (g *Game) FindShot() (angle, power float64) {
-	for a := 15.0; a <= 75; a += 1 {
-		for p := 20.0; p <= 100; p += 2 {
-			if g.testShot(a, p) {
-				return a, p
+	angle, power, _ = g.FindShotContext(context.Background())
+	return angle, power
+}
+
+// FindShotContext tries throws on clones of g across one goroutine per CPU,
+// so the search neither slows the frontend for long nor changes g. It
+// returns the same shot a one-by-one search would: the lowest angle that
+// works, at its lowest power. g must not change until it returns. If ctx is
+// done first it gives up, returning 45, 50 and ctx's error.
+func (g *Game) FindShotContext(ctx context.Context) (angle, power float64, err error) {
+	n := shotGrid()
+	var next, best atomic.Int64
+	best.Store(int64(n))
+	var wg sync.WaitGroup
+	for w := 0; w < runtime.GOMAXPROCS(0); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for ctx.Err() == nil {
+				i := next.Add(1) - 1
+				// a preferred shot has already been found
+				if i >= best.Load() {
+					return
+				}
+				if !g.testShot(shotAt(int(i))) {
+					continue
+				}
+				for {
+					b := best.Load()
+					if i >= b || best.CompareAndSwap(b, i) {
+						return
+					}
+				}
 			}
-		}
+		}()
 	}
-	return 45, 50
+	wg.Wait()
+	if err := ctx.Err(); err != nil {
+		return 45, 50, err
+	}
+	if b := int(best.Load()); b < n {
+		angle, power = shotAt(b)
+		return angle, power, nil
+	}
+	return 45, 50, nil
 }
 
-// AutoShot selects a shot using FindShot and throws the banana.
+// AutoShot selects a shot with AIShot at Settings.AILevel and throws the
+// banana.
 func (g *Game) AutoShot() {
-	g.Angle, g.Power = g.FindShot()
+	g.Angle, g.Power = g.AIShot(g.Settings.AILevel)
 	g.Throw()
 }
 
 // evaluateMiss analyses a non-scoring shot and sets LastEvent if it was weak or backwards.
 func (g *Game) evaluateMiss() {
+	g.rememberMiss()
 	dxToOther := g.lastOtherX - g.lastStartX
 	dxShot := g.Banana.X - g.lastStartX
 	if g.lastVX*dxToOther < 0 {
 		g.LastEvent = EventBackwards
 		g.LastEventTicks = eventDisplayTicks
-		g.LastEventMsg = EventMessage(EventBackwards)
+		g.LastEventMsg = eventMessage(g.rng().Intn, EventBackwards)
 		if g.Settings.UseSound {
 			PlayBeep()
 		}
+		g.publishMiss(Backwards)
 		return
 	}
 	if math.Abs(dxShot) < math.Abs(dxToOther)/3 {
 		g.LastEvent = EventWeak
 		g.LastEventTicks = eventDisplayTicks
-		g.LastEventMsg = EventMessage(EventWeak)
+		g.LastEventMsg = eventMessage(g.rng().Intn, EventWeak)
 		if g.Settings.UseSound {
 			PlayBeep()
 		}
+		g.publishMiss(WeakShot)
 	}
 }
+
+func (g *Game) publishMiss(k EventKind) {
+	e := g.event(k)
+	e.X, e.Y = g.Banana.X, g.Banana.Y
+	g.publish(e)
+}
@@ -1,11 +1,19 @@
 package gorillas
 
 import (
+	"context"
+	"errors"
+	"image"
+	"image/color"
+	"image/draw"
 	"math"
 	"math/rand"
+	"os"
 	"path/filepath"
 	"reflect"
+	"runtime"
 	"testing"
+	"time"
 )
 
 func newTestGame() *Game {
@@ -14,8 +22,9 @@ func newTestGame() *Game {
 	g.Settings = DefaultSettings()
 	g.Gravity = g.Settings.DefaultGravity
 	g.Wind = 0
-	// tests expect no persistent league data
+	// tests expect no persistent league data, and leave no files behind
 	g.League = nil
+	g.ScoreFile, g.ShotsFile = os.DevNull, os.DevNull
 	return g
 }
 
@@ -26,6 +35,17 @@ func almostEqual(a, b float64) bool {
 	return b-a < 1e-6
 }
 
+// lowerCity trims every building so no roof is above y, keeping the hit map
+// in step with the new skyline.
+func lowerCity(g *Game, y float64) {
+	for i := range g.Buildings {
+		if h := float64(g.Height) - y; g.Buildings[i].H > h {
+			g.Buildings[i].H = math.Max(h, 0)
+		}
+	}
+	g.RebuildHitMap()
+}
+
 func TestBananaTrajectoryAndOutOfBounds(t *testing.T) {
 	g := newTestGame()
 	g.Angle = 45
@@ -33,6 +53,7 @@ func TestBananaTrajectoryAndOutOfBounds(t *testing.T) {
 	g.Current = 0
 	startX := g.Gorillas[0].X
 	startY := g.Gorillas[0].Y
+	lowerCity(g, startY)
 
 	g.Throw()
 
@@ -47,7 +68,7 @@ func TestBananaTrajectoryAndOutOfBounds(t *testing.T) {
 		t.Fatalf("unexpected initial velocity got (%f,%f)", g.Banana.VX, g.Banana.VY)
 	}
 
-	g.Step()
+	g.Step(StepDuration)
 	if !almostEqual(g.Banana.X, startX+vx) || !almostEqual(g.Banana.Y, startY+vy) {
 		t.Fatalf("unexpected position after first step: (%f,%f)", g.Banana.X, g.Banana.Y)
 	}
@@ -59,12 +80,12 @@ func TestBananaTrajectoryAndOutOfBounds(t *testing.T) {
 		t.Fatal("banana should still be active after first step")
 	}
 
-	g.Step()
+	g.Step(StepDuration)
 	if !g.Banana.Active {
 		t.Fatal("banana should still be active after second step")
 	}
 
-	g.Step() // this should leave the screen
+	g.Step(StepDuration) // this should leave the screen
 	if g.Banana.Active {
 		t.Fatal("banana should be inactive after leaving screen")
 	}
@@ -77,13 +98,14 @@ func TestBuildingCollisionEndsTurn(t *testing.T) {
 	g := newTestGame()
 	// make building 2 tall enough to block the banana
 	g.Buildings[2].H = float64(g.Height) - g.Gorillas[0].Y + 5
+	g.RebuildHitMap()
 
 	g.Angle = 0
 	g.Power = 20
 	g.Current = 0
 
 	g.Throw()
-	g.Step()
+	g.Step(StepDuration)
 
 	if g.Banana.Active {
 		t.Fatal("banana should deactivate after hitting building")
@@ -97,30 +119,62 @@ func TestBuildingDamageRecorded(t *testing.T) {
 	g := newTestGame()
 	idx := 2
 	g.Buildings[idx].H = float64(g.Height) - g.Gorillas[0].Y + 10
+	g.RebuildHitMap()
 
 	g.Angle = 0
 	g.Power = 20
 	g.Current = 0
 
 	g.Throw()
-	g.Step()
+	g.Step(StepDuration)
 
 	if len(g.Buildings[idx].Damage) == 0 {
 		t.Fatal("expected damage to be recorded on building hit")
 	}
 }
 
+func TestFastBananaHitsThinWall(t *testing.T) {
+	g := newTestGame()
+	startY := g.Gorillas[0].Y
+	lowerCity(g, startY)
+	// a one unit wall the banana would step straight over
+	wall := &g.Buildings[3]
+	wall.X = 33
+	wall.W = 1
+	wall.H = float64(g.Height) - startY + 20
+	g.Gorillas[1] = Gorilla{X: 55, Y: startY}
+	g.RebuildHitMap()
+
+	g.Angle = 0
+	g.Power = 100
+	g.Current = 0
+
+	g.Throw()
+	g.Step(StepDuration)
+
+	if g.Banana.Active {
+		t.Fatal("banana should stop at the wall")
+	}
+	if g.Wins[0] != 0 {
+		t.Fatalf("wall should shield the gorilla behind it, wins: %v", g.Wins)
+	}
+	if !g.Explosion.Active || math.Abs(g.Explosion.X-33) > 0.5 || !almostEqual(g.Explosion.Y, startY) {
+		t.Fatalf("explosion should start at the wall face, got (%f,%f)", g.Explosion.X, g.Explosion.Y)
+	}
+}
+
 func TestBananaPassesThroughDamage(t *testing.T) {
 	g := newTestGame()
 	idx := 2
 	g.Buildings[idx].H = float64(g.Height) - g.Gorillas[0].Y + 10
+	g.RebuildHitMap()
 
 	g.Angle = 0
 	g.Power = 20
 	g.Current = 0
 
 	g.Throw()
-	g.Step()
+	g.Step(StepDuration)
 
 	if len(g.Buildings[idx].Damage) == 0 {
 		t.Fatal("damage not recorded")
@@ -128,7 +182,7 @@ func TestBananaPassesThroughDamage(t *testing.T) {
 
 	g.Current = 0
 	g.Throw()
-	g.Step()
+	g.Step(StepDuration)
 
 	if !g.Banana.Active {
 		t.Fatal("banana should pass through damaged section")
@@ -144,7 +198,7 @@ func TestWindInfluencesVelocity(t *testing.T) {
 
 	g.Throw()
 	initialVX := g.Banana.VX
-	g.Step()
+	g.Step(StepDuration)
 	expectedVX := initialVX + g.Wind/20
 	if !almostEqual(g.Banana.VX, expectedVX) {
 		t.Fatalf("expected vx %f got %f", expectedVX, g.Banana.VX)
@@ -155,7 +209,7 @@ func TestThrowAppliesWindFluctuation(t *testing.T) {
 	g := newTestGame()
 	g.Settings.WindFluctuations = true
 	g.Wind = 5
-	rand.Seed(2)
+	g.Rand = rand.New(rand.NewSource(2))
 	g.Angle = 0
 	g.Power = 20
 	g.Current = 0
@@ -173,7 +227,7 @@ func TestGravityInfluencesVelocity(t *testing.T) {
 	g.Gravity = 34
 
 	g.Throw()
-	g.Step()
+	g.Step(StepDuration)
 	if !almostEqual(g.Banana.VY, g.Gravity/34) {
 		t.Fatalf("expected vy %f got %f", g.Gravity/34, g.Banana.VY)
 	}
@@ -192,9 +246,10 @@ func TestGorillaHitIncrementsWin(t *testing.T) {
 	vy := -math.Sin(g.Angle*math.Pi/180) * (g.Power / 2)
 	// place second gorilla where the banana will be after one step
 	g.Gorillas[1] = Gorilla{X: startX + vx, Y: startY + vy}
+	lowerCity(g, startY)
 
 	g.Throw()
-	g.Step()
+	g.Step(StepDuration)
 
 	if g.Wins[0] != 1 {
 		t.Fatalf("expected player 1 to score, wins: %v", g.Wins)
@@ -207,7 +262,7 @@ func TestGorillaHitIncrementsWin(t *testing.T) {
 	}
 
 	for g.Explosion.Active {
-		g.Step()
+		g.Step(StepDuration)
 	}
 
 	if g.Current != 0 {
@@ -218,6 +273,65 @@ func TestGorillaHitIncrementsWin(t *testing.T) {
 	}
 }
 
+func TestBuildingsHaveVariableWidths(t *testing.T) {
+	g := NewGameWithSeed(WorldWidth, WorldHeight, DefaultBuildingCount, 5)
+	x := 0.0
+	narrow, wide := math.Inf(1), 0.0
+	for i, b := range g.Buildings {
+		if !almostEqual(b.X, x) {
+			t.Fatalf("building %d starts at %f, want %f", i, b.X, x)
+		}
+		x += b.W
+		narrow, wide = math.Min(narrow, b.W), math.Max(wide, b.W)
+	}
+	if !almostEqual(x, WorldWidth) {
+		t.Fatalf("buildings should fill the city, end at %f", x)
+	}
+	if narrow == wide {
+		t.Fatal("expected buildings of different widths")
+	}
+	if wide > narrow*2 {
+		t.Fatalf("widths %f to %f vary more than the original's", narrow, wide)
+	}
+	for i, b := range g.Buildings {
+		if idx := g.buildingAt(b.X + b.W*0.9); idx != i {
+			t.Fatalf("lookup near the right edge of building %d found %d", i, idx)
+		}
+	}
+	for i, gr := range g.Gorillas {
+		b := g.Buildings[g.buildingAt(gr.X)]
+		if !almostEqual(gr.X, b.X+b.W/2) || !almostEqual(gr.Y, WorldHeight-b.H) {
+			t.Fatalf("gorilla %d should stand in the middle of its roof", i)
+		}
+	}
+}
+
+func TestBananaHitsNarrowBuildingEdge(t *testing.T) {
+	g := newTestGame()
+	g.Buildings = []Building{{X: 0, W: 13}, {X: 13, W: 31}, {X: 44, W: 17}, {X: 61, W: 39}}
+	for i := range g.Buildings {
+		g.Buildings[i].H = 5
+	}
+	g.Buildings[2].H = 60
+	g.Settings.NewExplosionRadius = 5
+	g.Gorillas[0] = Gorilla{X: 28.5, Y: 80}
+	g.Gorillas[1] = Gorilla{X: 80.5, Y: 80}
+	g.RebuildHitMap()
+	g.Angle = 0
+	g.Power = 20
+	g.Current = 0
+	g.Throw()
+	for i := 0; i < 20 && g.Banana.Active; i++ {
+		g.step()
+	}
+	if g.Banana.Active || math.Abs(g.Banana.X-44) > 1 {
+		t.Fatalf("banana should stop at the narrow building's wall, got x=%f", g.Banana.X)
+	}
+	if len(g.Buildings[2].Damage) == 0 || len(g.Buildings[3].Damage) != 0 {
+		t.Fatal("only the building that was hit should be damaged")
+	}
+}
+
 func TestNewGameClampsSmallBuildingCounts(t *testing.T) {
 	t.Run("one building does not panic", func(t *testing.T) {
 		defer func() {
@@ -244,16 +358,14 @@ func TestHighSpeedGorillaHit(t *testing.T) {
 	g.Angle = 0
 	g.Power = 100
 	g.Current = 0
-	for i := range g.Buildings {
-		g.Buildings[i].H = 0
-	}
+	lowerCity(g, float64(g.Height))
 	startX := g.Gorillas[0].X
 	startY := g.Gorillas[0].Y
 	vx := math.Cos(g.Angle*math.Pi/180) * (g.Power / 2)
 	g.Gorillas[1] = Gorilla{X: startX + vx*0.5, Y: startY}
 
 	g.Throw()
-	g.Step()
+	g.Step(StepDuration)
 
 	if g.Wins[0] != 1 {
 		t.Fatalf("expected player 1 to score, wins: %v", g.Wins)
@@ -273,9 +385,10 @@ func TestDirectHitOverBuilding(t *testing.T) {
 	startY := g.Gorillas[0].Y
 	vx := math.Cos(g.Angle*math.Pi/180) * (g.Power / 2)
 	g.Gorillas[1] = Gorilla{X: startX + vx, Y: startY}
+	lowerCity(g, startY)
 
 	g.Throw()
-	g.Step()
+	g.Step(StepDuration)
 
 	if g.Wins[0] != 1 {
 		t.Fatalf("expected player 1 to score, wins: %v", g.Wins)
@@ -287,15 +400,13 @@ func TestDirectHitOverBuilding(t *testing.T) {
 
 func TestSelfHitAtLaunch(t *testing.T) {
 	g := newTestGame()
-	for i := range g.Buildings {
-		g.Buildings[i].H = 0
-	}
+	lowerCity(g, float64(g.Height))
 	g.Angle = -90
 	g.Power = 20
 	g.Current = 0
 
 	g.Throw()
-	g.Step()
+	g.Step(StepDuration)
 
 	if g.Wins[1] != 1 {
 		t.Fatalf("expected player 2 to score, wins: %v", g.Wins)
@@ -316,11 +427,12 @@ func TestWinnerFirstDisabled(t *testing.T) {
 	vx := math.Cos(g.Angle*math.Pi/180) * (g.Power / 2)
 	vy := -math.Sin(g.Angle*math.Pi/180) * (g.Power / 2)
 	g.Gorillas[1] = Gorilla{X: startX + vx, Y: startY + vy}
+	lowerCity(g, startY)
 
 	g.Throw()
-	g.Step()
+	g.Step(StepDuration)
 	for g.Explosion.Active {
-		g.Step()
+		g.Step(StepDuration)
 	}
 	if g.Current != 1 {
 		t.Fatalf("current should switch to player 2 when WinnerFirst is off, got %d", g.Current)
@@ -389,12 +501,12 @@ func TestExplosionProgressAndReset(t *testing.T) {
 		t.Fatalf("initial explosion Frame should be 0, got %d", g.Explosion.Frame)
 	}
 
-	g.Step()
+	g.Step(StepDuration)
 	if g.Explosion.Frame != 1 {
 		t.Fatalf("explosion Frame should advance, got %d", g.Explosion.Frame)
 	}
 
-	g.Step()
+	g.Step(StepDuration)
 	if g.Explosion.Active {
 		t.Fatal("explosion should finish and deactivate")
 	}
@@ -415,14 +527,14 @@ func TestSaveAndLoadScores(t *testing.T) {
 	tmp := filepath.Join(t.TempDir(), "scores.json")
 	g1 := newTestGame()
 	g1.ScoreFile = tmp
-	g1.TotalWins = [2]int{2, 3}
+	g1.TotalWins = []int{2, 3}
 	g1.SaveScores()
 
 	g2 := newTestGame()
 	g2.ScoreFile = tmp
 	g2.LoadScores()
 
-	if g2.TotalWins != g1.TotalWins {
+	if !reflect.DeepEqual(g2.TotalWins, g1.TotalWins) {
 		t.Fatalf("expected %v, got %v", g1.TotalWins, g2.TotalWins)
 	}
 }
@@ -431,22 +543,25 @@ func TestSaveAndLoadShots(t *testing.T) {
 	tmp := filepath.Join(t.TempDir(), "shots.json")
 	g1 := newTestGame()
 	g1.ShotsFile = tmp
-	g1.ShotHistory = []ShotRecord{{Angle: 45, Power: 50}, {Angle: 30, Power: 60}}
+	g1.Highlights = []Highlight{
+		{Player: "Ann", Score: 80, Shot: ShotRecord{Angle: 45, Power: 50}},
+		{Player: "Bob", Score: 60, Shot: ShotRecord{Angle: 30, Power: 60, Player: 1}},
+	}
 	g1.SaveShots()
 
 	g2 := newTestGame()
 	g2.ShotsFile = tmp
 	g2.LoadShots()
 
-	if !reflect.DeepEqual(g2.ShotHistory, g1.ShotHistory) {
-		t.Fatalf("expected %v, got %v", g1.ShotHistory, g2.ShotHistory)
+	if !reflect.DeepEqual(g2.Highlights, g1.Highlights) {
+		t.Fatalf("expected %v, got %v", g1.Highlights, g2.Highlights)
 	}
 }
 
 func TestStatsString(t *testing.T) {
 	g := newTestGame()
-	g.Wins = [2]int{1, 2}
-	g.TotalWins = [2]int{3, 4}
+	g.Wins = []int{1, 2}
+	g.TotalWins = []int{3, 4}
 	expected := "Session - P1:1 P2:2\nOverall - P1:3 P2:4"
 	if s := g.StatsString(); s != expected {
 		t.Fatalf("unexpected stats string: %q", s)
@@ -477,19 +592,20 @@ func TestVictoryDanceStartsOnHit(t *testing.T) {
 	vx := math.Cos(g.Angle*math.Pi/180) * (g.Power / 2)
 	vy := -math.Sin(g.Angle*math.Pi/180) * (g.Power / 2)
 	g.Gorillas[1] = Gorilla{X: startX + vx, Y: startY + vy}
+	lowerCity(g, startY)
 
 	g.Throw()
-	g.Step()
+	g.Step(StepDuration)
 	if !g.Dance.Active || g.Dance.idx != 0 {
 		t.Fatal("victory dance should start for player 1")
 	}
 	baseY := g.Dance.baseY
-	g.Step()
+	g.Step(StepDuration)
 	if g.Gorillas[0].Y == baseY {
 		t.Fatalf("expected gorilla Y to change during dance")
 	}
 	for g.Dance.Active {
-		g.Step()
+		g.Step(StepDuration)
 	}
 	if g.Gorillas[0].Y != baseY {
 		t.Fatalf("gorilla should return to base position")
@@ -497,43 +613,39 @@ func TestVictoryDanceStartsOnHit(t *testing.T) {
 }
 
 func TestNewGameWindUsesBasicAlgorithm(t *testing.T) {
-	rand.Seed(1)
-	g := NewGame(100, 100, DefaultBuildingCount)
+	g := NewGameWithSeed(100, 100, DefaultBuildingCount, 1)
 	if g.Wind != -11 {
 		t.Fatalf("expected wind -11 got %f", g.Wind)
 	}
 }
 
 func TestVariableWindChangesEachRound(t *testing.T) {
-	rand.Seed(1)
-	g := NewGame(100, 100, DefaultBuildingCount)
+	g := NewGameWithSeed(100, 100, DefaultBuildingCount, 1)
 	g.Settings = DefaultSettings()
 	g.Settings.VariableWind = true
 	initial := g.Wind
 	// trigger round end immediately
 	g.Explosion = Explosion{Active: true, Radii: []float64{1}}
 	for g.Explosion.Active {
-		g.Step()
+		g.Step(StepDuration)
 	}
 	if g.Wind == initial {
 		t.Fatalf("wind should change each round")
 	}
-	if g.Wind != 10 {
-		t.Fatalf("expected wind 10 got %f", g.Wind)
+	if g.Wind != 0 {
+		t.Fatalf("expected wind 0 got %f", g.Wind)
 	}
 }
 
 func TestGroundBounceReflectsVelocity(t *testing.T) {
 	g := newTestGame()
-	for i := range g.Buildings {
-		g.Buildings[i].H = 0
-	}
+	lowerCity(g, float64(g.Height))
 	g.Gorillas[0] = Gorilla{X: 30, Y: 50}
 	g.Angle = -90
 	g.Power = 20
 	g.Throw()
 
-	g.Step()
+	g.Step(StepDuration)
 
 	if g.Wins[1] != 1 {
 		t.Fatalf("expected player 2 to score after self hit, wins: %v", g.Wins)
@@ -553,3 +665,221 @@ func TestResetPreservesShotsFile(t *testing.T) {
 		t.Fatalf("expected ShotsFile %q after reset, got %q", path, g.ShotsFile)
 	}
 }
+
+func TestSameSeedReproducesMatch(t *testing.T) {
+	play := func() *Game {
+		g := NewGameWithSeed(100, 100, DefaultBuildingCount, 42)
+		g.League = nil
+		g.ScoreFile = filepath.Join(t.TempDir(), "scores.json")
+		g.Settings.WindFluctuations = true
+		g.Settings.VariableWind = true
+		g.Throw()
+		for g.Banana.Active || g.Explosion.Active {
+			g.Step(StepDuration)
+		}
+		g.Reset()
+		return g
+	}
+	a := play()
+	b := play()
+	if !reflect.DeepEqual(a.Buildings, b.Buildings) {
+		t.Fatal("same seed should build the same skyline after reset")
+	}
+	if a.Wind != b.Wind || a.LastEventMsg != b.LastEventMsg {
+		t.Fatalf("same seed should give the same wind and messages: %v/%v %q/%q", a.Wind, b.Wind, a.LastEventMsg, b.LastEventMsg)
+	}
+	if a.Seed != 42 || b.Seed != 42 {
+		t.Fatalf("seed should survive reset, got %d and %d", a.Seed, b.Seed)
+	}
+	c := NewGameWithSeed(100, 100, DefaultBuildingCount, 43)
+	if reflect.DeepEqual(NewGameWithSeed(100, 100, DefaultBuildingCount, 42).Buildings, c.Buildings) {
+		t.Fatal("different seeds should give different skylines")
+	}
+}
+
+func TestStepIsIndependentOfCallRate(t *testing.T) {
+	throw := func() *Game {
+		g := newTestGame()
+		lowerCity(g, float64(g.Height))
+		g.Angle = 60
+		g.Power = 30
+		g.Current = 0
+		g.Throw()
+		return g
+	}
+	fixed := throw()
+	for i := 0; i < 6; i++ {
+		fixed.Step(StepDuration)
+	}
+	coarse := throw()
+	coarse.Step(50 * time.Millisecond)
+	coarse.Step(50 * time.Millisecond)
+	if !almostEqual(fixed.Banana.X, coarse.Banana.X) || !almostEqual(fixed.Banana.Y, coarse.Banana.Y) {
+		t.Fatalf("trajectory depends on step size: (%f,%f) vs (%f,%f)", fixed.Banana.X, fixed.Banana.Y, coarse.Banana.X, coarse.Banana.Y)
+	}
+}
+
+func TestStepCarriesPartialTime(t *testing.T) {
+	g := newTestGame()
+	g.Angle = 45
+	g.Power = 20
+	g.Current = 0
+	g.Throw()
+	x := g.Banana.X
+	g.Step(StepDuration / 2)
+	if g.Banana.X != x {
+		t.Fatal("banana should not move before a whole step has elapsed")
+	}
+	g.Step(StepDuration / 2)
+	if g.Banana.X == x {
+		t.Fatal("banana should move once a whole step has accumulated")
+	}
+}
+
+func TestAnimationStepsOnlySlowTheAnimations(t *testing.T) {
+	// steps counts how long a throw into the next roof takes to fly and to
+	// finish exploding
+	steps := func(g *Game) (flying, exploding int) {
+		g.Angle, g.Power = 45, 30
+		g.Throw()
+		for ; g.Banana.Active && flying < 2000; flying++ {
+			g.step()
+		}
+		for ; g.Explosion.Active && exploding < 2000; exploding++ {
+			g.step()
+		}
+		return flying, exploding
+	}
+	fresh := func() *Game {
+		g := NewGameWithSeed(WorldWidth, WorldHeight, DefaultBuildingCount, 3)
+		g.League = nil
+		g.ScoreFile = filepath.Join(t.TempDir(), "scores.json")
+		g.Settings.UseSound = false
+		return g
+	}
+	fast, slow := fresh(), fresh()
+	slow.AnimationSteps = 3
+	// which a new city keeps
+	fast.Reset()
+	slow.Reset()
+	fastFlying, fastExploding := steps(fast)
+	slowFlying, slowExploding := steps(slow)
+	if fastExploding == 0 {
+		t.Fatal("the banana should have hit something")
+	}
+	if slowFlying != fastFlying {
+		t.Fatalf("the banana flew %d steps, not %d", slowFlying, fastFlying)
+	}
+	if slowExploding < 3*fastExploding-2 || slowExploding > 3*fastExploding {
+		t.Fatalf("the explosion took %d steps, expected about %d", slowExploding, 3*fastExploding)
+	}
+	if !reflect.DeepEqual(slow.Buildings, fast.Buildings) || !reflect.DeepEqual(slow.Wins, fast.Wins) || slow.Current != fast.Current {
+		t.Fatal("the pace of the animations changed the game")
+	}
+}
+
+func TestGorillaMaskSurvivesReset(t *testing.T) {
+	g := newTestGame()
+	mask := image.NewRGBA(image.Rect(0, 0, 10, 10))
+	draw.Draw(mask, mask.Bounds(), image.NewUniform(color.White), image.Point{}, draw.Src)
+	g.SetGorillaMask(mask)
+	gr := g.Gorillas[0]
+	if g.HitMap.GorillaHitAt(int(gr.X), int(gr.Y)-8) != 0 {
+		t.Fatal("mask should extend the gorilla hit area")
+	}
+	g.Reset()
+	gr = g.Gorillas[0]
+	if g.HitMap.GorillaHitAt(int(gr.X), int(gr.Y)-8) != 0 {
+		t.Fatal("mask should still be used after reset")
+	}
+}
+
+func TestHeadlessGamesHitTheDrawnGorillas(t *testing.T) {
+	settings := DefaultSettings()
+	settings.DefaultGravity = 12
+	g := NewHeadlessGame(WorldWidth, WorldHeight, DefaultBuildingCount, 3, settings)
+	if g.Settings.UseSound || g.Gravity != 12 || g.League != nil || g.ScoreFile != os.DevNull || g.ShotsFile != os.DevNull {
+		t.Fatalf("expected a quiet game that keeps nothing, got %+v", g.Settings)
+	}
+	// the sprite's head stands well above the feet the plain shape covers
+	gr := g.Gorillas[0]
+	if g.HitMap.GorillaHitAt(int(gr.X), int(gr.Y)-25) != 0 {
+		t.Fatal("expected the gorilla's head to be hit")
+	}
+}
+
+func TestCloneSharesNothing(t *testing.T) {
+	g := newFlatGame(t)
+	heard := 0
+	g.Subscribe(ListenerFunc(func(GameEvent) { heard++ }))
+	before := append([]byte(nil), g.HitMap.data...)
+	c := g.Clone()
+	// a weak throw blows a crater in the next roof
+	throwAndLand(c, 60, 30)
+	craters := 0
+	for i := range c.Buildings {
+		craters += len(c.Buildings[i].Damage)
+		if len(g.Buildings[i].Damage) != 0 {
+			t.Fatalf("the clone's crater landed on building %d of the original", i)
+		}
+	}
+	if craters == 0 {
+		t.Fatal("expected the clone's throw to leave a crater")
+	}
+	if !reflect.DeepEqual(before, g.HitMap.data) {
+		t.Fatal("the clone's explosion changed the original's hit map")
+	}
+	if heard != 0 || g.Shots[0] != 0 || g.Current != 0 {
+		t.Fatalf("the clone's throw reached the original: %d events, %v shots", heard, g.Shots)
+	}
+	if c.League != nil || c.Settings.UseSound || c.ResetHook != nil {
+		t.Fatal("a clone should have no league, sound or reset hook")
+	}
+}
+
+func TestShotSearchLeavesTheCityAlone(t *testing.T) {
+	g := newFlatGame(t)
+	before := append([]byte(nil), g.HitMap.data...)
+	sun := g.Sun
+	g.FindShot()
+	for i, b := range g.Buildings {
+		if len(b.Damage) != 0 {
+			t.Fatalf("shot search left a crater on building %d", i)
+		}
+	}
+	if !reflect.DeepEqual(before, g.HitMap.data) || g.Sun != sun {
+		t.Fatal("shot search changed the city")
+	}
+	if _, err := os.Stat(g.ScoreFile); !os.IsNotExist(err) {
+		t.Fatalf("shot search wrote the score file: %v", err)
+	}
+}
+
+func TestFindShotMatchesOneByOneSearch(t *testing.T) {
+	g := newWorldGame()
+	g.Settings.UseSound = false
+	wantA, wantP := 45.0, 50.0
+	for i := 0; i < shotGrid(); i++ {
+		if a, p := shotAt(i); g.testShot(a, p) {
+			wantA, wantP = a, p
+			break
+		}
+	}
+	defer runtime.GOMAXPROCS(runtime.GOMAXPROCS(0))
+	for _, procs := range []int{1, 4} {
+		runtime.GOMAXPROCS(procs)
+		if a, p := g.FindShot(); a != wantA || p != wantP {
+			t.Fatalf("with %d procs found %v/%v, want %v/%v", procs, a, p, wantA, wantP)
+		}
+	}
+}
+
+func TestFindShotContextGivesUpWhenCancelled(t *testing.T) {
+	g := newWorldGame()
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	a, p, err := g.FindShotContext(ctx)
+	if !errors.Is(err, context.Canceled) || a != 45 || p != 50 {
+		t.Fatalf("got %v/%v, %v; want the fallback and context.Canceled", a, p, err)
+	}
+}
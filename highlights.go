@@ -0,0 +1,259 @@
+package gorillas
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/rand"
+	"os"
+	"sort"
+	"time"
+)
+
+// HighlightsPerPlayer is how many of each player's best shots the highlight
+// reel keeps.
+const HighlightsPerPlayer = 5
+
+// ShotStats measures what made a round winning throw hard.
+type ShotStats struct {
+	// Distance is how far the thrower stood from the gorilla they hit.
+	Distance float64 `json:"distance"`
+	// WindAgainst is the strength of the wind blowing back towards the
+	// thrower, zero when it helped the banana along.
+	WindAgainst float64 `json:"windAgainst"`
+	// FlightTime is how long the banana was in the air, in seconds.
+	FlightTime float64 `json:"flightTime"`
+	// Bounces counts the times the banana skipped off the ground.
+	Bounces int `json:"bounces"`
+	// BuildingsPassed counts the buildings the banana cleared between the
+	// thrower and its target.
+	BuildingsPassed int `json:"buildingsPassed"`
+}
+
+// Difficulty scores the shot: a point for every ten units of distance, three
+// for each unit of wind against it, ten for each second in the air, 25 for
+// every bounce and five for every building passed over.
+func (s ShotStats) Difficulty() float64 {
+	return s.Distance/10 + s.WindAgainst*3 + s.FlightTime*10 + float64(s.Bounces)*25 + float64(s.BuildingsPassed)*5
+}
+
+// Highlight is a round winning throw kept in the highlight reel, along with
+// the city as it stood just before the banana left the thrower's hands so it
+// can be thrown again with NewHighlightReplay.
+type Highlight struct {
+	Player  string     `json:"player"`
+	Date    time.Time  `json:"date"`
+	Score   float64    `json:"score"`
+	Stats   ShotStats  `json:"stats"`
+	Shot    ShotRecord `json:"shot"`
+	Players []string   `json:"players"`
+	Teams   []int      `json:"teams,omitempty"`
+	// Out lists the gorillas already knocked out of the round.
+	Out      []int    `json:"out,omitempty"`
+	Settings Settings `json:"settings"`
+	City     *CityMap `json:"city"`
+}
+
+// String describes h on one line, as the best shots lists show it.
+func (h Highlight) String() string {
+	return fmt.Sprintf("%6.1f  %-12s %s  %3.0f units, %d buildings, %d bounces", h.Score, h.Player, h.Date.Format("2006-01-02"), h.Stats.Distance, h.Stats.BuildingsPassed, h.Stats.Bounces)
+}
+
+// flight follows the banana of the latest throw so a winning shot can be
+// scored and kept.
+type flight struct {
+	startX  float64
+	wind    float64
+	steps   int
+	bounces int
+	// craters counts each building's damage before the throw.
+	craters []int
+	// out lists the gorillas already knocked out before the throw.
+	out []int
+}
+
+// startFlight notes the state of the city as a banana is thrown.
+func (g *Game) startFlight() {
+	f := flight{startX: g.Gorillas[g.Current].X, wind: g.Wind, craters: make([]int, len(g.Buildings))}
+	for i, b := range g.Buildings {
+		f.craters[i] = len(b.Damage)
+	}
+	for i, gr := range g.Gorillas {
+		if gr.Dead {
+			f.out = append(f.out, i)
+		}
+	}
+	g.flight = f
+}
+
+// recordHighlight scores the throw that just won the round for player and
+// keeps it in the highlight reel, saving the reel to ShotsFile when the shot
+// made the cut.
+func (g *Game) recordHighlight(player int) {
+	h, ok := g.highlight(player)
+	if ok && g.AddHighlight(h) {
+		g.SaveShots()
+	}
+}
+
+// highlight builds the Highlight for player's winning throw. It reports false
+// when the throw knocked out no opponent, such as when the round was lost to
+// a self kill.
+func (g *Game) highlight(player int) (Highlight, bool) {
+	f := g.flight
+	target := -1
+	for i, gr := range g.Gorillas {
+		if gr.Dead && !containsInt(f.out, i) && g.Side(i) != g.Side(player) {
+			target = i
+			break
+		}
+	}
+	if target < 0 || len(g.ShotHistory) == 0 || len(f.craters) != len(g.Buildings) {
+		return Highlight{}, false
+	}
+	start, end := g.Gorillas[player], g.Gorillas[target]
+	stats := ShotStats{
+		Distance:   math.Hypot(end.X-start.X, end.Y-start.Y),
+		FlightTime: float64(f.steps) * StepDuration.Seconds(),
+		Bounces:    f.bounces,
+	}
+	if dir := end.X - f.startX; dir != 0 {
+		stats.WindAgainst = math.Max(0, -f.wind*math.Copysign(1, dir))
+	}
+	lo, hi := math.Min(f.startX, end.X), math.Max(f.startX, end.X)
+	for _, b := range g.Buildings {
+		if b.X > lo && b.X+b.W < hi {
+			stats.BuildingsPassed++
+		}
+	}
+	city := g.CityMap()
+	for i := range city.Buildings {
+		// leave out the crater the winning banana made
+		city.Buildings[i].Craters = city.Buildings[i].Craters[:f.craters[i]]
+	}
+	return Highlight{
+		Player:   g.Players[player],
+		Date:     time.Now(),
+		Score:    math.Round(stats.Difficulty()*10) / 10,
+		Stats:    stats,
+		Shot:     g.ShotHistory[len(g.ShotHistory)-1],
+		Players:  append([]string(nil), g.Players...),
+		Teams:    append([]int(nil), g.Teams...),
+		Out:      append([]int(nil), f.out...),
+		Settings: g.Settings,
+		City:     city,
+	}, true
+}
+
+func containsInt(s []int, v int) bool {
+	for _, x := range s {
+		if x == v {
+			return true
+		}
+	}
+	return false
+}
+
+// AddHighlight puts h into the highlight reel, best first, keeping only the
+// top HighlightsPerPlayer shots of each player. It reports whether h made
+// the cut.
+func (g *Game) AddHighlight(h Highlight) bool {
+	better := 0
+	for _, o := range g.Highlights {
+		if o.Player == h.Player && o.Score >= h.Score {
+			better++
+		}
+	}
+	if better >= HighlightsPerPlayer {
+		return false
+	}
+	reel := append(g.Highlights, h)
+	sort.SliceStable(reel, func(i, j int) bool { return reel[i].Score > reel[j].Score })
+	kept := reel[:0]
+	counts := map[string]int{}
+	for _, o := range reel {
+		if counts[o.Player] < HighlightsPerPlayer {
+			counts[o.Player]++
+			kept = append(kept, o)
+		}
+	}
+	g.Highlights = kept
+	return true
+}
+
+// LoadHighlights reads a highlight reel saved by SaveShots. A missing file
+// is an empty reel. Older builds kept a list of plain throws in the same
+// file; those are no highlights, so they are left out and go when the reel
+// is next saved.
+func LoadHighlights(path string) ([]Highlight, error) {
+	b, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("load shots: %w", err)
+	}
+	var entries []json.RawMessage
+	if err := json.Unmarshal(b, &entries); err != nil {
+		return nil, fmt.Errorf("load shots: %w", err)
+	}
+	var reel []Highlight
+	for _, e := range entries {
+		var old struct {
+			Shot json.RawMessage `json:"shot"`
+		}
+		if err := json.Unmarshal(e, &old); err != nil {
+			return nil, fmt.Errorf("load shots: %w", err)
+		}
+		if old.Shot == nil {
+			continue
+		}
+		var h Highlight
+		if err := json.Unmarshal(e, &h); err != nil {
+			return nil, fmt.Errorf("load shots: %w", err)
+		}
+		reel = append(reel, h)
+	}
+	return reel, nil
+}
+
+// NewHighlightReplay throws h again in slow motion, in the city it was
+// thrown in.
+func NewHighlightReplay(h Highlight) (*ShotReplay, error) {
+	if len(h.Players) < MinPlayers || len(h.Players) > MaxPlayers {
+		return nil, fmt.Errorf("highlight has %d players", len(h.Players))
+	}
+	if h.City == nil {
+		return nil, fmt.Errorf("highlight has no city")
+	}
+	if err := h.City.Validate(); err != nil {
+		return nil, fmt.Errorf("highlight: %w", err)
+	}
+	if h.Shot.Player < 0 || h.Shot.Player >= len(h.Players) {
+		return nil, fmt.Errorf("highlight thrown by unknown player %d", h.Shot.Player)
+	}
+	if _, err := numberTeams(h.Teams, len(h.Players)); err != nil {
+		return nil, fmt.Errorf("highlight: %w", err)
+	}
+	w, ht := h.City.size()
+	settings := h.Settings
+	settings.WindFluctuations = false
+	g := newGame(int(w), int(ht), len(h.City.Buildings), len(h.Players), settings, h.City, rand.New(rand.NewSource(h.Shot.Seed)))
+	g.Seed = h.Shot.Seed
+	g.League = nil
+	g.Players = append([]string(nil), h.Players...)
+	g.Teams = append([]int(nil), h.Teams...)
+	g.Wins = make([]int, g.Sides())
+	g.TotalWins = make([]int, g.Sides())
+	g.StartMatch()
+	for _, i := range h.Out {
+		if i >= 0 && i < len(g.Gorillas) {
+			g.Gorillas[i].Dead = true
+		}
+	}
+	g.RebuildHitMap()
+	g.setCurrent(h.Shot.Player)
+	g.Angle, g.Power = h.Shot.Angle, h.Shot.Power
+	g.Wind, g.Gravity = h.Shot.Wind, h.Shot.Gravity
+	return newShotReplay(g), nil
+}
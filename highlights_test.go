@@ -0,0 +1,150 @@
+package gorillas
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// throwAndLand throws with angle and power and steps until the banana and
+// its explosion are done.
+func throwAndLand(g *Game, angle, power float64) {
+	g.Angle, g.Power = angle, power
+	g.Throw()
+	for i := 0; i < 2000 && (g.Banana.Active || g.Explosion.Active); i++ {
+		g.step()
+	}
+}
+
+func TestWinningShotJoinsTheHighlightReel(t *testing.T) {
+	g := newFlatGame(t)
+	g.ShotsFile = filepath.Join(t.TempDir(), "shots.json")
+	// both players miss into the next roof before player 1 finds the range
+	throwAndLand(g, 60, 30)
+	throwAndLand(g, 60, 30)
+	craters := 0
+	for _, b := range g.Buildings {
+		craters += len(b.Damage)
+	}
+	throwAndLand(g, 45, 30)
+	if g.Wins[0] != 1 {
+		t.Fatal("expected the third throw to knock out player 2")
+	}
+	if len(g.Highlights) != 1 {
+		t.Fatalf("expected one highlight, got %d", len(g.Highlights))
+	}
+	h := g.Highlights[0]
+	if h.Player != g.Players[0] || h.Shot.Angle != 45 || h.Shot.Power != 30 || h.Shot.Player != 0 {
+		t.Fatalf("unexpected highlight %+v", h)
+	}
+	if h.Stats.BuildingsPassed != 4 || h.Stats.Bounces != 0 || h.Stats.WindAgainst != 0 || h.Stats.Distance < 500 || h.Stats.FlightTime <= 0 {
+		t.Fatalf("unexpected stats %+v", h.Stats)
+	}
+	if math.Abs(h.Score-h.Stats.Difficulty()) > 0.05 {
+		t.Fatalf("score %g does not match stats %+v", h.Score, h.Stats)
+	}
+	n := 0
+	for _, b := range h.City.Buildings {
+		n += len(b.Craters)
+	}
+	if n != craters {
+		t.Fatalf("highlight city has %d craters, want the %d made before the throw", n, craters)
+	}
+
+	saved := newWorldGame()
+	saved.ShotsFile = g.ShotsFile
+	saved.LoadShots()
+	if len(saved.Highlights) != 1 || !reflect.DeepEqual(saved.Highlights[0].Stats, h.Stats) {
+		t.Fatalf("highlight reel was not saved: %+v", saved.Highlights)
+	}
+
+	r, err := NewHighlightReplay(saved.Highlights[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; !r.Done(); i++ {
+		if i > 100000 {
+			t.Fatal("highlight replay never finished")
+		}
+		r.Step(StepDuration)
+	}
+	if !r.Game.Gorillas[1].Dead || r.Game.Gorillas[0].Dead {
+		t.Fatalf("replay should knock out the same gorilla: %+v", r.Game.Gorillas)
+	}
+}
+
+func TestOldShotsFilesLoadWithoutTheirThrows(t *testing.T) {
+	g := newFlatGame(t)
+	g.ShotsFile = filepath.Join(t.TempDir(), "shots.json")
+	throwAndLand(g, 45, 30)
+	reel, err := os.ReadFile(g.ShotsFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range []struct {
+		file string
+		want int
+	}{
+		// the shot history the original port kept
+		{`[{"angle":45,"power":60},{"angle":30,"power":75}]`, 0},
+		// and the one kept once throws knew who made them
+		{`[{"angle":45,"power":60,"player":1,"round":0,"wind":3,"gravity":17,"seed":7}]`, 0},
+		{`[{"angle":45,"power":60},` + string(reel[1:]), 1},
+	} {
+		if err := os.WriteFile(g.ShotsFile, []byte(c.file), 0644); err != nil {
+			t.Fatal(err)
+		}
+		got, err := LoadHighlights(g.ShotsFile)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(got) != c.want {
+			t.Fatalf("%s: expected %d highlights, got %+v", c.file, c.want, got)
+		}
+		for _, h := range got {
+			if _, err := NewHighlightReplay(h); err != nil {
+				t.Fatalf("%s: %v", c.file, err)
+			}
+		}
+	}
+}
+
+func TestSelfKillIsNoHighlight(t *testing.T) {
+	g := newFlatGame(t)
+	g.ShotsFile = filepath.Join(t.TempDir(), "shots.json")
+	throwAndLand(g, 90, 20)
+	if g.Wins[1] != 1 {
+		t.Fatal("expected player 1 to knock themselves out")
+	}
+	if len(g.Highlights) != 0 {
+		t.Fatalf("a self kill should not be a highlight: %+v", g.Highlights)
+	}
+}
+
+func TestHighlightReelKeepsEachPlayersBest(t *testing.T) {
+	g := newTestGame()
+	for i := 1; i <= HighlightsPerPlayer+1; i++ {
+		if !g.AddHighlight(Highlight{Player: "Ann", Score: float64(i)}) {
+			t.Fatalf("shot %d should make the reel", i)
+		}
+	}
+	if !g.AddHighlight(Highlight{Player: "Bob", Score: 0.5}) {
+		t.Fatal("Bob's first shot should make the reel")
+	}
+	if g.AddHighlight(Highlight{Player: "Ann", Score: 1.5}) {
+		t.Fatal("a shot worse than Ann's best should not make the reel")
+	}
+	if len(g.Highlights) != HighlightsPerPlayer+1 {
+		t.Fatalf("expected %d highlights, got %d", HighlightsPerPlayer+1, len(g.Highlights))
+	}
+	if g.Highlights[0].Score != float64(HighlightsPerPlayer+1) || g.Highlights[len(g.Highlights)-1].Player != "Bob" {
+		t.Fatalf("reel should be sorted best first: %+v", g.Highlights)
+	}
+	for _, h := range g.Highlights {
+		if h.Player == "Ann" && h.Score == 1 {
+			t.Fatal("Ann's worst shot should have been dropped")
+		}
+	}
+}
@@ -5,10 +5,21 @@ import "image"
 const (
 	hitMapEmpty    = 0
 	hitMapBuilding = 1
+	// hitMapGorilla0 marks the first gorilla; gorilla i is hitMapGorilla0+i.
 	hitMapGorilla0 = 2
-	hitMapGorilla1 = 3
 )
 
+// gorillaValue returns the value marking gorilla idx.
+func gorillaValue(idx int) byte { return byte(hitMapGorilla0 + idx) }
+
+// gorillaIndex returns the gorilla marked by v, or -1.
+func gorillaIndex(v byte) int {
+	if v < hitMapGorilla0 || int(v) >= hitMapGorilla0+MaxPlayers {
+		return -1
+	}
+	return int(v) - hitMapGorilla0
+}
+
 // HitMap is a simple bitmap identifying hittable objects.
 type HitMap struct {
 	width, height int
@@ -20,6 +31,13 @@ func NewHitMap(w, h int) *HitMap {
 	return &HitMap{width: w, height: h, data: make([]byte, w*h)}
 }
 
+// Clone returns a copy of m that can be changed without affecting m.
+func (m *HitMap) Clone() *HitMap {
+	c := *m
+	c.data = append([]byte(nil), m.data...)
+	return &c
+}
+
 func (m *HitMap) index(x, y int) int { return y*m.width + x }
 
 // At returns the value stored at the given coordinates.
@@ -123,21 +141,14 @@ func (m *HitMap) AnyValueInCircle(cx, cy, r int, val byte) bool {
 
 // DrawGorilla marks the gorilla location with the appropriate value.
 func (m *HitMap) DrawGorilla(x, y int, idx int, r int) {
-	val := hitMapGorilla0
-	if idx == 1 {
-		val = hitMapGorilla1
-	}
-	m.DrawCircle(x, y, r, byte(val))
+	m.DrawCircle(x, y, r, gorillaValue(idx))
 }
 
 // DrawGorillaImage marks non-transparent pixels of img using the same anchor
 // position as DrawGorilla (bottom centre). This allows hit detection to match
 // the rendered gorilla sprite.
 func (m *HitMap) DrawGorillaImage(x, y int, idx int, img image.Image) {
-	val := byte(hitMapGorilla0)
-	if idx == 1 {
-		val = hitMapGorilla1
-	}
+	val := gorillaValue(idx)
 	b := img.Bounds()
 	w, h := b.Dx(), b.Dy()
 	baseX := x - w/2
@@ -154,23 +165,12 @@ func (m *HitMap) DrawGorillaImage(x, y int, idx int, img image.Image) {
 
 // GorillaValue returns the gorilla index stored at the coordinate or -1.
 func (m *HitMap) GorillaValue(x, y int) int {
-	v := m.At(x, y)
-	switch v {
-	case hitMapGorilla0:
-		return 0
-	case hitMapGorilla1:
-		return 1
-	default:
-		return -1
-	}
+	return gorillaIndex(m.At(x, y))
 }
 
 // ClearGorilla removes gorilla pixels for idx.
 func (m *HitMap) ClearGorilla(x, y int, idx int, r int) {
-	val := byte(hitMapGorilla0)
-	if idx == 1 {
-		val = hitMapGorilla1
-	}
+	val := gorillaValue(idx)
 	r2 := r * r
 	for yy := y - r; yy <= y+r; yy++ {
 		if yy < 0 || yy >= m.height {
@@ -189,28 +189,40 @@ func (m *HitMap) ClearGorilla(x, y int, idx int, r int) {
 	}
 }
 
+// RemoveGorilla clears every pixel belonging to gorilla idx, whatever shape
+// it was drawn with.
+func (m *HitMap) RemoveGorilla(idx int) {
+	val := gorillaValue(idx)
+	for i, v := range m.data {
+		if v == val {
+			m.data[i] = hitMapEmpty
+		}
+	}
+}
+
 // GorillaHitInCircle returns the index of a gorilla found within the circle, or -1.
 func (m *HitMap) GorillaHitInCircle(cx, cy, r int) int {
-	if m.AnyValueInCircle(cx, cy, r, hitMapGorilla0) {
-		return 0
-	}
-	if m.AnyValueInCircle(cx, cy, r, hitMapGorilla1) {
-		return 1
+	if hit := m.GorillasInCircle(cx, cy, r); len(hit) > 0 {
+		return hit[0]
 	}
 	return -1
 }
 
+// GorillasInCircle returns the indexes of all gorillas found within the
+// circle in ascending order.
+func (m *HitMap) GorillasInCircle(cx, cy, r int) []int {
+	var hit []int
+	for i := 0; i < MaxPlayers; i++ {
+		if m.AnyValueInCircle(cx, cy, r, gorillaValue(i)) {
+			hit = append(hit, i)
+		}
+	}
+	return hit
+}
+
 // GorillaHitAt checks if a gorilla occupies the exact coordinate.
 func (m *HitMap) GorillaHitAt(x, y int) int {
-	v := m.At(x, y)
-	switch v {
-	case hitMapGorilla0:
-		return 0
-	case hitMapGorilla1:
-		return 1
-	default:
-		return -1
-	}
+	return gorillaIndex(m.At(x, y))
 }
 
 // AddBuilding draws the building rectangle on the hit map.
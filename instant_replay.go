@@ -0,0 +1,64 @@
+package gorillas
+
+import "time"
+
+// InstantReplaySlowdown is how many times slower than real time a
+// ShotReplay plays its throw.
+const InstantReplaySlowdown = 4
+
+// ShotReplay re-runs a single throw in slow motion on a private copy of the
+// game, tracing the banana's path as it goes. Nothing it does reaches the
+// live game's scores, league or listeners.
+type ShotReplay struct {
+	Game *Game
+	// Trail holds every position the banana has passed through so far.
+	Trail []VectorPoint
+}
+
+// InstantReplay returns a replay of the latest throw, thrown again from the
+// state the game was in just before it. It returns nil unless
+// Settings.InstantReplay is on and a banana has been thrown since the city
+// was built. Frontends typically call it on RoundOver, before the explosion
+// finishes and Reset raises the next city.
+func (g *Game) InstantReplay() *ShotReplay {
+	if g.lastThrow == nil {
+		return nil
+	}
+	sim := g.lastThrow.snapshot()
+	// the copy already carries the wind the banana flew through
+	sim.Settings.WindFluctuations = false
+	return newShotReplay(sim)
+}
+
+// newShotReplay throws sim's banana with its current angle and power,
+// tracing it from the start.
+func newShotReplay(sim *Game) *ShotReplay {
+	sim.replaying = true
+	r := &ShotReplay{Game: sim}
+	sim.Subscribe(ListenerFunc(func(e GameEvent) {
+		if e.Kind == ThrowStarted || e.Kind == BananaMoved {
+			r.Trail = append(r.Trail, VectorPoint{e.X, e.Y})
+		}
+	}))
+	sim.Throw()
+	return r
+}
+
+// Step advances the replay by dt of real time, slowed down by
+// InstantReplaySlowdown.
+func (r *ShotReplay) Step(dt time.Duration) {
+	r.Game.Step(dt / InstantReplaySlowdown)
+}
+
+// Done reports whether the banana has landed and its explosion finished.
+func (r *ShotReplay) Done() bool {
+	return !r.Game.Banana.Active && !r.Game.Explosion.Active
+}
+
+// snapshot returns a Clone of g to throw again later. The throw is watched,
+// so unlike a plain Clone it keeps g's sound.
+func (g *Game) snapshot() *Game {
+	s := g.Clone()
+	s.Settings.UseSound = g.Settings.UseSound
+	return s
+}
@@ -0,0 +1,95 @@
+package gorillas
+
+import (
+	"reflect"
+	"testing"
+)
+
+// newFlatGame returns a two player game on an even, windless city.
+func newFlatGame(t *testing.T) *Game {
+	t.Helper()
+	g := newWorldGame()
+	g.ScoreFile, g.ShotsFile = t.TempDir()+"/scores.json", t.TempDir()+"/shots.json"
+	g.Settings.UseSound = false
+	city := &CityMap{Wind: Fixed(0)}
+	for i := 0; i < 8; i++ {
+		city.Buildings = append(city.Buildings, MapBuilding{X: float64(i) * 100, Width: 100, Height: 150 + float64(i%2)*50})
+	}
+	if err := g.ApplyMap(city); err != nil {
+		t.Fatal(err)
+	}
+	return g
+}
+
+func TestInstantReplayRethrowsTheWinningShot(t *testing.T) {
+	g := newFlatGame(t)
+	g.Settings.InstantReplay = true
+	var r *ShotReplay
+	g.Subscribe(ListenerFunc(func(e GameEvent) {
+		if e.Kind == RoundOver {
+			r = g.InstantReplay()
+		}
+	}))
+	// a weak throw into the next roof leaves a crater for the replay
+	g.Angle, g.Power = 60, 30
+	g.Throw()
+	for i := 0; i < 2000 && (g.Banana.Active || g.Explosion.Active); i++ {
+		g.step()
+	}
+	// then player 2 throws straight up and lands on their own head
+	g.Angle, g.Power = 90, 20
+	g.Throw()
+	liveSteps := 0
+	for ; g.Banana.Active; liveSteps++ {
+		g.step()
+	}
+	if r == nil {
+		t.Fatal("expected an instant replay when the round was won")
+	}
+	wins := append([]int(nil), g.Wins...)
+	played := g.Match.Played
+	buildings := g.snapshot().Buildings
+
+	craters := 0
+	for _, b := range r.Game.Buildings {
+		craters += len(b.Damage)
+	}
+	if craters == 0 {
+		t.Fatal("the replay should start from the damaged city")
+	}
+	steps := 0
+	for ; !r.Done(); steps++ {
+		if steps > 100000 {
+			t.Fatal("replay never finished")
+		}
+		r.Step(StepDuration)
+	}
+	if !r.Game.Gorillas[1].Dead || r.Game.Gorillas[0].Dead {
+		t.Fatalf("replay should knock out the same gorilla: %+v", r.Game.Gorillas)
+	}
+	if steps < liveSteps*InstantReplaySlowdown {
+		t.Fatalf("replay took %d steps for a %d step throw, want slow motion", steps, liveSteps)
+	}
+	if len(r.Trail) < liveSteps || r.Trail[0] != (VectorPoint{g.Gorillas[1].X, g.Gorillas[1].Y}) {
+		t.Fatalf("trail should follow the banana from the thrower, got %d points", len(r.Trail))
+	}
+	if len(r.Game.Buildings) != len(buildings) || r.Game.Match.Played != played {
+		t.Fatal("the replay should stay in the same city and round")
+	}
+	if !reflect.DeepEqual(g.Wins, wins) || g.Match.Played != played || !reflect.DeepEqual(g.Buildings, buildings) {
+		t.Fatal("the replay should leave the live game alone")
+	}
+}
+
+func TestInstantReplayIsOptional(t *testing.T) {
+	g := newFlatGame(t)
+	g.Angle, g.Power = 90, 20
+	g.Throw()
+	if g.InstantReplay() != nil {
+		t.Fatal("expected no instant replay with the setting off")
+	}
+	g.Settings.InstantReplay = true
+	if g.InstantReplay() != nil {
+		t.Fatal("expected no instant replay before a throw is made with it on")
+	}
+}
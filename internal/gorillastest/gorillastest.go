@@ -0,0 +1,53 @@
+// Package gorillastest holds what the golden tests of the renderers share:
+// playing a game to a picture worth keeping, and keeping it in testdata.
+package gorillastest
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/arran4/gorillas"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+// Explode throws from the current gorilla and plays on until the explosion
+// reaches frame.
+func Explode(t *testing.T, g *gorillas.Game, angle, power float64, frame int) {
+	t.Helper()
+	g.Angle, g.Power = angle, power
+	g.Throw()
+	for i := 0; i < 2000 && !g.Explosion.Active; i++ {
+		g.Step(gorillas.StepDuration)
+	}
+	// frames can last several steps, as they do in the tcell port
+	for i := 0; i < 2000 && g.Explosion.Active && g.Explosion.Frame < frame; i++ {
+		g.Step(gorillas.StepDuration)
+	}
+	if !g.Explosion.Active || g.Explosion.Frame != frame {
+		t.Fatalf("expected the explosion at frame %d, got %+v", frame, g.Explosion)
+	}
+}
+
+// Golden returns the golden file at path for got to be compared with. Run
+// with -update, it writes got there instead and returns it, accepting a
+// deliberate change.
+func Golden(t *testing.T, path string, got []byte) []byte {
+	t.Helper()
+	if *update {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, got, 0644); err != nil {
+			t.Fatal(err)
+		}
+		return got
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("%v; run the test with -update to create it", err)
+	}
+	return want
+}
@@ -0,0 +1,194 @@
+package gorillas
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// LANPort is the UDP port hosts announce their games on.
+const LANPort = 4647
+
+// LANBroadcast is where hosts announce their games: LANPort on every
+// machine on the local network.
+const LANBroadcast = "255.255.255.255:4647"
+
+const (
+	// LANAnnounceEvery is how often a host announces its game.
+	LANAnnounceEvery = time.Second
+	// LANTimeout is how long a game stays listed after its last
+	// announcement, long enough to ride out a lost datagram or two.
+	LANTimeout = 4 * LANAnnounceEvery
+)
+
+// lanType marks a datagram as a game announcement.
+const lanType = "gorillas"
+
+// LANGame is a network game waiting for a player, as its host announces it
+// on the local network in one JSON datagram such as
+// {"type":"gorillas","protocol":1,"name":"Ann","port":4646,"players":["Ann"],
+// "seats":2,"rounds":3,"match":"bestof","gravity":17,"skyline":"basic"}.
+type LANGame struct {
+	Type     string `json:"type"`
+	Protocol int    `json:"protocol"`
+	// Name is the host's player name and Port the TCP port it waits on.
+	Name    string   `json:"name"`
+	Port    int      `json:"port"`
+	Players []string `json:"players"`
+	Seats   int      `json:"seats"`
+	Rounds  int      `json:"rounds"`
+	Match   string   `json:"match"`
+	Gravity float64  `json:"gravity"`
+	Skyline string   `json:"skyline"`
+	// Map is set when the game is played on a city map.
+	Map bool `json:"map,omitempty"`
+	// Addr is where to join the game, the announcement's sender at Port,
+	// and Seen when it was last announced. Neither is sent.
+	Addr string    `json:"-"`
+	Seen time.Time `json:"-"`
+}
+
+// NewLANGame describes the network game g is hosting on l.
+func NewLANGame(g *Game, l net.Listener) LANGame {
+	port := 0
+	if a, ok := l.Addr().(*net.TCPAddr); ok {
+		port = a.Port
+	}
+	return LANGame{
+		Type:     lanType,
+		Protocol: NetProtocol,
+		Name:     g.Players[0],
+		Port:     port,
+		Players:  g.Players[:1],
+		Seats:    MinPlayers,
+		Rounds:   g.Settings.DefaultRoundQty,
+		Match:    g.Settings.MatchMode.String(),
+		Gravity:  g.Settings.DefaultGravity,
+		Skyline:  g.Settings.Skyline,
+		Map:      g.Map != nil,
+	}
+}
+
+// String describes the game in a line for a list to pick from.
+func (lg LANGame) String() string {
+	city := lg.Skyline + " skyline"
+	if lg.Map {
+		city = "city map"
+	}
+	return fmt.Sprintf("%s (%d/%d) - %s %d, gravity %.0f, %s - %s",
+		lg.Name, len(lg.Players), lg.Seats, lg.Match, lg.Rounds, lg.Gravity, city, lg.Addr)
+}
+
+// AdvertiseLAN announces game to addr, usually LANBroadcast, every
+// LANAnnounceEvery until ctx is done. Announcements that cannot be sent,
+// say while the network is down, are skipped.
+func AdvertiseLAN(ctx context.Context, addr string, game LANGame) error {
+	to, err := net.ResolveUDPAddr("udp", addr)
+	if err != nil {
+		return err
+	}
+	conn, err := net.ListenUDP("udp", nil)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	game.Type, game.Protocol = lanType, NetProtocol
+	b, err := json.Marshal(game)
+	if err != nil {
+		return err
+	}
+	t := time.NewTicker(LANAnnounceEvery)
+	defer t.Stop()
+	for {
+		conn.WriteTo(b, to)
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-t.C:
+		}
+	}
+}
+
+// LANBrowser collects the games announced on the local network.
+type LANBrowser struct {
+	// Timeout is how long a game stays listed after its last
+	// announcement, LANTimeout unless changed.
+	Timeout time.Duration
+
+	conn  net.PacketConn
+	mu    sync.Mutex
+	games map[string]LANGame
+}
+
+// BrowseLAN listens for announcements on addr, such as ":4647" for LANPort
+// on every interface, until Close. Datagrams that are not announcements of
+// this protocol are ignored.
+func BrowseLAN(addr string) (*LANBrowser, error) {
+	conn, err := net.ListenPacket("udp", addr)
+	if err != nil {
+		return nil, err
+	}
+	b := &LANBrowser{Timeout: LANTimeout, conn: conn, games: map[string]LANGame{}}
+	go b.listen()
+	return b, nil
+}
+
+// listen records announcements until the browser is closed.
+func (b *LANBrowser) listen() {
+	buf := make([]byte, 64<<10)
+	for {
+		n, from, err := b.conn.ReadFrom(buf)
+		if err != nil {
+			return
+		}
+		var game LANGame
+		if json.Unmarshal(buf[:n], &game) != nil || game.Type != lanType || game.Protocol != NetProtocol || game.Port <= 0 || game.Port > 65535 {
+			continue
+		}
+		host, _, err := net.SplitHostPort(from.String())
+		if err != nil {
+			continue
+		}
+		game.Addr = net.JoinHostPort(host, strconv.Itoa(game.Port))
+		game.Seen = time.Now()
+		b.mu.Lock()
+		b.games[game.Addr] = game
+		b.mu.Unlock()
+	}
+}
+
+// Addr returns the address the browser listens on.
+func (b *LANBrowser) Addr() net.Addr {
+	return b.conn.LocalAddr()
+}
+
+// Games returns the games announced within Timeout, ordered by host name.
+func (b *LANBrowser) Games() []LANGame {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	var games []LANGame
+	for addr, game := range b.games {
+		if time.Since(game.Seen) > b.Timeout {
+			delete(b.games, addr)
+			continue
+		}
+		games = append(games, game)
+	}
+	sort.Slice(games, func(i, j int) bool {
+		if games[i].Name != games[j].Name {
+			return games[i].Name < games[j].Name
+		}
+		return games[i].Addr < games[j].Addr
+	})
+	return games
+}
+
+// Close stops listening.
+func (b *LANBrowser) Close() error {
+	return b.conn.Close()
+}
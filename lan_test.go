@@ -0,0 +1,114 @@
+package gorillas
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+)
+
+// browse returns a browser listening on loopback.
+func browse(t *testing.T) *LANBrowser {
+	t.Helper()
+	b, err := BrowseLAN("127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { b.Close() })
+	return b
+}
+
+// waitForGames polls b until it lists n games or a few seconds pass.
+func waitForGames(t *testing.T, b *LANBrowser, n int) []LANGame {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		games := b.Games()
+		if len(games) == n {
+			return games
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("expected %d games, got %v", n, games)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestLANGameCanBeFoundAndJoined(t *testing.T) {
+	hostGame, joinGame := newNetGame(t, "Ann", 7), newNetGame(t, "Bob", 99)
+	hostGame.Settings.DefaultRoundQty = 3
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	b := browse(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go AdvertiseLAN(ctx, b.Addr().String(), NewLANGame(hostGame, l))
+	var hostSide *NetSession
+	hosted := make(chan error, 1)
+	go func() {
+		var err error
+		hostSide, err = HostNet(ctx, l, hostGame)
+		hosted <- err
+	}()
+
+	games := waitForGames(t, b, 1)
+	if games[0].Name != "Ann" || games[0].Addr != l.Addr().String() || games[0].Rounds != 3 || len(games[0].Players) != 1 || games[0].Seats != 2 {
+		t.Fatalf("unexpected announcement %+v", games[0])
+	}
+	s, err := JoinNet(context.Background(), games[0].Addr, joinGame)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	if err := <-hosted; err != nil {
+		t.Fatal(err)
+	}
+	defer hostSide.Close()
+	if joinGame.StateHash() != hostGame.StateHash() {
+		t.Fatal("the joiner did not take the host's match")
+	}
+}
+
+func TestLANBrowserForgetsStaleGames(t *testing.T) {
+	b := browse(t)
+	b.Timeout = 50 * time.Millisecond
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- AdvertiseLAN(ctx, b.Addr().String(), NewLANGame(newNetGame(t, "Ann", 7), l)) }()
+	waitForGames(t, b, 1)
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	waitForGames(t, b, 0)
+}
+
+func TestLANBrowserIgnoresOtherDatagrams(t *testing.T) {
+	b := browse(t)
+	conn, err := net.Dial("udp", b.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	for _, d := range []string{
+		"not json",
+		`{"type":"other","protocol":1,"name":"Eve","port":4646}`,
+		`{"type":"gorillas","protocol":99,"name":"Eve","port":4646}`,
+		`{"type":"gorillas","protocol":1,"name":"Eve","port":0}`,
+		`{"type":"gorillas","protocol":1,"name":"Ann","port":4646}`,
+	} {
+		conn.Write([]byte(d))
+	}
+	games := waitForGames(t, b, 1)
+	if games[0].Name != "Ann" || games[0].Addr != "127.0.0.1:4646" {
+		t.Fatalf("unexpected game %+v", games[0])
+	}
+}
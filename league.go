@@ -18,7 +18,32 @@ type PlayerStats struct {
 // League manages a set of PlayerStats loaded from disk.
 type League struct {
 	Players map[string]*PlayerStats `json:"players"`
-	file    string
+	// Teams holds statistics for each line-up that has played as a team,
+	// keyed by TeamName.
+	Teams map[string]*PlayerStats `json:"teams,omitempty"`
+	file  string
+}
+
+// leagueFileVersion marks league files that hold teams as well as players.
+// Older files are a bare map of player statistics.
+const leagueFileVersion = 2
+
+type leagueFile struct {
+	Version int                     `json:"version"`
+	Players map[string]*PlayerStats `json:"players"`
+	Teams   map[string]*PlayerStats `json:"teams,omitempty"`
+}
+
+// TeamName returns the league key for a team made up of members.
+func TeamName(members []string) string {
+	sorted := append([]string(nil), members...)
+	sort.Strings(sorted)
+	return strings.Join(sorted, " & ")
+}
+
+// teamMembers splits a TeamName back into its members.
+func teamMembers(team string) []string {
+	return strings.Split(team, " & ")
 }
 
 // AddPlayer ensures a player exists in the league.
@@ -46,6 +71,20 @@ func (l *League) RenamePlayer(oldName, newName string) {
 		delete(l.Players, oldName)
 		l.Players[newName] = ps
 	}
+	for team, ts := range l.Teams {
+		members := teamMembers(team)
+		renamed := false
+		for i, m := range members {
+			if m == oldName {
+				members[i] = newName
+				renamed = true
+			}
+		}
+		if renamed {
+			delete(l.Teams, team)
+			l.Teams[TeamName(members)] = ts
+		}
+	}
 }
 
 // DeletePlayer removes a player from the league.
@@ -54,6 +93,14 @@ func (l *League) DeletePlayer(name string) {
 		return
 	}
 	delete(l.Players, name)
+	for team := range l.Teams {
+		for _, m := range teamMembers(team) {
+			if m == name {
+				delete(l.Teams, team)
+				break
+			}
+		}
+	}
 }
 
 // Names returns the list of player names sorted alphabetically.
@@ -71,11 +118,23 @@ func (l *League) Names() []string {
 
 // LoadLeague reads statistics from the given file.
 func LoadLeague(path string) *League {
-	l := &League{Players: map[string]*PlayerStats{}, file: path}
-	if b, err := os.ReadFile(path); err == nil {
-		if err := json.Unmarshal(b, &l.Players); err != nil {
-			fmt.Fprintf(os.Stderr, "load league: %v\n", err)
+	l := &League{Players: map[string]*PlayerStats{}, Teams: map[string]*PlayerStats{}, file: path}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return l
+	}
+	var f leagueFile
+	if json.Unmarshal(b, &f) == nil && f.Version > 0 {
+		if f.Players != nil {
+			l.Players = f.Players
+		}
+		if f.Teams != nil {
+			l.Teams = f.Teams
 		}
+		return l
+	}
+	if err := json.Unmarshal(b, &l.Players); err != nil {
+		fmt.Fprintf(os.Stderr, "load league: %v\n", err)
 	}
 	return l
 }
@@ -88,7 +147,12 @@ func (l *League) Save() {
 	if l.file == "" {
 		return
 	}
-	if b, err := json.Marshal(l.Players); err == nil {
+	// without any teams keep writing the original format older builds read
+	var v any = l.Players
+	if len(l.Teams) > 0 {
+		v = leagueFile{Version: leagueFileVersion, Players: l.Players, Teams: l.Teams}
+	}
+	if b, err := json.Marshal(v); err == nil {
 		if err := os.WriteFile(l.file, b, 0644); err != nil {
 			fmt.Fprintf(os.Stderr, "save league: %v\n", err)
 		}
@@ -99,32 +163,58 @@ func (l *League) Save() {
 // winner indicates which player won (0 or 1). shots is how many throws
 // the winner took to achieve the hit.
 func (l *League) RecordRound(p1, p2 string, winner, shots int) {
+	l.RecordMatchRound([]string{p1, p2}, winner, shots)
+}
+
+// RecordMatchRound updates the league for a round between any number of
+// players. winner is the index in players of the last gorilla standing and
+// shots is how many throws they took.
+func (l *League) RecordMatchRound(players []string, winner, shots int) {
 	if l == nil {
 		return
 	}
-	ps1 := l.getPlayer(p1)
-	ps2 := l.getPlayer(p2)
-	ps1.Rounds++
-	ps2.Rounds++
-	if winner == 0 {
-		ps := ps1
-		ps.Wins++
-		if shots > 0 {
-			if ps.Accuracy > 0 {
-				ps.Accuracy = (ps.Accuracy + float64(shots)) / 2
-			} else {
-				ps.Accuracy = float64(shots)
-			}
+	for i, name := range players {
+		l.getPlayer(name).record(i == winner, shots)
+	}
+}
+
+// RecordTeamRound updates the league for a round between teams, each given
+// as its members' names. winner is the index of the team left standing and
+// shots is how many throws the whole team took. Every member shares in the
+// team's result.
+func (l *League) RecordTeamRound(teams [][]string, winner, shots int) {
+	if l == nil {
+		return
+	}
+	if l.Teams == nil {
+		l.Teams = map[string]*PlayerStats{}
+	}
+	for i, members := range teams {
+		name := TeamName(members)
+		ts, ok := l.Teams[name]
+		if !ok {
+			ts = &PlayerStats{}
+			l.Teams[name] = ts
 		}
-	} else if winner == 1 {
-		ps := ps2
-		ps.Wins++
-		if shots > 0 {
-			if ps.Accuracy > 0 {
-				ps.Accuracy = (ps.Accuracy + float64(shots)) / 2
-			} else {
-				ps.Accuracy = float64(shots)
-			}
+		ts.record(i == winner, shots)
+		for _, m := range members {
+			l.getPlayer(m).record(i == winner, shots)
+		}
+	}
+}
+
+// record counts a round played, and for a win the shots it took.
+func (ps *PlayerStats) record(won bool, shots int) {
+	ps.Rounds++
+	if !won {
+		return
+	}
+	ps.Wins++
+	if shots > 0 {
+		if ps.Accuracy > 0 {
+			ps.Accuracy = (ps.Accuracy + float64(shots)) / 2
+		} else {
+			ps.Accuracy = float64(shots)
 		}
 	}
 }
@@ -142,12 +232,27 @@ func (l *League) getPlayer(name string) *PlayerStats {
 func (l *League) Standings() []struct {
 	Name string
 	PlayerStats
+} {
+	return standings(l.Players)
+}
+
+// TeamStandings returns the team table sorted like Standings.
+func (l *League) TeamStandings() []struct {
+	Name string
+	PlayerStats
+} {
+	return standings(l.Teams)
+}
+
+func standings(stats map[string]*PlayerStats) []struct {
+	Name string
+	PlayerStats
 } {
 	var list []struct {
 		Name string
 		PlayerStats
 	}
-	for name, ps := range l.Players {
+	for name, ps := range stats {
 		list = append(list, struct {
 			Name string
 			PlayerStats
@@ -181,5 +286,11 @@ func (l *League) String() string {
 		_ = i
 		b.WriteString(fmt.Sprintf("%-15s %6d %4d %8.1f\n", s.Name, s.Rounds, s.Wins, s.Accuracy))
 	}
+	if len(l.Teams) > 0 {
+		b.WriteString("\nTeam             Rounds Wins Accuracy\n")
+		for _, s := range l.TeamStandings() {
+			b.WriteString(fmt.Sprintf("%-15s %6d %4d %8.1f\n", s.Name, s.Rounds, s.Wins, s.Accuracy))
+		}
+	}
 	return b.String()
 }
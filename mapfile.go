@@ -0,0 +1,308 @@
+package gorillas
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"image/color"
+	"math/rand"
+	"os"
+	"strings"
+)
+
+// Window is a window on a building, placed in world units from the
+// building's top left corner. Only lit windows are drawn; dark ones show the
+// wall behind them.
+type Window struct {
+	X   float64 `json:"x"`
+	Y   float64 `json:"y"`
+	Lit bool    `json:"lit,omitempty"`
+}
+
+// Crater is a hole blown in a building, centred X and Y world units from the
+// building's top left corner.
+type Crater struct {
+	X float64 `json:"x"`
+	Y float64 `json:"y"`
+	R float64 `json:"r"`
+}
+
+// CityMap describes a hand made arena that replaces the randomly generated
+// city. Coordinates are in world units for a city Width by Height units in
+// size, measured from the top left corner, and are scaled to fit the game.
+type CityMap struct {
+	Name      string        `json:"name,omitempty"`
+	Width     float64       `json:"width,omitempty"`
+	Height    float64       `json:"height,omitempty"`
+	Buildings []MapBuilding `json:"buildings"`
+	// Spawns lists the building each gorilla starts on, in seating order.
+	// Without enough spawns the gorillas are spread over the city as usual.
+	Spawns []int `json:"spawns,omitempty"`
+	// Sun places the sun; it keeps its usual spot when nil.
+	Sun *MapSun `json:"sun,omitempty"`
+	// Wind and Gravity replace the random wind and configured gravity.
+	Wind    *Range `json:"wind,omitempty"`
+	Gravity *Range `json:"gravity,omitempty"`
+}
+
+// MapBuilding is a single building of a CityMap. Height is measured up from
+// the ground and Colour is written as "#rrggbb". Craters let a map start
+// part way through a round, already damaged.
+type MapBuilding struct {
+	X       float64  `json:"x"`
+	Width   float64  `json:"width"`
+	Height  float64  `json:"height"`
+	Colour  string   `json:"colour,omitempty"`
+	Windows []Window `json:"windows,omitempty"`
+	Craters []Crater `json:"craters,omitempty"`
+}
+
+// MapSun positions the sun. A zero Radius keeps the usual size.
+type MapSun struct {
+	X      float64 `json:"x"`
+	Y      float64 `json:"y"`
+	Radius float64 `json:"radius,omitempty"`
+}
+
+// Range is a setting that is either fixed or drawn afresh each round from
+// Min to Max. In JSON a fixed value is a plain number and a range is
+// {"min": a, "max": b}.
+type Range struct {
+	Min float64 `json:"min"`
+	Max float64 `json:"max"`
+}
+
+// Fixed returns a Range that always yields v.
+func Fixed(v float64) *Range {
+	return &Range{Min: v, Max: v}
+}
+
+// UnmarshalJSON accepts either a number or a min/max object.
+func (r *Range) UnmarshalJSON(b []byte) error {
+	var v float64
+	if err := json.Unmarshal(b, &v); err == nil {
+		r.Min, r.Max = v, v
+		return nil
+	}
+	type plain Range
+	return json.Unmarshal(b, (*plain)(r))
+}
+
+// MarshalJSON writes a fixed Range as a plain number.
+func (r Range) MarshalJSON() ([]byte, error) {
+	if r.Min == r.Max {
+		return json.Marshal(r.Min)
+	}
+	type plain Range
+	return json.Marshal(plain(r))
+}
+
+func (r Range) pick(rng *rand.Rand) float64 {
+	if r.Max <= r.Min {
+		return r.Min
+	}
+	return r.Min + rng.Float64()*(r.Max-r.Min)
+}
+
+func (m *CityMap) size() (float64, float64) {
+	w, h := m.Width, m.Height
+	if w <= 0 {
+		w = WorldWidth
+	}
+	if h <= 0 {
+		h = WorldHeight
+	}
+	return w, h
+}
+
+// Validate reports the first problem that would stop the map from being
+// played.
+func (m *CityMap) Validate() error {
+	w, h := m.size()
+	if len(m.Buildings) < 3 {
+		return fmt.Errorf("map needs at least 3 buildings, has %d", len(m.Buildings))
+	}
+	end := 0.0
+	for i, b := range m.Buildings {
+		switch {
+		case b.Width <= 0:
+			return fmt.Errorf("building %d has no width", i)
+		case b.Height < 0 || b.Height > h:
+			return fmt.Errorf("building %d is %g tall, outside 0 to %g", i, b.Height, h)
+		case b.X < end:
+			return fmt.Errorf("building %d overlaps the one before it", i)
+		case b.X+b.Width > w:
+			return fmt.Errorf("building %d runs past the edge of the city", i)
+		}
+		if _, err := parseColour(b.Colour); err != nil {
+			return fmt.Errorf("building %d: %w", i, err)
+		}
+		for _, c := range b.Craters {
+			if c.R <= 0 {
+				return fmt.Errorf("building %d has a crater without a radius", i)
+			}
+		}
+		end = b.X + b.Width
+	}
+	used := map[int]bool{}
+	for i, s := range m.Spawns {
+		if s < 0 || s >= len(m.Buildings) {
+			return fmt.Errorf("spawn %d is on building %d, which does not exist", i, s)
+		}
+		if used[s] {
+			return fmt.Errorf("spawn %d shares building %d", i, s)
+		}
+		used[s] = true
+	}
+	for name, r := range map[string]*Range{"wind": m.Wind, "gravity": m.Gravity} {
+		if r != nil && r.Max < r.Min {
+			return fmt.Errorf("%s range %g to %g is backwards", name, r.Min, r.Max)
+		}
+	}
+	if m.Gravity != nil && m.Gravity.Min <= 0 {
+		return fmt.Errorf("gravity must be positive")
+	}
+	return nil
+}
+
+// LoadMap reads and checks a CityMap from a JSON file.
+func LoadMap(path string) (*CityMap, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("load map: %w", err)
+	}
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.DisallowUnknownFields()
+	var m CityMap
+	if err := dec.Decode(&m); err != nil {
+		return nil, fmt.Errorf("load map %s: %w", path, err)
+	}
+	if err := m.Validate(); err != nil {
+		return nil, fmt.Errorf("load map %s: %w", path, err)
+	}
+	return &m, nil
+}
+
+// SaveMap checks m and writes it to path as indented JSON.
+func SaveMap(path string, m *CityMap) error {
+	if err := m.Validate(); err != nil {
+		return fmt.Errorf("save map: %w", err)
+	}
+	b, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return fmt.Errorf("save map: %w", err)
+	}
+	if err := os.WriteFile(path, append(b, '\n'), 0644); err != nil {
+		return fmt.Errorf("save map: %w", err)
+	}
+	return nil
+}
+
+// ApplyMap makes every round from now on play in the arena m instead of a
+// random city, starting with a fresh one now. Passing nil goes back to
+// random cities.
+func (g *Game) ApplyMap(m *CityMap) error {
+	if m != nil {
+		if err := m.Validate(); err != nil {
+			return err
+		}
+		if len(m.Spawns) < len(g.Gorillas) && len(m.Buildings) < len(g.Gorillas)+2 {
+			return fmt.Errorf("map has no room for %d gorillas", len(g.Gorillas))
+		}
+	}
+	if g.Map != nil && g.Map.Gravity != nil {
+		// the old map's gravity should not outlive it
+		g.Gravity = g.Settings.DefaultGravity
+	}
+	g.Map = m
+	cur := g.Current
+	g.Reset()
+	g.setCurrent(cur)
+	return nil
+}
+
+// CityMap captures the current city as a map, craters included, with the
+// gorillas' buildings as spawns and the wind and gravity fixed at their
+// present values, ready to be edited and saved with SaveMap.
+func (g *Game) CityMap() *CityMap {
+	m := &CityMap{
+		Width:   float64(g.Width),
+		Height:  float64(g.Height),
+		Sun:     &MapSun{X: g.Sun.X, Y: g.Sun.Y, Radius: g.Sun.R},
+		Wind:    Fixed(g.Wind),
+		Gravity: Fixed(g.Gravity),
+	}
+	for _, b := range g.Buildings {
+		mb := MapBuilding{X: b.X, Width: b.W, Height: b.H, Windows: append([]Window(nil), b.Windows...)}
+		top := float64(g.Height) - b.H
+		for _, d := range b.Damage {
+			mb.Craters = append(mb.Craters, Crater{X: d.X - b.X, Y: d.Y - top, R: d.R})
+		}
+		if b.Color.A != 0 {
+			mb.Colour = fmt.Sprintf("#%02x%02x%02x", b.Color.R, b.Color.G, b.Color.B)
+		}
+		m.Buildings = append(m.Buildings, mb)
+	}
+	for _, gr := range g.Gorillas {
+		m.Spawns = append(m.Spawns, g.buildingAt(gr.X))
+	}
+	return m
+}
+
+// buildMap lays out the city from m in place of the random skyline.
+func (g *Game) buildMap(m *CityMap, rng *rand.Rand) {
+	w, h := m.size()
+	sx, sy := float64(g.Width)/w, float64(g.Height)/h
+	g.Buildings = make([]Building, len(m.Buildings))
+	for i, mb := range m.Buildings {
+		b := Building{X: mb.X * sx, W: mb.Width * sx, H: mb.Height * sy}
+		b.Color, _ = parseColour(mb.Colour)
+		for _, win := range mb.Windows {
+			b.Windows = append(b.Windows, Window{X: win.X * sx, Y: win.Y * sy, Lit: win.Lit})
+		}
+		top := float64(g.Height) - b.H
+		for _, c := range mb.Craters {
+			b.Damage = append(b.Damage, DamageCircle{X: b.X + c.X*sx, Y: top + c.Y*sy, R: c.R * sy})
+		}
+		g.Buildings[i] = b
+	}
+	if len(m.Spawns) >= len(g.Gorillas) {
+		for i := range g.Gorillas {
+			b := g.Buildings[m.Spawns[i]]
+			g.Gorillas[i] = Gorilla{X: b.X + b.W/2, Y: float64(g.Height) - b.H}
+		}
+	} else {
+		g.placeGorillas()
+	}
+	if m.Sun != nil {
+		g.Sun.X, g.Sun.Y = m.Sun.X*sx, m.Sun.Y*sy
+		if m.Sun.Radius > 0 {
+			g.Sun.R = m.Sun.Radius * sy
+		}
+	}
+	if m.Wind != nil {
+		g.Wind = m.Wind.pick(rng)
+	} else {
+		g.Wind = basicWind(rng)
+	}
+	if m.Gravity != nil {
+		g.Gravity = m.Gravity.pick(rng)
+	}
+}
+
+// parseColour reads a "#rrggbb" colour. An empty string gives the zero
+// colour, leaving frontends to choose one.
+func parseColour(s string) (color.RGBA, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return color.RGBA{}, nil
+	}
+	var r, g, b uint8
+	if len(s) != 7 || s[0] != '#' {
+		return color.RGBA{}, fmt.Errorf("colour %q is not #rrggbb", s)
+	}
+	if _, err := fmt.Sscanf(s[1:], "%02x%02x%02x", &r, &g, &b); err != nil {
+		return color.RGBA{}, fmt.Errorf("colour %q is not #rrggbb", s)
+	}
+	return color.RGBA{r, g, b, 255}, nil
+}
@@ -0,0 +1,163 @@
+package gorillas
+
+import (
+	"image/color"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const testMapJSON = `{
+  "name": "Twin Towers",
+  "width": 400,
+  "height": 300,
+  "buildings": [
+    {"x": 0, "width": 100, "height": 60},
+    {"x": 100, "width": 50, "height": 150, "colour": "#804020",
+     "windows": [{"x": 10, "y": 20, "lit": true}, {"x": 30, "y": 20}]},
+    {"x": 150, "width": 100, "height": 30},
+    {"x": 250, "width": 50, "height": 150},
+    {"x": 300, "width": 100, "height": 60}
+  ],
+  "spawns": [1, 3],
+  "sun": {"x": 200, "y": 20},
+  "wind": {"min": -5, "max": 5},
+  "gravity": 12
+}`
+
+func writeTestMap(t *testing.T, body string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "city.json")
+	if err := os.WriteFile(path, []byte(body), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func newWorldGame() *Game {
+	g := NewGameWithSeed(WorldWidth, WorldHeight, DefaultBuildingCount, 1)
+	g.League = nil
+	return g
+}
+
+func TestApplyMapBuildsTheCity(t *testing.T) {
+	m, err := LoadMap(writeTestMap(t, testMapJSON))
+	if err != nil {
+		t.Fatal(err)
+	}
+	g := newWorldGame()
+	if err := g.ApplyMap(m); err != nil {
+		t.Fatal(err)
+	}
+	if len(g.Buildings) != 5 {
+		t.Fatalf("got %d buildings, want 5", len(g.Buildings))
+	}
+	// the map is twice as small as the world, so everything doubles
+	b := g.Buildings[1]
+	if b.X != 200 || b.W != 100 || b.H != 300 {
+		t.Fatalf("building scaled to %+v", b)
+	}
+	if b.Color != (color.RGBA{0x80, 0x40, 0x20, 255}) {
+		t.Fatalf("colour = %v", b.Color)
+	}
+	if len(b.Windows) != 2 || b.Windows[0] != (Window{X: 20, Y: 40, Lit: true}) {
+		t.Fatalf("windows = %+v", b.Windows)
+	}
+	if g.Gorillas[0].X != 250 || g.Gorillas[0].Y != WorldHeight-300 || g.Gorillas[1].X != 550 {
+		t.Fatalf("gorillas not on their spawns: %+v", g.Gorillas)
+	}
+	if g.Sun.X != 400 || g.Sun.Y != 40 {
+		t.Fatalf("sun at %f,%f", g.Sun.X, g.Sun.Y)
+	}
+	if g.Gravity != 12 || g.Wind < -5 || g.Wind > 5 {
+		t.Fatalf("gravity %f, wind %f", g.Gravity, g.Wind)
+	}
+
+	g.Reset()
+	if len(g.Buildings) != 5 || g.Buildings[1].H != 300 || g.Gravity != 12 {
+		t.Fatal("the next round should be played on the same map")
+	}
+	if err := g.ApplyMap(nil); err != nil || g.Map != nil || g.Gravity != g.Settings.DefaultGravity {
+		t.Fatalf("clearing the map should return to random cities: %v", err)
+	}
+}
+
+func TestMapFallsBackWithoutSpawns(t *testing.T) {
+	m, err := LoadMap(writeTestMap(t, testMapJSON))
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.Spawns = nil
+	g := newWorldGame()
+	if err := g.SetPlayers("Ann", "Bob", "Cat"); err != nil {
+		t.Fatal(err)
+	}
+	if err := g.ApplyMap(m); err != nil {
+		t.Fatal(err)
+	}
+	for i, gr := range g.Gorillas {
+		if idx := g.buildingAt(gr.X); idx <= 0 || idx >= len(g.Buildings)-1 {
+			t.Fatalf("gorilla %d placed on building %d", i, idx)
+		}
+	}
+	if err := g.SetPlayers("Ann", "Bob", "Cat", "Dan"); err != nil {
+		t.Fatal(err)
+	}
+	if err := g.ApplyMap(m); err == nil {
+		t.Fatal("expected an error when the map has no room for every gorilla")
+	}
+}
+
+func TestSaveMapRoundTrips(t *testing.T) {
+	g := newTestGame()
+	g.Buildings[0].Color = color.RGBA{1, 2, 3, 255}
+	b1 := g.Buildings[1]
+	g.recordExplosionDamage(b1.X+b1.W/2, float64(g.Height)-b1.H, 5)
+	m := g.CityMap()
+	path := filepath.Join(t.TempDir(), "saved.json")
+	if err := SaveMap(path, m); err != nil {
+		t.Fatal(err)
+	}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(b), `"colour": "#010203"`) || !strings.Contains(string(b), `"wind": `) {
+		t.Fatalf("unexpected map file:\n%s", b)
+	}
+	loaded, err := LoadMap(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(loaded, m) {
+		t.Fatalf("map changed on the way through a file:\n%+v\n%+v", loaded, m)
+	}
+
+	h := newTestGame()
+	if err := h.ApplyMap(loaded); err != nil {
+		t.Fatal(err)
+	}
+	if h.Wind != g.Wind || !reflect.DeepEqual(h.Gorillas, g.Gorillas) || !reflect.DeepEqual(h.Buildings[1].Damage, g.Buildings[1].Damage) {
+		t.Fatal("a saved city should play back the same, craters and all")
+	}
+}
+
+func TestLoadMapRejectsBadMaps(t *testing.T) {
+	for name, body := range map[string]string{
+		"too few":  `{"buildings": [{"x": 0, "width": 10, "height": 5}]}`,
+		"overlap":  `{"buildings": [{"x": 0, "width": 300, "height": 50}, {"x": 200, "width": 300, "height": 50}, {"x": 500, "width": 300, "height": 50}]}`,
+		"off edge": `{"buildings": [{"x": 0, "width": 300, "height": 50}, {"x": 300, "width": 300, "height": 50}, {"x": 600, "width": 300, "height": 50}]}`,
+		"colour":   `{"buildings": [{"x": 0, "width": 10, "height": 5, "colour": "red"}, {"x": 10, "width": 10, "height": 5}, {"x": 20, "width": 10, "height": 5}]}`,
+		"spawn":    `{"buildings": [{"x": 0, "width": 10, "height": 5}, {"x": 10, "width": 10, "height": 5}, {"x": 20, "width": 10, "height": 5}], "spawns": [0, 3]}`,
+		"crater":   `{"buildings": [{"x": 0, "width": 10, "height": 5, "craters": [{"x": 1, "y": 1}]}, {"x": 10, "width": 10, "height": 5}, {"x": 20, "width": 10, "height": 5}]}`,
+		"gravity":  `{"buildings": [{"x": 0, "width": 10, "height": 5}, {"x": 10, "width": 10, "height": 5}, {"x": 20, "width": 10, "height": 5}], "gravity": {"min": 5, "max": 1}}`,
+		"unknown":  `{"buildings": [], "towers": 3}`,
+		"not json": `buildings`,
+	} {
+		if _, err := LoadMap(writeTestMap(t, body)); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
@@ -0,0 +1,153 @@
+package gorillas
+
+import (
+	"fmt"
+	"strings"
+)
+
+// MatchMode selects how the winner of a match is decided.
+type MatchMode int
+
+const (
+	// MatchBestOf plays up to DefaultRoundQty rounds and stops early once a
+	// player has won a majority of them, like the BASIC original.
+	MatchBestOf MatchMode = iota
+	// MatchFirstTo keeps playing until a player has won DefaultRoundQty rounds.
+	MatchFirstTo
+)
+
+// String returns the name used for the mode in flags and settings files.
+func (m MatchMode) String() string {
+	switch m {
+	case MatchFirstTo:
+		return "firstto"
+	default:
+		return "bestof"
+	}
+}
+
+// ParseMatchMode converts a name such as "bestof" or "first-to" into a MatchMode.
+func ParseMatchMode(s string) (MatchMode, error) {
+	switch strings.ToLower(strings.NewReplacer("-", "", "_", "", " ", "").Replace(strings.TrimSpace(s))) {
+	case "bestof", "best":
+		return MatchBestOf, nil
+	case "firstto", "first":
+		return MatchFirstTo, nil
+	}
+	return MatchBestOf, fmt.Errorf("unknown match mode %q", s)
+}
+
+// Match counts the rounds played and won during a single match.
+type Match struct {
+	Mode   MatchMode
+	Rounds int
+	Played int
+	Wins   []int
+}
+
+// NewMatch creates a match for the given number of players. A non-positive
+// round count produces a match that never ends on its own.
+func NewMatch(mode MatchMode, rounds, players int) *Match {
+	return &Match{Mode: mode, Rounds: rounds, Wins: make([]int, players)}
+}
+
+// RecordRound notes that winner took the round just played.
+func (m *Match) RecordRound(winner int) {
+	if m == nil {
+		return
+	}
+	m.Played++
+	if winner >= 0 && winner < len(m.Wins) {
+		m.Wins[winner]++
+	}
+}
+
+// Target returns the number of round wins that settles the match.
+func (m *Match) Target() int {
+	if m == nil || m.Rounds <= 0 {
+		return 0
+	}
+	if m.Mode == MatchFirstTo {
+		return m.Rounds
+	}
+	return m.Rounds/2 + 1
+}
+
+// Over reports whether the match has been decided.
+func (m *Match) Over() bool {
+	if m == nil || m.Rounds <= 0 {
+		return false
+	}
+	if m.Mode == MatchBestOf && m.Played >= m.Rounds {
+		return true
+	}
+	target := m.Target()
+	for _, w := range m.Wins {
+		if w >= target {
+			return true
+		}
+	}
+	return false
+}
+
+// Winner returns the index of the player who won the match. It returns -1
+// while the match is still running or when it ended in a draw.
+func (m *Match) Winner() int {
+	if !m.Over() {
+		return -1
+	}
+	best := -1
+	tied := false
+	for i, w := range m.Wins {
+		switch {
+		case best < 0 || w > m.Wins[best]:
+			best = i
+			tied = false
+		case w == m.Wins[best]:
+			tied = true
+		}
+	}
+	if tied {
+		return -1
+	}
+	return best
+}
+
+// StartMatch begins a new match using the configured round count and mode,
+// starting a fresh Recording with its first throw.
+func (g *Game) StartMatch() {
+	g.Match = NewMatch(g.Settings.MatchMode, g.Settings.DefaultRoundQty, g.Sides())
+	g.Recording = nil
+}
+
+// MatchOver reports whether the current match has been decided.
+func (g *Game) MatchOver() bool {
+	return g.Match.Over()
+}
+
+// Winner returns the side that won the match, or -1 while the match is
+// running or if it was drawn. Sides are players unless teams are set.
+func (g *Game) Winner() int {
+	return g.Match.Winner()
+}
+
+// MatchSummary returns a one line description of the match result.
+func (g *Game) MatchSummary() string {
+	if !g.MatchOver() {
+		return ""
+	}
+	score := joinScores(g.Match.Wins)
+	if w := g.Winner(); w >= 0 {
+		return fmt.Sprintf("%s wins the match %s", g.SideName(w), score)
+	}
+	return fmt.Sprintf("Match drawn %s", score)
+}
+
+// joinScores formats wins as "a-b-...".
+func joinScores(wins []int) string {
+	scores := make([]string, len(wins))
+	for i, w := range wins {
+		scores[i] = fmt.Sprint(w)
+	}
+	return strings.Join(scores, "-")
+}
@@ -0,0 +1,110 @@
+package gorillas
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestMatchBestOfEndsOnMajority(t *testing.T) {
+	m := NewMatch(MatchBestOf, 5, 2)
+	m.RecordRound(0)
+	m.RecordRound(1)
+	m.RecordRound(0)
+	if m.Over() {
+		t.Fatal("match should continue at 2-1 in a best of 5")
+	}
+	m.RecordRound(0)
+	if !m.Over() {
+		t.Fatal("match should end once a player has 3 of 5")
+	}
+	if m.Winner() != 0 {
+		t.Fatalf("expected player 1 to win, got %d", m.Winner())
+	}
+}
+
+func TestMatchBestOfDrawAfterAllRounds(t *testing.T) {
+	m := NewMatch(MatchBestOf, 4, 2)
+	for _, w := range []int{0, 1, 0, 1} {
+		if m.Over() {
+			t.Fatal("match ended early")
+		}
+		m.RecordRound(w)
+	}
+	if !m.Over() {
+		t.Fatal("match should end after all rounds are played")
+	}
+	if m.Winner() != -1 {
+		t.Fatalf("expected draw, got winner %d", m.Winner())
+	}
+}
+
+func TestMatchFirstToIgnoresRoundCount(t *testing.T) {
+	m := NewMatch(MatchFirstTo, 2, 2)
+	for _, w := range []int{0, 1, 1} {
+		m.RecordRound(w)
+	}
+	if m.Winner() != 1 {
+		t.Fatalf("expected player 2 to win, got %d", m.Winner())
+	}
+	m = NewMatch(MatchFirstTo, 3, 2)
+	for _, w := range []int{0, 1, 0, 1, 1} {
+		if m.Over() {
+			t.Fatal("first to 3 should not end before a player has 3 wins")
+		}
+		m.RecordRound(w)
+	}
+	if !m.Over() || m.Winner() != 1 {
+		t.Fatalf("expected player 2 to win first to 3, wins %v", m.Wins)
+	}
+}
+
+func TestParseMatchMode(t *testing.T) {
+	for in, want := range map[string]MatchMode{"bestof": MatchBestOf, "Best-Of": MatchBestOf, "firstto": MatchFirstTo, " first_to ": MatchFirstTo} {
+		got, err := ParseMatchMode(in)
+		if err != nil || got != want {
+			t.Errorf("ParseMatchMode(%q) = %v, %v", in, got, err)
+		}
+	}
+	if _, err := ParseMatchMode("sudden death"); err == nil {
+		t.Error("expected error for unknown mode")
+	}
+}
+
+func TestGameStopsResettingWhenMatchOver(t *testing.T) {
+	g := newTestGame()
+	g.ScoreFile = filepath.Join(t.TempDir(), "scores.json")
+	g.Settings.DefaultRoundQty = 1
+	g.StartMatch()
+	g.Current = 0
+	g.startExplosion(g.Gorillas[1].X, g.Gorillas[1].Y)
+	for g.Explosion.Active {
+		g.Step(StepDuration)
+	}
+	if !g.MatchOver() {
+		t.Fatal("match should be over after the only round")
+	}
+	if g.Winner() != 0 {
+		t.Fatalf("expected player 1 to win the match, got %d", g.Winner())
+	}
+	if g.Wins[0] != 1 {
+		t.Fatalf("wins should not be reset once the match is over, got %v", g.Wins)
+	}
+	if s := g.MatchSummary(); s != "Player 1 wins the match 1-0" {
+		t.Fatalf("unexpected summary %q", s)
+	}
+	saved := newTestGame()
+	saved.ScoreFile = g.ScoreFile
+	saved.LoadScores()
+	if saved.TotalWins[0] != 1 {
+		t.Fatalf("the round should be saved to the score file, got %v", saved.TotalWins)
+	}
+}
+
+func TestResetKeepsMatchProgress(t *testing.T) {
+	g := newTestGame()
+	g.Match.RecordRound(1)
+	g.Reset()
+	if g.Match.Played != 1 || g.Match.Wins[1] != 1 {
+		t.Fatalf("match progress lost on reset: %+v", g.Match)
+	}
+}
@@ -0,0 +1,441 @@
+package gorillas
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"math/rand"
+	"net"
+	"strings"
+	"sync"
+	"time"
+)
+
+// NetProtocol is the version of the protocol two players speak over TCP.
+//
+// Both ends send one JSON object per line. The player joining opens with
+// {"type":"hello","protocol":1,"name":"Bob"}. The host answers with
+// {"type":"welcome",...} carrying the NetMatch both sides set their game up
+// from, the seat the joiner plays and the StateHash the game starts at, or
+// with {"type":"bye","reason":"..."} to turn them away. From then on each
+// side sends {"type":"throw","angle":45,"power":60,"hash":"..."} for every
+// throw of its own seat, hash being StateHash as the banana leaves, and
+// {"type":"bye"} when it quits. Both run the same simulation from the same
+// seed, so nothing else needs to cross the network.
+const NetProtocol = 1
+
+// netHandshakeTimeout is how long either side waits for the other's
+// greeting once connected.
+const netHandshakeTimeout = 10 * time.Second
+
+// netMessage is any line of the protocol; fields that do not apply to its
+// Type are left empty.
+type netMessage struct {
+	Type     string    `json:"type"`
+	Protocol int       `json:"protocol,omitempty"`
+	Name     string    `json:"name,omitempty"`
+	Match    *NetMatch `json:"match,omitempty"`
+	Seat     int       `json:"seat,omitempty"`
+	Angle    float64   `json:"angle,omitempty"`
+	Power    float64   `json:"power,omitempty"`
+	Hash     string    `json:"hash,omitempty"`
+	Reason   string    `json:"reason,omitempty"`
+}
+
+// NetMatch is what the host decides for a network game: the seed, the
+// settings that change how it plays, the city and the players, the host
+// first.
+type NetMatch struct {
+	Seed      int64    `json:"seed"`
+	Settings  Settings `json:"settings"`
+	Buildings int      `json:"buildings"`
+	Players   []string `json:"players"`
+	Map       *CityMap `json:"map,omitempty"`
+}
+
+// withLocal returns s with the settings that only change what one screen
+// shows taken from local: sound, text, intro, colour, instant replay and AI.
+func (s Settings) withLocal(local Settings) Settings {
+	s.UseSound = local.UseSound
+	s.UseSlidingText = local.UseSlidingText
+	s.ShowIntro = local.ShowIntro
+	s.ForceCGA = local.ForceCGA
+	s.InstantReplay = local.InstantReplay
+	s.AILevel = local.AILevel
+	return s
+}
+
+// apply sets g up afresh as the match. Settings that only change what this
+// side sees stay as they were.
+func (m NetMatch) apply(g *Game) error {
+	g.Settings = m.Settings.withLocal(g.Settings)
+	g.Seed = m.Seed
+	g.Rand = rand.New(rand.NewSource(m.Seed))
+	g.aiRand, g.aims = nil, nil
+	g.BuildingCount = m.Buildings
+	g.Gravity = g.Settings.DefaultGravity
+	g.Map = nil
+	if err := g.SetPlayers(m.Players...); err != nil {
+		return err
+	}
+	if m.Map != nil {
+		return g.ApplyMap(m.Map)
+	}
+	return nil
+}
+
+// hashString formats a StateHash as it is sent.
+func hashString(h uint64) string {
+	return fmt.Sprintf("%016x", h)
+}
+
+// netConn reads and writes protocol lines on a connection.
+type netConn struct {
+	conn net.Conn
+	in   *bufio.Scanner
+	mu   sync.Mutex
+}
+
+func newNetConn(conn net.Conn) *netConn {
+	in := bufio.NewScanner(conn)
+	// a map file can make a long welcome
+	in.Buffer(nil, 1<<20)
+	return &netConn{conn: conn, in: in}
+}
+
+func (c *netConn) send(m netMessage) error {
+	b, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	_, err = c.conn.Write(append(b, '\n'))
+	return err
+}
+
+func (c *netConn) read() (netMessage, error) {
+	var m netMessage
+	if !c.in.Scan() {
+		if err := c.in.Err(); err != nil {
+			return m, err
+		}
+		return m, errors.New("connection closed")
+	}
+	if err := json.Unmarshal(c.in.Bytes(), &m); err != nil {
+		return m, fmt.Errorf("bad message %q: %w", c.in.Text(), err)
+	}
+	return m, nil
+}
+
+// closeOnCancel closes conn if ctx is cancelled before the returned stop is
+// called.
+func closeOnCancel(ctx context.Context, c io.Closer) (stop func() bool) {
+	return context.AfterFunc(ctx, func() { c.Close() })
+}
+
+// HostNet waits on l for a player to join a network game and returns the
+// session once they have. The match is g's seed, settings, building count
+// and map, played between g's first player on seat 0 and the player joining
+// on seat 1, and g is set up afresh for it. Connections that do not greet
+// like a player of the same protocol are turned away and the wait goes on.
+// Cancelling ctx gives up waiting and closes l.
+func HostNet(ctx context.Context, l net.Listener, g *Game) (*NetSession, error) {
+	stop := closeOnCancel(ctx, l)
+	defer stop()
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			return nil, fmt.Errorf("net: %w", err)
+		}
+		if s, err := host(ctx, conn, g); err == nil {
+			return s, nil
+		}
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// host greets a player who has connected and sets the match up with them.
+func host(ctx context.Context, conn net.Conn, g *Game) (*NetSession, error) {
+	stop := closeOnCancel(ctx, conn)
+	defer stop()
+	c := newNetConn(conn)
+	conn.SetDeadline(time.Now().Add(netHandshakeTimeout))
+	refuse := func(err error) (*NetSession, error) {
+		c.send(netMessage{Type: "bye", Reason: err.Error()})
+		conn.Close()
+		return nil, err
+	}
+	hello, err := c.read()
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	if hello.Type != "hello" {
+		return refuse(fmt.Errorf("expected a hello, got %q", hello.Type))
+	}
+	if hello.Protocol != NetProtocol {
+		return refuse(fmt.Errorf("the host speaks protocol %d, not %d", NetProtocol, hello.Protocol))
+	}
+	name := strings.TrimSpace(hello.Name)
+	if name == "" || name == g.Players[0] {
+		name = PlayerNames(MinPlayers)[1]
+	}
+	m := NetMatch{Seed: g.Seed, Settings: g.Settings, Buildings: g.BuildingCount, Players: []string{g.Players[0], name}, Map: g.Map}
+	if err := m.apply(g); err != nil {
+		return refuse(err)
+	}
+	if err := c.send(netMessage{Type: "welcome", Protocol: NetProtocol, Match: &m, Seat: 1, Hash: hashString(g.StateHash())}); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	conn.SetDeadline(time.Time{})
+	return newNetSession(g, c, 0, name), nil
+}
+
+// JoinNet connects to the game hosted at addr and returns the session. g is
+// set up afresh as the host's match and its first player's name is the one
+// the host is told; the joiner plays seat 1. Cancelling ctx gives up.
+func JoinNet(ctx context.Context, addr string, g *Game) (*NetSession, error) {
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("net: %w", err)
+	}
+	stop := closeOnCancel(ctx, conn)
+	defer stop()
+	fail := func(err error) (*NetSession, error) {
+		conn.Close()
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return nil, fmt.Errorf("net: %w", err)
+	}
+	c := newNetConn(conn)
+	conn.SetDeadline(time.Now().Add(netHandshakeTimeout))
+	if err := c.send(netMessage{Type: "hello", Protocol: NetProtocol, Name: g.Players[0]}); err != nil {
+		return fail(err)
+	}
+	w, err := c.read()
+	if err != nil {
+		return fail(err)
+	}
+	switch {
+	case w.Type == "bye":
+		return fail(fmt.Errorf("the host turned us away: %s", w.Reason))
+	case w.Type != "welcome" || w.Match == nil:
+		return fail(fmt.Errorf("expected a welcome, got %q", w.Type))
+	case w.Protocol != NetProtocol:
+		return fail(fmt.Errorf("the host speaks protocol %d, not %d", w.Protocol, NetProtocol))
+	case len(w.Match.Players) != MinPlayers || w.Seat != 1:
+		return fail(fmt.Errorf("the host offered seat %d of %d", w.Seat, len(w.Match.Players)))
+	}
+	if err := w.Match.apply(g); err != nil {
+		c.send(netMessage{Type: "bye", Reason: err.Error()})
+		return fail(err)
+	}
+	if h := hashString(g.StateHash()); h != w.Hash {
+		c.send(netMessage{Type: "bye", Reason: "out of sync"})
+		return fail(errors.New("this build sets up a different game from the host's"))
+	}
+	conn.SetDeadline(time.Time{})
+	return newNetSession(g, c, w.Seat, w.Match.Players[0]), nil
+}
+
+// NetSession is this side of a network game in lockstep with the other: it
+// follows the game's events to send the throws of its own seat, and throws
+// the other side's as they arrive. When the other side leaves, the
+// connection drops or the games drift apart, the session ends and the
+// computer plays on for the other side.
+type NetSession struct {
+	// Seat is the gorilla played on this side and Remote the name of the
+	// player across the network.
+	Seat   int
+	Remote string
+
+	g      *Game
+	c      *netConn
+	in     chan netMessage
+	notify chan struct{}
+	// readErr says why the other side's messages stopped; it is set before
+	// in is closed.
+	readErr error
+	// expect is the hash the other side sent with the throw being made.
+	expect  string
+	err     error
+	told    bool
+	closing sync.Once
+}
+
+func newNetSession(g *Game, c *netConn, seat int, remote string) *NetSession {
+	s := &NetSession{Seat: seat, Remote: remote, g: g, c: c, in: make(chan netMessage, 16), notify: make(chan struct{}, 1)}
+	go s.read()
+	g.Subscribe(s)
+	return s
+}
+
+// read passes the other side's messages on until the connection ends.
+func (s *NetSession) read() {
+	defer close(s.notify)
+	defer close(s.in)
+	for {
+		m, err := s.c.read()
+		if err != nil {
+			s.readErr = err
+			return
+		}
+		s.in <- m
+		select {
+		case s.notify <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// Notify returns a channel that receives whenever something arrives from
+// the other side and is closed once the connection ends, so a frontend
+// blocked waiting for its own player's input can poll again.
+func (s *NetSession) Notify() <-chan struct{} {
+	return s.notify
+}
+
+// Plays reports whether the gorilla at idx is played from the other side,
+// or by the computer standing in for them.
+func (s *NetSession) Plays(idx int) bool {
+	return idx != s.Seat
+}
+
+// Poll is called while the game waits for a throw. When it is the other
+// side's turn it throws their shot if it has arrived, and reports true
+// either way so the frontend does not take input for them. The first call
+// after the session ends returns why; from then on AutoShot throws for the
+// other side.
+func (s *NetSession) Poll() (bool, error) {
+	return s.poll(false)
+}
+
+// Wait is Poll that waits for the other side's throw before returning.
+func (s *NetSession) Wait() (bool, error) {
+	return s.poll(true)
+}
+
+func (s *NetSession) poll(wait bool) (bool, error) {
+	remote := s.Plays(s.g.Current)
+	if s.err == nil {
+		var m netMessage
+		var ok bool
+		if remote && wait {
+			m, ok = <-s.in
+			s.receive(m, ok)
+		} else {
+			select {
+			case m, ok = <-s.in:
+				s.receive(m, ok)
+			default:
+			}
+		}
+	}
+	if s.err != nil && !s.told {
+		s.told = true
+		return remote, s.err
+	}
+	if remote && s.err != nil && !s.g.Banana.Active {
+		s.g.AutoShot()
+	}
+	return remote, nil
+}
+
+// receive handles a message from the other side, ok being false once they
+// are gone.
+func (s *NetSession) receive(m netMessage, ok bool) {
+	if !ok {
+		s.fail(fmt.Errorf("net: lost the connection to %s: %v", s.Remote, s.readErr))
+		return
+	}
+	switch m.Type {
+	case "bye":
+		if m.Reason != "" {
+			s.fail(fmt.Errorf("net: %s left the game: %s", s.Remote, m.Reason))
+		} else {
+			s.fail(fmt.Errorf("net: %s left the game", s.Remote))
+		}
+	case "throw":
+		switch {
+		case !s.Plays(s.g.Current):
+			s.fail(fmt.Errorf("net: %s threw out of turn", s.Remote))
+		case math.IsNaN(m.Angle) || m.Angle < 0 || m.Angle > 360 || math.IsNaN(m.Power) || m.Power < 0 || m.Power > 200:
+			s.fail(fmt.Errorf("net: %s threw %v/%v", s.Remote, m.Angle, m.Power))
+		default:
+			s.expect = m.Hash
+			s.g.Angle, s.g.Power = m.Angle, m.Power
+			s.g.Throw()
+		}
+	}
+	// later versions may add other messages; there is nothing to do for them
+}
+
+// HandleEvent sends this side's throws and checks the other side's throws
+// leave both games the same.
+func (s *NetSession) HandleEvent(e GameEvent) {
+	if e.Kind != ThrowStarted || s.err != nil {
+		return
+	}
+	h := hashString(s.g.StateHash())
+	if e.Player == s.Seat {
+		if err := s.c.send(netMessage{Type: "throw", Angle: e.Angle, Power: e.Power, Hash: h}); err != nil {
+			s.fail(fmt.Errorf("net: lost the connection to %s: %w", s.Remote, err))
+		}
+		return
+	}
+	if s.expect != "" && s.expect != h {
+		s.fail(fmt.Errorf("net: out of sync with %s", s.Remote))
+	}
+	s.expect = ""
+}
+
+// fail ends the session for err, telling the other side if it can.
+func (s *NetSession) fail(err error) {
+	if s.err != nil {
+		return
+	}
+	s.err = err
+	s.closing.Do(func() {
+		s.c.send(netMessage{Type: "bye", Reason: strings.TrimPrefix(err.Error(), "net: ")})
+		s.c.conn.Close()
+	})
+}
+
+// Err returns why the session ended early, or nil while it is going.
+func (s *NetSession) Err() error {
+	return s.err
+}
+
+// Status returns a line for the frontend to show: who is being waited on,
+// why the session ended, or nothing.
+func (s *NetSession) Status() string {
+	switch {
+	case s.err != nil:
+		return strings.TrimPrefix(s.err.Error(), "net: ") + "; the computer plays on"
+	case s.Plays(s.g.Current) && !s.g.MatchOver():
+		return "Waiting for " + s.Remote + "..."
+	}
+	return ""
+}
+
+// Close says goodbye to the other side and hangs up.
+func (s *NetSession) Close() error {
+	s.closing.Do(func() {
+		s.c.send(netMessage{Type: "bye"})
+		s.c.conn.Close()
+	})
+	return nil
+}
@@ -0,0 +1,171 @@
+package gorillas
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newNetGame returns a quiet game with nothing on disk, named for its player.
+func newNetGame(t *testing.T, name string, seed int64) *Game {
+	t.Helper()
+	g := NewGameWithSeed(WorldWidth, WorldHeight, DefaultBuildingCount, seed)
+	g.League = nil
+	g.ScoreFile, g.ShotsFile = t.TempDir()+"/scores.json", t.TempDir()+"/shots.json"
+	g.Settings.UseSound = false
+	g.Settings.DefaultRoundQty = 1
+	if err := g.SetPlayers(name, "Guest"); err != nil {
+		t.Fatal(err)
+	}
+	return g
+}
+
+// netPair hosts a network game on loopback and joins it.
+func netPair(t *testing.T) (hostGame, joinGame *Game, hostSide, joinSide *NetSession) {
+	t.Helper()
+	hostGame, joinGame = newNetGame(t, "Ann", 7), newNetGame(t, "Bob", 99)
+	hostGame.Settings.DefaultGravity = 12
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	hosted := make(chan error, 1)
+	go func() {
+		var err error
+		hostSide, err = HostNet(context.Background(), l, hostGame)
+		hosted <- err
+	}()
+	joinSide, err = JoinNet(context.Background(), l.Addr().String(), joinGame)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := <-hosted; err != nil {
+		t.Fatal(err)
+	}
+	l.Close()
+	t.Cleanup(func() {
+		hostSide.Close()
+		joinSide.Close()
+	})
+	return hostGame, joinGame, hostSide, joinSide
+}
+
+// settle plays g on until it waits for the next throw.
+func settle(g *Game) {
+	for i := 0; i < 5000 && (g.Banana.Active || g.Explosion.Active || g.Dance.Active); i++ {
+		g.step()
+	}
+}
+
+func TestNetPlayKeepsBothGamesInStep(t *testing.T) {
+	hostGame, joinGame, hostSide, joinSide := netPair(t)
+	if hostSide.Seat != 0 || joinSide.Seat != 1 || hostSide.Remote != "Bob" || joinSide.Remote != "Ann" {
+		t.Fatalf("unexpected seats %d, %d for %q and %q", hostSide.Seat, joinSide.Seat, hostSide.Remote, joinSide.Remote)
+	}
+	if joinGame.Seed != 7 || joinGame.Gravity != 12 || joinGame.Players[0] != "Ann" || joinGame.Players[1] != "Bob" {
+		t.Fatalf("the joiner did not take the host's match: seed %d, gravity %v, players %v", joinGame.Seed, joinGame.Gravity, joinGame.Players)
+	}
+	sides := []struct {
+		g *Game
+		s *NetSession
+	}{{hostGame, hostSide}, {joinGame, joinSide}}
+	for throws := 0; !hostGame.MatchOver(); throws++ {
+		if throws > 40 {
+			t.Fatal("the match never ended")
+		}
+		local, remote := sides[hostGame.Current], sides[1-hostGame.Current]
+		if played, err := local.s.Poll(); played || err != nil {
+			t.Fatalf("it is this side's turn, got %v, %v", played, err)
+		}
+		local.g.AutoShot()
+		if played, err := remote.s.Wait(); !played || err != nil || !remote.g.Banana.Active {
+			t.Fatalf("the other side did not throw the shot: %v, %v", played, err)
+		}
+		settle(hostGame)
+		settle(joinGame)
+		if hostGame.StateHash() != joinGame.StateHash() {
+			t.Fatalf("the games drifted apart after throw %d", throws+1)
+		}
+	}
+	if !joinGame.MatchOver() || joinGame.Winner() != hostGame.Winner() {
+		t.Fatal("both sides should see the same winner")
+	}
+}
+
+func TestNetPlayNoticesDrift(t *testing.T) {
+	hostGame, joinGame, _, joinSide := netPair(t)
+	joinGame.Wind += 3
+	hostGame.AutoShot()
+	played, err := joinSide.Wait()
+	if !played || err == nil || !strings.Contains(err.Error(), "out of sync") {
+		t.Fatalf("expected the joiner to notice, got %v, %v", played, err)
+	}
+	if joinSide.Err() == nil || !strings.Contains(joinSide.Status(), "computer plays on") {
+		t.Errorf("unexpected status %q", joinSide.Status())
+	}
+}
+
+func TestNetPlayCarriesOnWhenTheOtherSideLeaves(t *testing.T) {
+	for _, how := range []string{"bye", "drop"} {
+		t.Run(how, func(t *testing.T) {
+			_, joinGame, hostSide, joinSide := netPair(t)
+			if how == "bye" {
+				hostSide.Close()
+			} else {
+				hostSide.c.conn.Close()
+			}
+			played, err := joinSide.Wait()
+			if !played || err == nil {
+				t.Fatalf("expected the joiner to hear the host go, got %v, %v", played, err)
+			}
+			if how == "bye" && !strings.Contains(err.Error(), "Ann left the game") {
+				t.Errorf("unexpected error %v", err)
+			}
+			if played, err := joinSide.Wait(); !played || err != nil || !joinGame.Banana.Active {
+				t.Fatalf("expected the computer to throw for the host, got %v, %v", played, err)
+			}
+		})
+	}
+}
+
+func TestHostTurnsAwayOtherProtocols(t *testing.T) {
+	g := newNetGame(t, "Ann", 7)
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	hosted := make(chan error, 1)
+	go func() {
+		_, err := HostNet(ctx, l, g)
+		hosted <- err
+	}()
+	conn, err := net.Dial("tcp", l.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	conn.Write([]byte(`{"type":"hello","protocol":99,"name":"Eve"}` + "\n"))
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	in := bufio.NewScanner(conn)
+	if !in.Scan() {
+		t.Fatal(in.Err())
+	}
+	var bye netMessage
+	if err := json.Unmarshal(in.Bytes(), &bye); err != nil || bye.Type != "bye" || !strings.Contains(bye.Reason, "protocol") {
+		t.Fatalf("expected to be turned away, got %s", in.Text())
+	}
+	select {
+	case err := <-hosted:
+		t.Fatalf("the host stopped waiting: %v", err)
+	default:
+	}
+	cancel()
+	if err := <-hosted; err != context.Canceled {
+		t.Fatalf("expected the wait to be cancelled, got %v", err)
+	}
+}
@@ -0,0 +1,120 @@
+package gorillas
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSetPlayersValidatesCount(t *testing.T) {
+	g := newTestGame()
+	if err := g.SetPlayers("solo"); err == nil {
+		t.Fatal("expected an error for a single player")
+	}
+	if err := g.SetPlayers("a", "b", "c", "d", "e", "f", "g", "h", "i"); err == nil {
+		t.Fatal("expected an error for nine players")
+	}
+}
+
+func TestSetPlayersSpreadsGorillas(t *testing.T) {
+	g := newTestGame()
+	if err := g.SetPlayers("Ann", "Bob", "Cat", "Dan"); err != nil {
+		t.Fatal(err)
+	}
+	if len(g.Gorillas) != 4 || len(g.Wins) != 4 || len(g.Angles) != 4 || g.Match == nil || len(g.Match.Wins) != 4 {
+		t.Fatalf("per-player state not sized for four players")
+	}
+	for i := 1; i < len(g.Gorillas); i++ {
+		if g.Gorillas[i].X <= g.Gorillas[i-1].X {
+			t.Fatalf("gorillas should stand left to right: %v", g.Gorillas)
+		}
+	}
+	for i, gr := range g.Gorillas {
+		if idx := g.buildingAt(gr.X); idx <= 0 || idx >= len(g.Buildings)-1 {
+			t.Fatalf("gorilla %d should stand on an inner building, got %d", i, idx)
+		}
+	}
+	if g.facing(0) != 1 || g.facing(3) != -1 {
+		t.Fatal("outer gorillas should face into the city")
+	}
+}
+
+func TestSetPlayersGrowsSmallCity(t *testing.T) {
+	g := NewGame(800, 600, 4)
+	if err := g.SetPlayers("a", "b", "c", "d", "e", "f"); err != nil {
+		t.Fatal(err)
+	}
+	if g.BuildingCount < 8 || len(g.Buildings) != g.BuildingCount {
+		t.Fatalf("expected room for six gorillas, got %d buildings", g.BuildingCount)
+	}
+}
+
+func TestFreeForAllElimination(t *testing.T) {
+	g := newTestGame()
+	if err := g.SetPlayers("Ann", "Bob", "Cat"); err != nil {
+		t.Fatal(err)
+	}
+	g.League = nil
+	g.ScoreFile = t.TempDir() + "/scores.json"
+
+	g.Current = 0
+	g.handleGorillaKill(1)
+	if !g.Gorillas[1].Dead || g.roundOver {
+		t.Fatal("round should continue after the first elimination")
+	}
+	g.nextTurn()
+	if g.Current != 2 {
+		t.Fatalf("turn should skip the eliminated gorilla, got %d", g.Current)
+	}
+
+	g.handleGorillaKill(0)
+	if !g.roundOver {
+		t.Fatal("round should end with one gorilla left")
+	}
+	if !reflect.DeepEqual(g.Wins, []int{0, 0, 1}) {
+		t.Fatalf("last gorilla standing should score, wins: %v", g.Wins)
+	}
+}
+
+func TestSelfKillInFreeForAllPassesTurn(t *testing.T) {
+	g := newTestGame()
+	if err := g.SetPlayers("Ann", "Bob", "Cat"); err != nil {
+		t.Fatal(err)
+	}
+	g.League = nil
+	g.Current = 1
+	g.handleGorillaKill(1)
+	if g.roundOver || g.LastEvent != EventSelf {
+		t.Fatal("self kill should eliminate only the thrower")
+	}
+	g.nextTurn()
+	if g.Current != 2 {
+		t.Fatalf("expected player 3 to throw next, got %d", g.Current)
+	}
+}
+
+func TestLeagueRecordsMultiPlayerRounds(t *testing.T) {
+	l := &League{Players: map[string]*PlayerStats{}}
+	l.RecordMatchRound([]string{"Ann", "Bob", "Cat"}, 2, 3)
+	for _, n := range []string{"Ann", "Bob", "Cat"} {
+		if l.Players[n].Rounds != 1 {
+			t.Fatalf("%s should have played one round", n)
+		}
+	}
+	if l.Players["Cat"].Wins != 1 || l.Players["Cat"].Accuracy != 3 || l.Players["Ann"].Wins != 0 {
+		t.Fatalf("unexpected league stats: %+v", l.Players["Cat"])
+	}
+}
+
+func TestStatsStringListsEveryPlayer(t *testing.T) {
+	g := newTestGame()
+	g.League = nil
+	if err := g.SetPlayers("a", "b", "c"); err != nil {
+		t.Fatal(err)
+	}
+	g.Wins = []int{1, 0, 2}
+	g.TotalWins = []int{1, 0, 2}
+	want := "Session - P1:1 P2:0 P3:2\nOverall - P1:1 P2:0 P3:2"
+	if s := g.StatsString(); s != want {
+		t.Fatalf("got %q", s)
+	}
+}
@@ -0,0 +1,343 @@
+package gorillas
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"io/fs"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// DefaultReplayDir is where the frontends keep recorded matches.
+const DefaultReplayDir = "replays"
+
+// replayFileVersion is written to every replay so older files can still be
+// recognised if the format changes.
+const replayFileVersion = 1
+
+// ReplayPause is how long a Replay waits after a banana lands before it
+// throws the next one.
+const ReplayPause = 750 * time.Millisecond
+
+// ReplayFile is a recorded match: who played, under which settings, and the
+// city and throws of every round.
+type ReplayFile struct {
+	Version  int           `json:"version"`
+	Date     time.Time     `json:"date"`
+	Summary  string        `json:"summary,omitempty"`
+	Width    int           `json:"width"`
+	Height   int           `json:"height"`
+	Seed     int64         `json:"seed"`
+	Players  []string      `json:"players"`
+	Teams    []int         `json:"teams,omitempty"`
+	Settings Settings      `json:"settings"`
+	Rounds   []ReplayRound `json:"rounds"`
+	// GorillaMask is the shape the gorillas were hit in, a row of text per
+	// row of pixels with '#' for each solid one. Replays recorded without
+	// one leave the frontend to choose.
+	GorillaMask []string `json:"gorillaMask,omitempty"`
+}
+
+// ReplayRound is one round of a ReplayFile. City is the arena as it stood
+// before the first throw.
+type ReplayRound struct {
+	Round  int          `json:"round"`
+	City   *CityMap     `json:"city"`
+	Throws []ShotRecord `json:"throws"`
+}
+
+// LoadReplay reads a recorded match from path.
+func LoadReplay(path string) (*ReplayFile, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("load replay: %w", err)
+	}
+	var f ReplayFile
+	if err := json.Unmarshal(b, &f); err != nil {
+		return nil, fmt.Errorf("load replay %s: %w", path, err)
+	}
+	return &f, nil
+}
+
+// SaveReplay writes a recorded match to path as JSON.
+func SaveReplay(path string, f *ReplayFile) error {
+	b, err := json.Marshal(f)
+	if err != nil {
+		return fmt.Errorf("save replay: %w", err)
+	}
+	if err := os.WriteFile(path, b, 0644); err != nil {
+		return fmt.Errorf("save replay: %w", err)
+	}
+	return nil
+}
+
+// saveNewReplay writes f to dir as name.json, or as name_2.json, name_3.json
+// and so on when that is taken, and never over an existing file.
+func saveNewReplay(dir, name string, f *ReplayFile) error {
+	b, err := json.Marshal(f)
+	if err != nil {
+		return fmt.Errorf("save replay: %w", err)
+	}
+	for n := 1; ; n++ {
+		path := filepath.Join(dir, name+".json")
+		if n > 1 {
+			path = filepath.Join(dir, fmt.Sprintf("%s_%d.json", name, n))
+		}
+		file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+		if errors.Is(err, fs.ErrExist) {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("save replay: %w", err)
+		}
+		_, err = file.Write(b)
+		if cerr := file.Close(); err == nil {
+			err = cerr
+		}
+		if err != nil {
+			return fmt.Errorf("save replay: %w", err)
+		}
+		return nil
+	}
+}
+
+// ListReplays returns the replay files in dir, newest first. A missing
+// directory simply holds no replays.
+func ListReplays(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("list replays: %w", err)
+	}
+	var paths []string
+	for _, e := range entries {
+		if !e.IsDir() && strings.HasSuffix(e.Name(), ".json") {
+			paths = append(paths, filepath.Join(dir, e.Name()))
+		}
+	}
+	sort.Sort(sort.Reverse(sort.StringSlice(paths)))
+	return paths, nil
+}
+
+// recordThrow notes the throw about to be made in ShotHistory and the
+// match's Recording, opening a new round with a snapshot of the city on the
+// first throw of each round.
+func (g *Game) recordThrow() {
+	shot := ShotRecord{
+		Angle:   g.Angle,
+		Power:   g.Power,
+		Player:  g.Current,
+		Wind:    g.Wind,
+		Gravity: g.Gravity,
+		Seed:    g.Seed,
+		Skyline: g.Settings.Skyline,
+	}
+	if g.Match != nil {
+		shot.Round = g.Match.Played
+	}
+	g.ShotHistory = append(g.ShotHistory, shot)
+	if g.Recording == nil {
+		g.Recording = &ReplayFile{
+			Version:     replayFileVersion,
+			Width:       g.Width,
+			Height:      g.Height,
+			Seed:        g.Seed,
+			Players:     append([]string(nil), g.Players...),
+			Teams:       append([]int(nil), g.Teams...),
+			Settings:    g.Settings,
+			GorillaMask: maskRows(g.GorillaMask),
+		}
+	}
+	rec := g.Recording
+	if n := len(rec.Rounds); n == 0 || rec.Rounds[n-1].Round != shot.Round {
+		rec.Rounds = append(rec.Rounds, ReplayRound{Round: shot.Round, City: g.CityMap()})
+	}
+	last := &rec.Rounds[len(rec.Rounds)-1]
+	last.Throws = append(last.Throws, shot)
+}
+
+// saveRecording writes the finished match to ReplayDir, named after the
+// time it ended. Matches that end in the same second are numbered after the
+// first rather than written over it.
+func (g *Game) saveRecording() {
+	if g.ReplayDir == "" || g.Recording == nil {
+		return
+	}
+	rec := g.Recording
+	rec.Date = time.Now()
+	rec.Summary = g.MatchSummary()
+	if err := os.MkdirAll(g.ReplayDir, 0755); err != nil {
+		fmt.Fprintf(os.Stderr, "save replay: %v\n", err)
+		return
+	}
+	if err := saveNewReplay(g.ReplayDir, rec.Date.Format("20060102-150405"), rec); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+	}
+}
+
+// Replay plays a recorded match back by throwing each recorded banana in
+// its own Game. Frontends draw Game as they would a live one; nothing it
+// does touches scores or the league.
+type Replay struct {
+	Game *Game
+	File *ReplayFile
+
+	round int
+	next  int
+	wait  time.Duration
+}
+
+// NewReplay prepares f for playback, standing in the city of its first
+// round with the gorillas hit in the shape they were recorded with.
+func NewReplay(f *ReplayFile) (*Replay, error) {
+	if len(f.Players) < MinPlayers || len(f.Players) > MaxPlayers {
+		return nil, fmt.Errorf("replay has %d players", len(f.Players))
+	}
+	if len(f.Rounds) == 0 {
+		return nil, fmt.Errorf("replay has no throws")
+	}
+	for i, r := range f.Rounds {
+		if r.City == nil {
+			return nil, fmt.Errorf("replay round %d has no city", i+1)
+		}
+		if err := r.City.Validate(); err != nil {
+			return nil, fmt.Errorf("replay round %d: %w", i+1, err)
+		}
+	}
+	settings := f.Settings
+	// every throw carries the wind it was thrown in
+	settings.WindFluctuations = false
+	settings.VariableWind = false
+	g := newGame(f.Width, f.Height, DefaultBuildingCount, len(f.Players), settings, nil, rand.New(rand.NewSource(f.Seed)))
+	g.Seed = f.Seed
+	g.League = nil
+	g.Players = append([]string(nil), f.Players...)
+	if _, err := numberTeams(f.Teams, len(f.Players)); err != nil {
+		return nil, fmt.Errorf("replay: %w", err)
+	}
+	g.Teams = append([]int(nil), f.Teams...)
+	g.Wins = make([]int, g.Sides())
+	g.TotalWins = make([]int, g.Sides())
+	if f.GorillaMask != nil {
+		g.GorillaMask = maskImage(f.GorillaMask)
+	}
+	g.StartMatch()
+	r := &Replay{Game: g, File: f}
+	r.startRound(0)
+	return r, nil
+}
+
+// startRound rebuilds the city of round i.
+func (r *Replay) startRound(i int) {
+	r.round, r.next = i, 0
+	r.Game.Map = r.File.Rounds[i].City
+	r.Game.Reset()
+}
+
+// Step advances the replay by dt of real time, throwing the next recorded
+// banana once the last has landed and ReplayPause has passed.
+func (r *Replay) Step(dt time.Duration) {
+	g := r.Game
+	if g.Banana.Active || g.Explosion.Active || g.Dance.Active {
+		g.Step(dt)
+		return
+	}
+	if r.wait > 0 {
+		r.wait -= dt
+		return
+	}
+	shot, ok := r.nextShot()
+	if !ok {
+		return
+	}
+	g.setCurrent(shot.Player)
+	g.Angle, g.Power = shot.Angle, shot.Power
+	g.Wind, g.Gravity = shot.Wind, shot.Gravity
+	g.Throw()
+	r.wait = ReplayPause
+}
+
+// nextShot returns the next recorded throw, moving on to the next round's
+// city when the current round has run out.
+func (r *Replay) nextShot() (ShotRecord, bool) {
+	rounds := r.File.Rounds
+	for r.next >= len(rounds[r.round].Throws) {
+		if r.round+1 >= len(rounds) {
+			return ShotRecord{}, false
+		}
+		r.startRound(r.round + 1)
+	}
+	shot := rounds[r.round].Throws[r.next]
+	r.next++
+	return shot, true
+}
+
+// Done reports whether every recorded throw has been played and has landed.
+func (r *Replay) Done() bool {
+	g := r.Game
+	if g.Banana.Active || g.Explosion.Active || g.Dance.Active || r.wait > 0 {
+		return false
+	}
+	return r.round == len(r.File.Rounds)-1 && r.next >= len(r.File.Rounds[r.round].Throws)
+}
+
+// Thrown counts the bananas thrown so far across every round.
+func (r *Replay) Thrown() int {
+	n := r.next
+	for _, round := range r.File.Rounds[:r.round] {
+		n += len(round.Throws)
+	}
+	return n
+}
+
+// Status describes how far through the replay playback is, such as
+// "Round 2 of 3, throw 4 of 7".
+func (r *Replay) Status() string {
+	return fmt.Sprintf("Round %d of %d, throw %d of %d", r.round+1, len(r.File.Rounds), r.next, len(r.File.Rounds[r.round].Throws))
+}
+
+// maskRows writes the opaque pixels of img as a ReplayFile's GorillaMask.
+func maskRows(img image.Image) []string {
+	if img == nil {
+		return nil
+	}
+	b := img.Bounds()
+	rows := make([]string, b.Dy())
+	for y := range rows {
+		row := make([]byte, b.Dx())
+		for x := range row {
+			row[x] = '.'
+			if _, _, _, a := img.At(b.Min.X+x, b.Min.Y+y).RGBA(); a != 0 {
+				row[x] = '#'
+			}
+		}
+		rows[y] = string(row)
+	}
+	return rows
+}
+
+// maskImage turns a ReplayFile's GorillaMask back into an image.
+func maskImage(rows []string) image.Image {
+	w := 0
+	for _, row := range rows {
+		w = max(w, len(row))
+	}
+	img := image.NewAlpha(image.Rect(0, 0, w, len(rows)))
+	for y, row := range rows {
+		for x := 0; x < len(row); x++ {
+			if row[x] == '#' {
+				img.SetAlpha(x, y, color.Alpha{A: 255})
+			}
+		}
+	}
+	return img
+}
@@ -0,0 +1,174 @@
+package gorillas
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// playRecordedMatch plays a two round match on a flat map without wind in
+// which every gorilla first throws a weak banana into the next building and
+// then one straight up that falls back on itself.
+func playRecordedMatch(t *testing.T) *Game {
+	t.Helper()
+	g := NewGameWithSeed(WorldWidth, WorldHeight, DefaultBuildingCount, 21)
+	g.League = nil
+	g.ScoreFile = filepath.Join(t.TempDir(), "scores.json")
+	g.ReplayDir = filepath.Join(t.TempDir(), "replays")
+	g.Settings.UseSound = false
+	g.Settings.DefaultRoundQty = 2
+	city := &CityMap{Wind: Fixed(0)}
+	for i := 0; i < 8; i++ {
+		city.Buildings = append(city.Buildings, MapBuilding{X: float64(i) * 100, Width: 100, Height: 150 + float64(i%2)*50})
+	}
+	if err := g.ApplyMap(city); err != nil {
+		t.Fatal(err)
+	}
+	g.StartMatch()
+	for throws := 0; !g.MatchOver(); throws++ {
+		if throws > 20 {
+			t.Fatal("match did not finish")
+		}
+		g.Angle, g.Power = 60, 30
+		if throws%3 == 2 {
+			g.Angle, g.Power = 90, 20
+		}
+		g.Throw()
+		for i := 0; i < 2000 && (g.Banana.Active || g.Explosion.Active); i++ {
+			g.step()
+		}
+	}
+	return g
+}
+
+func TestThrowsAreRecorded(t *testing.T) {
+	g := playRecordedMatch(t)
+	rec := g.Recording
+	if rec == nil || len(rec.Rounds) != 2 {
+		t.Fatalf("expected two recorded rounds, got %+v", rec)
+	}
+	n := 0
+	for _, r := range rec.Rounds {
+		n += len(r.Throws)
+	}
+	if n != len(g.ShotHistory) || n < 4 {
+		t.Fatalf("recorded %d throws but the history holds %d", n, len(g.ShotHistory))
+	}
+	first := rec.Rounds[0].Throws[0]
+	if first.Angle != 60 || first.Power != 30 || first.Seed != 21 || first.Skyline != g.Settings.Skyline || first.Gravity != g.Gravity {
+		t.Fatalf("unexpected first throw %+v", first)
+	}
+	if rec.Rounds[1].Round != 1 || rec.Rounds[1].City == nil {
+		t.Fatal("each round should keep its own city")
+	}
+
+	g.StartMatch()
+	if g.Recording != nil {
+		t.Fatal("a new match should start a new recording")
+	}
+}
+
+func TestReplayReproducesTheMatch(t *testing.T) {
+	g := playRecordedMatch(t)
+	paths, err := ListReplays(g.ReplayDir)
+	if err != nil || len(paths) != 1 {
+		t.Fatalf("expected one saved replay, got %v, %v", paths, err)
+	}
+	f, err := LoadReplay(paths[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f.Summary != g.MatchSummary() || !reflect.DeepEqual(f.Players, g.Players) {
+		t.Fatalf("unexpected replay header %+v", f)
+	}
+
+	r, err := NewReplay(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; !r.Done(); i++ {
+		if i > 100000 {
+			t.Fatalf("replay did not finish: %s", r.Status())
+		}
+		r.Step(StepDuration)
+	}
+	if !reflect.DeepEqual(r.Game.Wins, g.Wins) || !r.Game.MatchOver() {
+		t.Fatalf("replay wins %v, match wins %v", r.Game.Wins, g.Wins)
+	}
+	if !reflect.DeepEqual(r.Game.Buildings, g.Buildings) || !reflect.DeepEqual(r.Game.Gorillas, g.Gorillas) {
+		t.Fatal("replay should leave the final city as the match did")
+	}
+	if got := r.Status(); got != fmt.Sprintf("Round 2 of 2, throw %[1]d of %[1]d", len(f.Rounds[1].Throws)) {
+		t.Fatalf("status = %q", got)
+	}
+}
+
+func TestReplaysHitTheRecordedGorillas(t *testing.T) {
+	g := playRecordedMatch(t)
+	if g.Recording.GorillaMask != nil {
+		t.Fatal("a game without a gorilla mask should record none")
+	}
+	// a wide, flat gorilla no port draws
+	wide := image.NewAlpha(image.Rect(0, 0, 41, 3))
+	for x := 0; x < 41; x++ {
+		for y := 0; y < 3; y++ {
+			wide.SetAlpha(x, y, color.Alpha{A: 255})
+		}
+	}
+	g.SetGorillaMask(wide)
+	g.StartMatch()
+	g.Throw()
+	dir := t.TempDir()
+	if err := saveNewReplay(dir, "masked", g.Recording); err != nil {
+		t.Fatal(err)
+	}
+	f, err := LoadReplay(filepath.Join(dir, "masked.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err := NewReplay(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(maskRows(r.Game.GorillaMask), maskRows(wide)) {
+		t.Fatalf("replay mask %q", maskRows(r.Game.GorillaMask))
+	}
+	gr := r.Game.Gorillas[0]
+	if got := r.Game.HitMap.GorillaValue(int(gr.X)+19, int(gr.Y)-1); got != 0 {
+		t.Fatalf("the edge of the recorded gorilla holds %d", got)
+	}
+}
+
+func TestReplaysEndingTogetherAreAllKept(t *testing.T) {
+	rec := playRecordedMatch(t).Recording
+	dir := t.TempDir()
+	for i := 0; i < 3; i++ {
+		if err := saveNewReplay(dir, "20240101-120000", rec); err != nil {
+			t.Fatal(err)
+		}
+	}
+	paths, err := ListReplays(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, p := range paths {
+		names = append(names, filepath.Base(p))
+	}
+	if want := []string{"20240101-120000_3.json", "20240101-120000_2.json", "20240101-120000.json"}; !reflect.DeepEqual(names, want) {
+		t.Fatalf("expected %v newest first, got %v", want, names)
+	}
+}
+
+func TestListReplaysWithoutDirectory(t *testing.T) {
+	paths, err := ListReplays(filepath.Join(t.TempDir(), "missing"))
+	if err != nil || paths != nil {
+		t.Fatalf("ListReplays = %v, %v", paths, err)
+	}
+	if _, err := NewReplay(&ReplayFile{Players: []string{"Ann", "Bob"}}); err == nil {
+		t.Fatal("expected an error for a replay without throws")
+	}
+}
@@ -0,0 +1,584 @@
+package gorillas
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// GameServer plays games for clients over HTTP, so they need not link the
+// game in. Each game is thrown from requests rather than a frame loop: a
+// throw is played out until the game waits for the next one before the
+// request returns.
+//
+//	POST   /games              starts a game from a NewGameRequest
+//	GET    /games              lists the games as ServedGames
+//	GET    /games/{id}         the whole Game, as F5 dumps it
+//	DELETE /games/{id}         ends a game
+//	POST   /games/{id}/throws  throws for the current player, see ThrowRequest
+//	GET    /games/{id}/events  follows the game as server-sent events
+//	GET    /league             the league table, when the server keeps one
+//
+// The event stream sends an event named after the EventKind, with the
+// GameEvent as its data, for everything a throw ends in, then a "turn"
+// event with the ServedGame once the game waits again. Games touch no files
+// and share one lock, so throws are played one at a time. Games nobody has
+// asked after for IdleTimeout are ended, and no more than MaxGames are kept.
+type GameServer struct {
+	// League, when set, records every round played on the server. Set it
+	// before serving.
+	League *League
+	// MaxGames caps how many games are kept at once; POST /games is
+	// refused while there are that many. Zero keeps any number.
+	MaxGames int
+	// IdleTimeout ends a game once no request has named it for that long.
+	// Zero keeps games until they are deleted.
+	IdleTimeout time.Duration
+
+	mux *http.ServeMux
+
+	mu     sync.Mutex
+	games  map[string]*servedGame
+	nextID int
+}
+
+// serverBacklog is how many events a subscriber may fall behind before it
+// is let go rather than hold the game up.
+const serverBacklog = 256
+
+// maxRequestBody is the most a request body may hold, enough for any map.
+const maxRequestBody = 1 << 20
+
+// DefaultMaxGames and DefaultIdleTimeout are the limits NewGameServer
+// starts with.
+const (
+	DefaultMaxGames    = 1000
+	DefaultIdleTimeout = time.Hour
+)
+
+// servedGame is a game with the clients following it.
+type servedGame struct {
+	id          string
+	g           *Game
+	subscribers map[chan serverEvent]struct{}
+	// used is when a request last named the game.
+	used time.Time
+	// thrown collects what the throw being played out ends in.
+	thrown []ThrowEvent
+}
+
+// serverEvent is one server-sent event.
+type serverEvent struct {
+	name string
+	data []byte
+}
+
+// NewGameRequest is the body of POST /games. Anything left out takes its
+// default.
+type NewGameRequest struct {
+	Players   []string        `json:"players,omitempty"`
+	Seed      int64           `json:"seed,omitempty"`
+	Buildings int             `json:"buildings,omitempty"`
+	Settings  *ServedSettings `json:"settings,omitempty"`
+	Map       *CityMap        `json:"map,omitempty"`
+}
+
+// ServedSettings are the settings a client may choose for a served game,
+// named as in Settings. Anything left out keeps its DefaultSettings value;
+// MatchMode, FriendlyFire and AILevel are given by name, as in flags.
+type ServedSettings struct {
+	UseOldExplosions    *bool    `json:",omitempty"`
+	UseVectorExplosions *bool    `json:",omitempty"`
+	NewExplosionRadius  *float64 `json:",omitempty"`
+	DefaultGravity      *float64 `json:",omitempty"`
+	DefaultRoundQty     *int     `json:",omitempty"`
+	WinnerFirst         *bool    `json:",omitempty"`
+	VariableWind        *bool    `json:",omitempty"`
+	WindFluctuations    *bool    `json:",omitempty"`
+	MatchMode           string   `json:",omitempty"`
+	Skyline             string   `json:",omitempty"`
+	FriendlyFire        string   `json:",omitempty"`
+	AILevel             string   `json:",omitempty"`
+}
+
+// The limits a NewGameRequest is held to, so that every throw is played out
+// in good time and no one game can starve the others.
+const (
+	maxServedBuildings = 80
+	maxServedRounds    = 99
+	minServedGravity   = 1
+	maxServedGravity   = 100
+	maxServedRadius    = 200
+)
+
+// apply lays the chosen settings over s, reporting the first one out of
+// range.
+func (c *ServedSettings) apply(s *Settings) error {
+	if c == nil {
+		return nil
+	}
+	for _, b := range []struct {
+		from *bool
+		to   *bool
+	}{
+		{c.UseOldExplosions, &s.UseOldExplosions},
+		{c.UseVectorExplosions, &s.UseVectorExplosions},
+		{c.WinnerFirst, &s.WinnerFirst},
+		{c.VariableWind, &s.VariableWind},
+		{c.WindFluctuations, &s.WindFluctuations},
+	} {
+		if b.from != nil {
+			*b.to = *b.from
+		}
+	}
+	if r := c.NewExplosionRadius; r != nil {
+		if !(*r > 0 && *r <= maxServedRadius) {
+			return fmt.Errorf("NewExplosionRadius must be over 0 and at most %d", maxServedRadius)
+		}
+		s.NewExplosionRadius = *r
+	}
+	if g := c.DefaultGravity; g != nil {
+		if !(*g >= minServedGravity && *g <= maxServedGravity) {
+			return fmt.Errorf("DefaultGravity must be between %d and %d", minServedGravity, maxServedGravity)
+		}
+		s.DefaultGravity = *g
+	}
+	if n := c.DefaultRoundQty; n != nil {
+		if *n < 1 || *n > maxServedRounds {
+			return fmt.Errorf("DefaultRoundQty must be between 1 and %d", maxServedRounds)
+		}
+		s.DefaultRoundQty = *n
+	}
+	var err error
+	if c.MatchMode != "" {
+		if s.MatchMode, err = ParseMatchMode(c.MatchMode); err != nil {
+			return err
+		}
+	}
+	if c.Skyline != "" {
+		if _, err := SkylineByName(c.Skyline); err != nil {
+			return err
+		}
+		s.Skyline = c.Skyline
+	}
+	if c.FriendlyFire != "" {
+		if s.FriendlyFire, err = ParseFriendlyFire(c.FriendlyFire); err != nil {
+			return err
+		}
+	}
+	if c.AILevel != "" {
+		if s.AILevel, err = ParseAILevel(c.AILevel); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ThrowRequest is the body of POST /games/{id}/throws. Player, when given,
+// must be the current player, so two clients cannot both take a turn.
+// Computer lets the game's AI take the turn instead.
+type ThrowRequest struct {
+	Player   *int    `json:"player,omitempty"`
+	Angle    float64 `json:"angle"`
+	Power    float64 `json:"power"`
+	Computer bool    `json:"computer,omitempty"`
+}
+
+// ThrowEvent is a GameEvent as the server reports it, with its kind named.
+type ThrowEvent struct {
+	Kind  string    `json:"kind"`
+	Event GameEvent `json:"event"`
+}
+
+// ThrowResult answers a throw with what it ended in and how the game now
+// stands.
+type ThrowResult struct {
+	Events []ThrowEvent `json:"events"`
+	Game   ServedGame   `json:"game"`
+}
+
+// ServedGame sums a game up as it waits for a throw. Winner is -1 until the
+// match is won, and stays -1 for a drawn one.
+type ServedGame struct {
+	ID      string   `json:"id"`
+	Players []string `json:"players"`
+	Current int      `json:"current"`
+	Wind    float64  `json:"wind"`
+	Round   int      `json:"round"`
+	Wins    []int    `json:"wins"`
+	Over    bool     `json:"over"`
+	Winner  int      `json:"winner"`
+	Summary string   `json:"summary,omitempty"`
+}
+
+// NewGameServer returns a server with no games and the default limits.
+func NewGameServer() *GameServer {
+	s := &GameServer{
+		MaxGames:    DefaultMaxGames,
+		IdleTimeout: DefaultIdleTimeout,
+		games:       map[string]*servedGame{},
+		mux:         http.NewServeMux(),
+	}
+	s.mux.HandleFunc("POST /games", s.create)
+	s.mux.HandleFunc("GET /games", s.list)
+	s.mux.HandleFunc("GET /games/{id}", s.state)
+	s.mux.HandleFunc("DELETE /games/{id}", s.remove)
+	s.mux.HandleFunc("POST /games/{id}/throws", s.throw)
+	s.mux.HandleFunc("GET /games/{id}/events", s.events)
+	s.mux.HandleFunc("GET /league", s.league)
+	return s
+}
+
+// ServeHTTP answers the requests listed on GameServer.
+func (s *GameServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	s.expire(time.Now())
+	s.mu.Unlock()
+	s.mux.ServeHTTP(w, r)
+}
+
+// expire ends the games left idle for longer than IdleTimeout. The caller
+// must hold s.mu.
+func (s *GameServer) expire(now time.Time) {
+	if s.IdleTimeout <= 0 {
+		return
+	}
+	for id, sg := range s.games {
+		if now.Sub(sg.used) > s.IdleTimeout {
+			sg.hangUp()
+			delete(s.games, id)
+		}
+	}
+}
+
+// Close ends every game, hanging up on their subscribers.
+func (s *GameServer) Close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for id, sg := range s.games {
+		sg.hangUp()
+		delete(s.games, id)
+	}
+}
+
+// writeJSON answers with v as JSON.
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(append(b, '\n'))
+}
+
+// writeError answers with {"error":"..."}.
+func writeError(w http.ResponseWriter, status int, err error) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	b, _ := json.Marshal(struct {
+		Error string `json:"error"`
+	}{err.Error()})
+	w.Write(append(b, '\n'))
+}
+
+// readJSON decodes the body of r into v, answering the error itself when
+// it cannot.
+func readJSON(w http.ResponseWriter, r *http.Request, v any) bool {
+	err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxRequestBody)).Decode(v)
+	var tooLarge *http.MaxBytesError
+	switch {
+	case errors.As(err, &tooLarge):
+		writeError(w, http.StatusRequestEntityTooLarge, fmt.Errorf("the body is over %d bytes", tooLarge.Limit))
+		return false
+	case err != nil:
+		writeError(w, http.StatusBadRequest, fmt.Errorf("bad request: %w", err))
+		return false
+	}
+	return true
+}
+
+// newServedGame sets a quiet game up from req.
+func (s *GameServer) newServedGame(req NewGameRequest) (*Game, error) {
+	settings := DefaultSettings()
+	if err := req.Settings.apply(&settings); err != nil {
+		return nil, fmt.Errorf("bad settings: %w", err)
+	}
+	if req.Buildings < 0 || req.Buildings > maxServedBuildings {
+		return nil, fmt.Errorf("buildings must be between 0 and %d", maxServedBuildings)
+	}
+	if m := req.Map; m != nil {
+		if len(m.Buildings) > maxServedBuildings {
+			return nil, fmt.Errorf("the map has over %d buildings", maxServedBuildings)
+		}
+		if m.Gravity != nil && (m.Gravity.Min < minServedGravity || m.Gravity.Max > maxServedGravity) {
+			return nil, fmt.Errorf("the map's gravity must be between %d and %d", minServedGravity, maxServedGravity)
+		}
+	}
+	settings.ShowIntro = false
+	settings.InstantReplay = false
+	seed := req.Seed
+	if seed == 0 {
+		seed = rand.Int63()
+	}
+	players := req.Players
+	if len(players) == 0 {
+		players = PlayerNames(MinPlayers)
+	}
+	g := NewHeadlessGame(WorldWidth, WorldHeight, req.Buildings, seed, settings)
+	g.League = s.League
+	if err := g.SetPlayers(players...); err != nil {
+		return nil, err
+	}
+	if req.Map != nil {
+		if err := g.ApplyMap(req.Map); err != nil {
+			return nil, err
+		}
+	}
+	return g, nil
+}
+
+func (s *GameServer) create(w http.ResponseWriter, r *http.Request) {
+	var req NewGameRequest
+	if r.ContentLength != 0 && !readJSON(w, r, &req) {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.MaxGames > 0 && len(s.games) >= s.MaxGames {
+		writeError(w, http.StatusServiceUnavailable, errors.New("the server is playing as many games as it may"))
+		return
+	}
+	g, err := s.newServedGame(req)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	s.nextID++
+	sg := &servedGame{id: strconv.Itoa(s.nextID), g: g, subscribers: map[chan serverEvent]struct{}{}, used: time.Now()}
+	g.Subscribe(sg)
+	s.games[sg.id] = sg
+	w.Header().Set("Location", "/games/"+sg.id)
+	writeJSON(w, http.StatusCreated, sg.summary())
+}
+
+func (s *GameServer) list(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	list := []ServedGame{}
+	for _, sg := range s.games {
+		list = append(list, sg.summary())
+	}
+	// ids count up, so the oldest game comes first
+	sort.Slice(list, func(i, j int) bool {
+		a, _ := strconv.Atoi(list[i].ID)
+		b, _ := strconv.Atoi(list[j].ID)
+		return a < b
+	})
+	writeJSON(w, http.StatusOK, list)
+}
+
+// lookup returns the game named in r, answering 404 itself when there is
+// none, and keeps it from expiring. The caller must hold s.mu.
+func (s *GameServer) lookup(w http.ResponseWriter, r *http.Request) *servedGame {
+	sg := s.games[r.PathValue("id")]
+	if sg == nil {
+		writeError(w, http.StatusNotFound, fmt.Errorf("no game %q", r.PathValue("id")))
+		return nil
+	}
+	sg.used = time.Now()
+	return sg
+}
+
+func (s *GameServer) state(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	sg := s.lookup(w, r)
+	if sg == nil {
+		return
+	}
+	c := sg.g.Clone()
+	c.ScoreFile, c.ShotsFile = "", ""
+	b, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(b)
+}
+
+func (s *GameServer) remove(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	sg := s.lookup(w, r)
+	if sg == nil {
+		return
+	}
+	sg.hangUp()
+	delete(s.games, sg.id)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *GameServer) throw(w http.ResponseWriter, r *http.Request) {
+	var req ThrowRequest
+	if !readJSON(w, r, &req) {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	sg := s.lookup(w, r)
+	if sg == nil {
+		return
+	}
+	g := sg.g
+	switch {
+	case g.MatchOver():
+		writeError(w, http.StatusConflict, errors.New("the match is over"))
+		return
+	case req.Player != nil && *req.Player != g.Current:
+		writeError(w, http.StatusConflict, fmt.Errorf("it is player %d's turn", g.Current))
+		return
+	case req.Computer:
+		g.AutoShot()
+	case math.IsNaN(req.Angle) || req.Angle < 0 || req.Angle > 360:
+		writeError(w, http.StatusBadRequest, errors.New("the angle must be between 0 and 360"))
+		return
+	case math.IsNaN(req.Power) || req.Power < 0 || req.Power > 200:
+		writeError(w, http.StatusBadRequest, errors.New("the power must be between 0 and 200"))
+		return
+	default:
+		g.Angle, g.Power = req.Angle, req.Power
+		g.Throw()
+	}
+	// the limits on new games keep every throw short
+	for g.Banana.Active || g.Explosion.Active || g.Dance.Active {
+		g.Step(StepDuration)
+	}
+	result := ThrowResult{Events: sg.thrown, Game: sg.summary()}
+	sg.thrown = nil
+	if result.Events == nil {
+		result.Events = []ThrowEvent{}
+	}
+	if b, err := json.Marshal(result.Game); err == nil {
+		sg.broadcast(serverEvent{"turn", b})
+	}
+	writeJSON(w, http.StatusOK, result)
+}
+
+func (s *GameServer) events(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, http.StatusInternalServerError, errors.New("streaming is not supported"))
+		return
+	}
+	s.mu.Lock()
+	sg := s.lookup(w, r)
+	if sg == nil {
+		s.mu.Unlock()
+		return
+	}
+	ch := make(chan serverEvent, serverBacklog)
+	sg.subscribers[ch] = struct{}{}
+	// start the stream with how the game stands
+	if b, err := json.Marshal(sg.summary()); err == nil {
+		ch <- serverEvent{"turn", b}
+	}
+	s.mu.Unlock()
+	defer func() {
+		s.mu.Lock()
+		delete(sg.subscribers, ch)
+		s.mu.Unlock()
+	}()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case e, ok := <-ch:
+			if !ok {
+				return
+			}
+			if _, err := fmt.Fprintf(w, "event: %s\ndata: %s\n\n", e.name, e.data); err != nil {
+				return
+			}
+			flusher.Flush()
+		}
+	}
+}
+
+func (s *GameServer) league(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.League == nil {
+		writeError(w, http.StatusNotFound, errors.New("this server keeps no league"))
+		return
+	}
+	writeJSON(w, http.StatusOK, s.League.Standings())
+}
+
+// HandleEvent collects what the throw ends in and passes it on to the
+// subscribers.
+func (sg *servedGame) HandleEvent(e GameEvent) {
+	if e.Kind == BananaMoved {
+		// too many to be worth sending
+		return
+	}
+	sg.thrown = append(sg.thrown, ThrowEvent{Kind: e.Kind.String(), Event: e})
+	if b, err := json.Marshal(e); err == nil {
+		sg.broadcast(serverEvent{e.Kind.String(), b})
+	}
+}
+
+// broadcast queues e for each subscriber, letting go of any that fell too
+// far behind.
+func (sg *servedGame) broadcast(e serverEvent) {
+	for ch := range sg.subscribers {
+		select {
+		case ch <- e:
+		default:
+			close(ch)
+			delete(sg.subscribers, ch)
+		}
+	}
+}
+
+// hangUp ends every subscriber's stream.
+func (sg *servedGame) hangUp() {
+	for ch := range sg.subscribers {
+		close(ch)
+		delete(sg.subscribers, ch)
+	}
+}
+
+// summary sums the game up as it stands.
+func (sg *servedGame) summary() ServedGame {
+	g := sg.g
+	round := g.Match.Played
+	if !g.MatchOver() {
+		round++
+	}
+	return ServedGame{
+		ID:      sg.id,
+		Players: g.Players,
+		Current: g.Current,
+		Wind:    g.Wind,
+		Round:   round,
+		Wins:    g.Match.Wins,
+		Over:    g.MatchOver(),
+		Winner:  g.Winner(),
+		Summary: g.MatchSummary(),
+	}
+}
@@ -0,0 +1,240 @@
+package gorillas
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// do sends body to srv and decodes the answer into v, failing unless it
+// comes back with status.
+func do(t *testing.T, srv http.Handler, method, path, body string, status int, v any) {
+	t.Helper()
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	rec := httptest.NewRecorder()
+	srv.ServeHTTP(rec, req)
+	if rec.Code != status {
+		t.Fatalf("%s %s: expected %d, got %d: %s", method, path, status, rec.Code, rec.Body)
+	}
+	if v != nil {
+		if err := json.Unmarshal(rec.Body.Bytes(), v); err != nil {
+			t.Fatalf("%s %s: %v", method, path, err)
+		}
+	}
+}
+
+func TestGameServerPlaysAMatch(t *testing.T) {
+	srv := NewGameServer()
+	srv.League = LoadLeague(t.TempDir() + "/server.lge")
+	defer srv.Close()
+
+	var created ServedGame
+	do(t, srv, "POST", "/games", `{"players":["Ann","Bob"],"seed":7,"settings":{"DefaultRoundQty":1,"DefaultGravity":12}}`, http.StatusCreated, &created)
+	if created.ID != "1" || created.Players[1] != "Bob" || created.Round != 1 || created.Over || created.Winner != -1 {
+		t.Fatalf("unexpected game %+v", created)
+	}
+	if g := srv.games["1"].g; g.GorillaMask == nil || g.League != srv.League {
+		t.Fatal("served games should hit the drawn gorillas and keep only the server's league")
+	}
+	var state Game
+	do(t, srv, "GET", "/games/1", "", http.StatusOK, &state)
+	if state.Seed != 7 || state.Gravity != 12 || len(state.Buildings) == 0 || len(state.Gorillas) != 2 {
+		t.Fatalf("unexpected state: seed %d, gravity %v, %d gorillas", state.Seed, state.Gravity, len(state.Gorillas))
+	}
+
+	var result ThrowResult
+	do(t, srv, "POST", "/games/1/throws", `{"player":0,"angle":45,"power":30}`, http.StatusOK, &result)
+	if len(result.Events) == 0 || result.Events[0].Kind != "ThrowStarted" || result.Events[0].Event.Angle != 45 {
+		t.Fatalf("unexpected events %+v", result.Events)
+	}
+	for throws := 1; !result.Game.Over; throws++ {
+		if throws > 60 {
+			t.Fatal("the match never ended")
+		}
+		do(t, srv, "POST", "/games/1/throws", `{"computer":true}`, http.StatusOK, &result)
+	}
+	last := result.Events[len(result.Events)-1]
+	if last.Kind != "MatchOver" || last.Event.Winner != result.Game.Winner || result.Game.Summary == "" {
+		t.Fatalf("unexpected end %+v of %+v", last, result.Game)
+	}
+	do(t, srv, "POST", "/games/1/throws", `{"computer":true}`, http.StatusConflict, nil)
+
+	var list []ServedGame
+	do(t, srv, "GET", "/games", "", http.StatusOK, &list)
+	if len(list) != 1 || !list[0].Over {
+		t.Fatalf("unexpected list %+v", list)
+	}
+	var table []struct {
+		Name   string
+		Rounds int
+	}
+	do(t, srv, "GET", "/league", "", http.StatusOK, &table)
+	if len(table) != 2 || table[0].Rounds != 1 {
+		t.Fatalf("the round was not recorded: %+v", table)
+	}
+}
+
+func TestGameServerRefusesBadRequests(t *testing.T) {
+	srv := NewGameServer()
+	defer srv.Close()
+	do(t, srv, "POST", "/games", "", http.StatusCreated, nil)
+	do(t, srv, "POST", "/games", `{"players":["Ann"]}`, http.StatusBadRequest, nil)
+	do(t, srv, "POST", "/games", `{"settings":{"DefaultGravity":"high"}}`, http.StatusBadRequest, nil)
+	do(t, srv, "POST", "/games/1/throws", `{"player":1,"angle":45,"power":50}`, http.StatusConflict, nil)
+	do(t, srv, "POST", "/games/1/throws", `{"angle":400,"power":50}`, http.StatusBadRequest, nil)
+	do(t, srv, "POST", "/games/1/throws", `not json`, http.StatusBadRequest, nil)
+	do(t, srv, "GET", "/games/2", "", http.StatusNotFound, nil)
+	do(t, srv, "GET", "/league", "", http.StatusNotFound, nil)
+	do(t, srv, "DELETE", "/games/1", "", http.StatusNoContent, nil)
+	do(t, srv, "GET", "/games/1", "", http.StatusNotFound, nil)
+}
+
+func TestGameServerLimitsGames(t *testing.T) {
+	srv := NewGameServer()
+	defer srv.Close()
+	srv.MaxGames = 2
+	do(t, srv, "POST", "/games", "", http.StatusCreated, nil)
+	do(t, srv, "POST", "/games", "", http.StatusCreated, nil)
+	do(t, srv, "POST", "/games", "", http.StatusServiceUnavailable, nil)
+
+	// the first game is left alone past the timeout, the second is looked at
+	srv.games["1"].used = time.Now().Add(-2 * srv.IdleTimeout)
+	srv.games["2"].used = time.Now().Add(-srv.IdleTimeout / 2)
+	do(t, srv, "GET", "/games/1", "", http.StatusNotFound, nil)
+	do(t, srv, "GET", "/games/2", "", http.StatusOK, nil)
+	var created ServedGame
+	do(t, srv, "POST", "/games", "", http.StatusCreated, &created)
+	if created.ID != "3" {
+		t.Fatalf("unexpected game %+v", created)
+	}
+
+	huge := `{"players":["` + strings.Repeat("x", maxRequestBody) + `"]}`
+	do(t, srv, "POST", "/games/2/throws", huge, http.StatusRequestEntityTooLarge, nil)
+	srv.MaxGames = 0
+	do(t, srv, "POST", "/games", huge, http.StatusRequestEntityTooLarge, nil)
+}
+
+func TestGameServerHoldsGamesToLimits(t *testing.T) {
+	srv := NewGameServer()
+	defer srv.Close()
+	for _, body := range []string{
+		`{"buildings":3,"settings":{"UseOldExplosions":true,"NewExplosionRadius":2e6}}`,
+		`{"buildings":-1}`,
+		`{"buildings":1000}`,
+		`{"settings":{"DefaultGravity":0.001}}`,
+		`{"settings":{"DefaultRoundQty":0}}`,
+		`{"settings":{"Skyline":"nowhere"}}`,
+		`{"settings":{"MatchMode":"sudden death"}}`,
+		`{"map":{"buildings":[{"x":0,"width":100,"height":50},{"x":100,"width":100,"height":50},{"x":200,"width":100,"height":50}],"gravity":{"min":0.01,"max":1}}}`,
+	} {
+		do(t, srv, "POST", "/games", body, http.StatusBadRequest, nil)
+	}
+	if len(srv.games) != 0 {
+		t.Fatalf("%d games were started", len(srv.games))
+	}
+
+	// the slowest throw the limits allow still comes to an end
+	do(t, srv, "POST", "/games", `{"buildings":80,"settings":{"UseOldExplosions":true,"NewExplosionRadius":200,"DefaultGravity":1,"Skyline":"valley","MatchMode":"firstto"}}`, http.StatusCreated, nil)
+	g := srv.games["1"].g
+	if g.Settings.MatchMode != MatchFirstTo || g.Settings.Skyline != "valley" || g.Settings.UseSound {
+		t.Fatalf("unexpected settings %+v", g.Settings)
+	}
+	var result ThrowResult
+	do(t, srv, "POST", "/games/1/throws", `{"angle":89,"power":200}`, http.StatusOK, &result)
+	if g.Banana.Active || g.Explosion.Active || g.Dance.Active {
+		t.Fatal("the throw was not played out")
+	}
+}
+
+func TestGameServerStreamsEvents(t *testing.T) {
+	games := NewGameServer()
+	ts := httptest.NewServer(games)
+	defer ts.Close()
+	defer games.Close()
+	do(t, games, "POST", "/games", `{"seed":7}`, http.StatusCreated, nil)
+
+	resp, err := http.Get(ts.URL + "/games/1/events")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("unexpected content type %q", ct)
+	}
+	type event struct{ name, data string }
+	events := make(chan event)
+	go func() {
+		defer close(events)
+		in := bufio.NewScanner(resp.Body)
+		in.Buffer(nil, 1<<20)
+		var e event
+		for in.Scan() {
+			line := in.Text()
+			switch {
+			case strings.HasPrefix(line, "event: "):
+				e.name = strings.TrimPrefix(line, "event: ")
+			case strings.HasPrefix(line, "data: "):
+				e.data = strings.TrimPrefix(line, "data: ")
+			case line == "":
+				events <- e
+				e = event{}
+			}
+		}
+	}()
+	next := func() event {
+		t.Helper()
+		select {
+		case e, ok := <-events:
+			if !ok {
+				t.Fatal("the stream ended early")
+			}
+			return e
+		case <-time.After(5 * time.Second):
+			t.Fatal("no event arrived")
+		}
+		return event{}
+	}
+
+	if e := next(); e.name != "turn" {
+		t.Fatalf("expected the game as it stands first, got %q", e.name)
+	}
+	var result ThrowResult
+	do(t, games, "POST", "/games/1/throws", `{"angle":60,"power":40}`, http.StatusOK, &result)
+	var streamed []string
+	for {
+		e := next()
+		if e.name == "turn" {
+			var turn ServedGame
+			if err := json.Unmarshal([]byte(e.data), &turn); err != nil {
+				t.Fatal(err)
+			}
+			if turn.Current != result.Game.Current || turn.Wind != result.Game.Wind {
+				t.Fatalf("the stream sent %+v, the throw answered %+v", turn, result.Game)
+			}
+			break
+		}
+		streamed = append(streamed, e.name)
+	}
+	if len(streamed) != len(result.Events) {
+		t.Fatalf("the stream sent %v, the throw answered %+v", streamed, result.Events)
+	}
+	for i, name := range streamed {
+		if name != result.Events[i].Kind {
+			t.Fatalf("the stream sent %v, the throw answered %+v", streamed, result.Events)
+		}
+	}
+
+	do(t, games, "DELETE", "/games/1", "", http.StatusNoContent, nil)
+	select {
+	case _, ok := <-events:
+		if ok {
+			t.Fatal("expected the stream to end with the game")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("the stream outlived the game")
+	}
+}
@@ -0,0 +1,204 @@
+package gorillas
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// SkylineGenerator decides how tall the buildings of a new city are.
+type SkylineGenerator interface {
+	// Heights sets H for each of buildings, which already have their X and
+	// W laid out across a city width by height units. All randomness must
+	// come from r so the same seed always raises the same skyline.
+	Heights(r *rand.Rand, buildings []Building, width, height float64)
+}
+
+// SkylineFunc adapts an ordinary function to the SkylineGenerator interface.
+type SkylineFunc func(r *rand.Rand, buildings []Building, width, height float64)
+
+// Heights calls f.
+func (f SkylineFunc) Heights(r *rand.Rand, buildings []Building, width, height float64) {
+	f(r, buildings, width, height)
+}
+
+// DefaultSkyline names the generator used when Settings.Skyline is empty.
+const DefaultSkyline = "basic"
+
+// skylinesMu guards skylines, as games are raised on several goroutines at
+// once.
+var skylinesMu sync.RWMutex
+
+var skylines = map[string]SkylineGenerator{
+	"basic":      SkylineFunc(basicSkyline),
+	"valley":     SkylineFunc(valleySkyline),
+	"mountain":   SkylineFunc(mountainSkyline),
+	"flat":       SkylineFunc(flatSkyline),
+	"randomwalk": SkylineFunc(randomWalkSkyline),
+	"canyon":     SkylineFunc(canyonSkyline),
+	"staircase":  SkylineFunc(staircaseSkyline),
+}
+
+// RegisterSkyline makes gen selectable by name through Settings.Skyline,
+// replacing any generator already registered under that name.
+func RegisterSkyline(name string, gen SkylineGenerator) {
+	skylinesMu.Lock()
+	defer skylinesMu.Unlock()
+	skylines[skylineKey(name)] = gen
+}
+
+// SkylineByName returns the generator registered as name. An empty name
+// selects DefaultSkyline.
+func SkylineByName(name string) (SkylineGenerator, error) {
+	if strings.TrimSpace(name) == "" {
+		name = DefaultSkyline
+	}
+	skylinesMu.RLock()
+	gen, ok := skylines[skylineKey(name)]
+	skylinesMu.RUnlock()
+	if ok {
+		return gen, nil
+	}
+	return nil, fmt.Errorf("unknown skyline %q (want one of %s)", name, strings.Join(SkylineNames(), ", "))
+}
+
+// SkylineNames returns the registered generator names in alphabetical order.
+func SkylineNames() []string {
+	skylinesMu.RLock()
+	defer skylinesMu.RUnlock()
+	names := make([]string, 0, len(skylines))
+	for n := range skylines {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func skylineKey(name string) string {
+	return strings.ToLower(strings.NewReplacer("-", "", "_", "", " ", "").Replace(strings.TrimSpace(name)))
+}
+
+// clampHeight keeps a building between 15% and 60% of the city height so
+// every roof is reachable and none hides the sun.
+func clampHeight(h, height float64) float64 {
+	return math.Max(height*0.15, math.Min(h, height*0.6))
+}
+
+// jitter returns a random offset of up to a twelfth of the city height
+// either way, as the BASIC original adds to each building.
+func jitter(r *rand.Rand, height float64) float64 {
+	return r.Float64()*height/6 - height/12
+}
+
+// centre returns where a building's middle sits as a fraction of the city
+// width.
+func centre(b Building, width float64) float64 {
+	return (b.X + b.W/2) / width
+}
+
+// basicSkyline picks one of the six slope profiles of MakeCityScape in the
+// original game: rising, falling, a valley or a peak.
+func basicSkyline(r *rand.Rand, buildings []Building, width, height float64) {
+	slope := r.Intn(6) + 1
+	newHt := height * 0.2
+	if slope == 2 || slope == 6 {
+		newHt = height * 0.6
+	}
+	htInc := height / 35
+
+	for i := range buildings {
+		x := buildings[i].X
+		switch slope {
+		case 1:
+			newHt += htInc
+		case 2:
+			newHt -= htInc
+		case 3, 5:
+			if x > width/2 {
+				newHt -= 2 * htInc
+			} else {
+				newHt += 2 * htInc
+			}
+		case 4:
+			if x > width/2 {
+				newHt += 2 * htInc
+			} else {
+				newHt -= 2 * htInc
+			}
+		}
+		buildings[i].H = clampHeight(newHt+jitter(r, height), height)
+	}
+}
+
+// valleySkyline runs from tall towers at the edges down to a low centre.
+func valleySkyline(r *rand.Rand, buildings []Building, width, height float64) {
+	for i := range buildings {
+		d := math.Abs(centre(buildings[i], width)-0.5) * 2
+		buildings[i].H = clampHeight(height*(0.15+0.45*d)+jitter(r, height)/2, height)
+	}
+}
+
+// mountainSkyline climbs from low edges to a peak in the centre.
+func mountainSkyline(r *rand.Rand, buildings []Building, width, height float64) {
+	for i := range buildings {
+		d := math.Abs(centre(buildings[i], width)-0.5) * 2
+		buildings[i].H = clampHeight(height*(0.6-0.45*d)+jitter(r, height)/2, height)
+	}
+}
+
+// flatSkyline keeps every roof close to one shared height.
+func flatSkyline(r *rand.Rand, buildings []Building, width, height float64) {
+	base := height * (0.25 + 0.15*r.Float64())
+	for i := range buildings {
+		buildings[i].H = clampHeight(base+jitter(r, height)/4, height)
+	}
+}
+
+// randomWalkSkyline starts at a random height and lets each building step
+// up or down from its neighbour.
+func randomWalkSkyline(r *rand.Rand, buildings []Building, width, height float64) {
+	h := height * (0.2 + 0.3*r.Float64())
+	for i := range buildings {
+		h = clampHeight(h+(r.Float64()*2-1)*height/8, height)
+		buildings[i].H = h
+	}
+}
+
+// canyonSkyline puts high plateaus on either side of a deep gorge in the
+// middle third of the city.
+func canyonSkyline(r *rand.Rand, buildings []Building, width, height float64) {
+	for i := range buildings {
+		c := centre(buildings[i], width)
+		h := height * 0.5
+		if c > 1.0/3 && c < 2.0/3 {
+			h = height * 0.15
+		}
+		buildings[i].H = clampHeight(h+jitter(r, height)/3, height)
+	}
+}
+
+// staircaseSkyline climbs in even steps of a few buildings from one side of
+// the city to the other.
+func staircaseSkyline(r *rand.Rand, buildings []Building, width, height float64) {
+	n := len(buildings)
+	if n == 0 {
+		return
+	}
+	run := 1 + r.Intn(3)
+	steps := (n + run - 1) / run
+	rising := r.Intn(2) == 0
+	for i := range buildings {
+		step := i / run
+		if !rising {
+			step = steps - 1 - step
+		}
+		frac := 0.0
+		if steps > 1 {
+			frac = float64(step) / float64(steps-1)
+		}
+		buildings[i].H = clampHeight(height*(0.15+0.45*frac), height)
+	}
+}
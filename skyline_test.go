@@ -0,0 +1,116 @@
+package gorillas
+
+import (
+	"math/rand"
+	"reflect"
+	"testing"
+)
+
+func skylineHeights(g *Game) []float64 {
+	var hs []float64
+	for _, b := range g.Buildings {
+		hs = append(hs, b.H)
+	}
+	return hs
+}
+
+func TestSkylinesAreDeterministicAndInRange(t *testing.T) {
+	for _, name := range SkylineNames() {
+		t.Run(name, func(t *testing.T) {
+			s := DefaultSettings()
+			s.Skyline = name
+			a := newGame(WorldWidth, WorldHeight, DefaultBuildingCount, 2, s, nil, rand.New(rand.NewSource(7)))
+			b := newGame(WorldWidth, WorldHeight, DefaultBuildingCount, 2, s, nil, rand.New(rand.NewSource(7)))
+			if !reflect.DeepEqual(skylineHeights(a), skylineHeights(b)) {
+				t.Fatal("the same seed should raise the same skyline")
+			}
+			for i, h := range skylineHeights(a) {
+				if h < WorldHeight*0.15-1e-9 || h > WorldHeight*0.6+1e-9 {
+					t.Fatalf("building %d is %f tall", i, h)
+				}
+			}
+		})
+	}
+}
+
+func TestSkylineShapes(t *testing.T) {
+	heights := func(name string) []float64 {
+		s := DefaultSettings()
+		s.Skyline = name
+		return skylineHeights(newGame(WorldWidth, WorldHeight, 9, 2, s, nil, rand.New(rand.NewSource(3))))
+	}
+	v := heights("valley")
+	if v[4] >= v[0] || v[4] >= v[8] {
+		t.Errorf("valley should dip in the middle: %v", v)
+	}
+	m := heights("mountain")
+	if m[4] <= m[0] || m[4] <= m[8] {
+		t.Errorf("mountain should peak in the middle: %v", m)
+	}
+	c := heights("canyon")
+	if c[4] >= c[0] || c[4] >= c[8] {
+		t.Errorf("canyon should have a gorge in the middle: %v", c)
+	}
+	st := heights("staircase")
+	for i := 1; i < len(st); i++ {
+		if (st[i]-st[i-1])*(st[8]-st[0]) < 0 {
+			t.Errorf("staircase should only climb one way: %v", st)
+		}
+	}
+}
+
+func TestResetUsesConfiguredSkyline(t *testing.T) {
+	g := NewGameWithSeed(WorldWidth, WorldHeight, DefaultBuildingCount, 11)
+	g.League = nil
+	g.Settings.Skyline = "flat"
+	RegisterSkyline("test-level", SkylineFunc(func(r *rand.Rand, bs []Building, w, h float64) {
+		for i := range bs {
+			bs[i].H = h / 4
+		}
+	}))
+	defer delete(skylines, "testlevel")
+	g.Settings.Skyline = "Test Level"
+	g.Reset()
+	for _, b := range g.Buildings {
+		if b.H != WorldHeight/4 {
+			t.Fatalf("registered skyline not used: %v", skylineHeights(g))
+		}
+	}
+	if g.Gorillas[0].Y != WorldHeight*3/4 {
+		t.Fatalf("gorillas should stand on the new roofs, got y=%f", g.Gorillas[0].Y)
+	}
+}
+
+func TestSkylineByNameRejectsUnknown(t *testing.T) {
+	if _, err := SkylineByName("moon"); err == nil {
+		t.Fatal("expected an error for an unknown skyline")
+	}
+	if _, err := SkylineByName(""); err != nil {
+		t.Fatalf("empty name should select the default: %v", err)
+	}
+}
+
+func TestSkylinesRegisterWhileGamesAreRaised(t *testing.T) {
+	defer func() {
+		skylinesMu.Lock()
+		delete(skylines, "late")
+		skylinesMu.Unlock()
+	}()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 50; i++ {
+			RegisterSkyline("late", SkylineFunc(flatSkyline))
+		}
+	}()
+	for i := 0; i < 50; i++ {
+		g := NewGameWithSeed(WorldWidth, WorldHeight, DefaultBuildingCount, int64(i))
+		g.League = nil
+		g.Settings.Skyline = "valley"
+		g.Reset()
+	}
+	<-done
+	if _, err := SkylineByName("late"); err != nil {
+		t.Fatal(err)
+	}
+}
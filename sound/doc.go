@@ -0,0 +1,8 @@
+// Package sound plays the game's beeps and tunes through the speakers.
+// The game stays silent until a frontend imports it for its side effect:
+//
+//	import _ "github.com/arran4/gorillas/sound"
+//
+// Tests, and builds with the nosound tag, leave the audio libraries out and
+// stay silent.
+package sound
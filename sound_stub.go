@@ -1,11 +0,0 @@
-//go:build test
-
-package gorillas
-
-func PlayBeep() {}
-
-func PlayIntroMusic() {}
-
-func PlayDanceMelody() {}
-
-func PlayExplosionMelody() {}
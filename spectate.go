@@ -0,0 +1,404 @@
+package gorillas
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net"
+	"sync"
+	"time"
+)
+
+// SpectateProtocol is the version of the stream a game sends its
+// spectators.
+//
+// The game sends one JSON object per line and reads nothing back. Each
+// spectator first gets {"type":"state","protocol":1,"game":{...}}, the whole
+// Game as it waits for a throw, and another every time it next waits for
+// one. In between come {"type":"throw","seat":1,"angle":45,"power":60,
+// "wind":3} as each banana leaves, wind being what it flies through, and
+// {"type":"event","kind":"BuildingHit","event":{...}} for each GameEvent
+// the throw ends in. Fields that are zero are left out. {"type":"bye"} ends
+// the stream when the game does.
+const SpectateProtocol = 1
+
+// spectatorBacklog is how many lines a spectator may fall behind before it
+// is let go rather than hold the game up.
+const spectatorBacklog = 256
+
+// spectateMessage is any line of the stream; fields that do not apply to
+// its Type are left empty.
+type spectateMessage struct {
+	Type     string          `json:"type"`
+	Protocol int             `json:"protocol,omitempty"`
+	Game     json.RawMessage `json:"game,omitempty"`
+	Seat     int             `json:"seat,omitempty"`
+	Angle    float64         `json:"angle,omitempty"`
+	Power    float64         `json:"power,omitempty"`
+	Wind     float64         `json:"wind,omitempty"`
+	Kind     string          `json:"kind,omitempty"`
+	Event    *GameEvent      `json:"event,omitempty"`
+}
+
+// line encodes m as one line of the stream, or returns nil if it cannot,
+// as when a number is not finite.
+func (m spectateMessage) line() []byte {
+	b, err := json.Marshal(m)
+	if err != nil {
+		return nil
+	}
+	return append(b, '\n')
+}
+
+// watcher is a spectator's connection. It is written from a goroutine of
+// its own so a slow spectator cannot hold the game up.
+type watcher struct {
+	conn net.Conn
+	out  chan []byte
+	gone chan struct{}
+}
+
+func newWatcher(conn net.Conn) *watcher {
+	w := &watcher{conn: conn, out: make(chan []byte, spectatorBacklog), gone: make(chan struct{})}
+	go w.write()
+	return w
+}
+
+// write sends the queued lines until the queue is closed or the spectator
+// hangs up.
+func (w *watcher) write() {
+	defer close(w.gone)
+	defer w.conn.Close()
+	for b := range w.out {
+		if _, err := w.conn.Write(b); err != nil {
+			return
+		}
+	}
+}
+
+// broadcast queues line for each of ws and returns those still watching.
+// Spectators that hung up or fell too far behind are let go.
+func broadcast(ws []*watcher, line []byte) []*watcher {
+	if line == nil {
+		return ws
+	}
+	keep := ws[:0]
+	for _, w := range ws {
+		select {
+		case <-w.gone:
+			close(w.out)
+			continue
+		default:
+		}
+		select {
+		case w.out <- line:
+			keep = append(keep, w)
+		default:
+			close(w.out)
+			w.conn.Close()
+		}
+	}
+	return keep
+}
+
+// SpectatorServer streams a game to any number of read-only spectators.
+type SpectatorServer struct {
+	g           *Game
+	l           net.Listener
+	unsubscribe func()
+	notify      chan struct{}
+
+	mu sync.Mutex
+	// joining holds those who connected since the last Update.
+	joining []*watcher
+
+	// watching and settled are only used from the game's goroutine;
+	// settled is set once the game as it stands after the latest throw
+	// has been sent.
+	watching []*watcher
+	settled  bool
+}
+
+// ServeSpectators lets spectators connect on l to watch g until Close. The
+// frontend calls Update whenever g waits for a throw.
+func ServeSpectators(l net.Listener, g *Game) *SpectatorServer {
+	s := &SpectatorServer{g: g, l: l, notify: make(chan struct{}, 1)}
+	s.unsubscribe = g.Subscribe(s)
+	go s.accept()
+	return s
+}
+
+func (s *SpectatorServer) accept() {
+	for {
+		conn, err := s.l.Accept()
+		if err != nil {
+			return
+		}
+		s.mu.Lock()
+		s.joining = append(s.joining, newWatcher(conn))
+		s.mu.Unlock()
+		select {
+		case s.notify <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// Notify returns a channel that receives when a spectator connects, so a
+// frontend blocked waiting for its player's input can call Update.
+func (s *SpectatorServer) Notify() <-chan struct{} {
+	return s.notify
+}
+
+// Update sends the game to spectators who have just connected, and to
+// everyone once it has settled after a throw.
+func (s *SpectatorServer) Update() {
+	s.mu.Lock()
+	joined := s.joining
+	s.joining = nil
+	s.mu.Unlock()
+	if s.settled && len(joined) == 0 {
+		return
+	}
+	c := s.g.Clone()
+	c.ScoreFile, c.ShotsFile = "", ""
+	game, err := json.Marshal(c)
+	if err != nil {
+		return
+	}
+	line := spectateMessage{Type: "state", Protocol: SpectateProtocol, Game: game}.line()
+	if s.settled {
+		s.watching = append(s.watching, broadcast(joined, line)...)
+		return
+	}
+	s.watching = broadcast(append(s.watching, joined...), line)
+	s.settled = true
+}
+
+// HandleEvent passes each throw and what it ends in on to the spectators.
+func (s *SpectatorServer) HandleEvent(e GameEvent) {
+	switch e.Kind {
+	case BananaMoved:
+		// spectators fly the banana themselves
+	case ThrowStarted:
+		s.settled = false
+		s.watching = broadcast(s.watching, spectateMessage{Type: "throw", Seat: e.Player, Angle: e.Angle, Power: e.Power, Wind: s.g.Wind}.line())
+	default:
+		s.watching = broadcast(s.watching, spectateMessage{Type: "event", Kind: e.Kind.String(), Event: &e}.line())
+	}
+}
+
+// Watching returns how many spectators are watching.
+func (s *SpectatorServer) Watching() int {
+	return len(s.watching)
+}
+
+// Close sends the game as it ended, says goodbye and stops serving,
+// giving the spectators a moment to take the last lines.
+func (s *SpectatorServer) Close() error {
+	err := s.l.Close()
+	s.unsubscribe()
+	s.Update()
+	s.watching = broadcast(s.watching, spectateMessage{Type: "bye"}.line())
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	for _, w := range s.watching {
+		close(w.out)
+		select {
+		case <-w.gone:
+		case <-ctx.Done():
+			w.conn.Close()
+		}
+	}
+	s.watching = nil
+	return err
+}
+
+// Spectator follows a game served by ServeSpectators, throwing each banana
+// again on a game of its own so it can be drawn like any other.
+type Spectator struct {
+	g      *Game
+	conn   net.Conn
+	in     *bufio.Scanner
+	lines  chan spectateMessage
+	notify chan struct{}
+	// readErr says why lines was closed.
+	readErr error
+	err     error
+}
+
+// WatchGame connects to the game streamed at addr and sets g up as it
+// stands. Settings that only change what this side shows stay g's own.
+// Cancelling ctx gives up.
+func WatchGame(ctx context.Context, addr string, g *Game) (*Spectator, error) {
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	stop := closeOnCancel(ctx, conn)
+	defer stop()
+	in := bufio.NewScanner(conn)
+	// a whole game with its craters and map makes long lines
+	in.Buffer(nil, 16<<20)
+	s := &Spectator{g: g, conn: conn, in: in, lines: make(chan spectateMessage, spectatorBacklog), notify: make(chan struct{}, 1)}
+	conn.SetReadDeadline(time.Now().Add(netHandshakeTimeout))
+	m, err := s.read()
+	if err == nil && m.Type != "state" {
+		err = fmt.Errorf("expected the game, got %q", m.Type)
+	}
+	if err == nil {
+		err = s.apply(m)
+	}
+	if err != nil {
+		conn.Close()
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return nil, err
+	}
+	conn.SetReadDeadline(time.Time{})
+	go s.follow()
+	return s, nil
+}
+
+func (s *Spectator) read() (spectateMessage, error) {
+	var m spectateMessage
+	if !s.in.Scan() {
+		if err := s.in.Err(); err != nil {
+			return m, err
+		}
+		return m, errors.New("connection closed")
+	}
+	if err := json.Unmarshal(s.in.Bytes(), &m); err != nil {
+		return m, fmt.Errorf("bad line %q: %w", s.in.Text(), err)
+	}
+	return m, nil
+}
+
+// follow passes the stream on until it ends.
+func (s *Spectator) follow() {
+	defer close(s.notify)
+	for {
+		m, err := s.read()
+		if err != nil {
+			s.readErr = err
+			close(s.lines)
+			return
+		}
+		s.lines <- m
+		select {
+		case s.notify <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// apply replaces the game with the state in m, keeping this side's
+// settings, listeners and gorilla shape. The frontend's ResetHook is called
+// when the city changes.
+func (s *Spectator) apply(m spectateMessage) error {
+	if m.Protocol != SpectateProtocol {
+		return fmt.Errorf("the game streams protocol %d, this build follows %d", m.Protocol, SpectateProtocol)
+	}
+	var next Game
+	if err := json.Unmarshal(m.Game, &next); err != nil {
+		return fmt.Errorf("bad game: %w", err)
+	}
+	if len(next.Players) < MinPlayers || len(next.Gorillas) != len(next.Players) {
+		return errors.New("bad game: the players do not match their gorillas")
+	}
+	g := s.g
+	newCity := !sameCity(g.Buildings, next.Buildings)
+	if !newCity {
+		// keep the colours the city is already drawn in
+		for i := range next.Buildings {
+			if next.Buildings[i].Color.A == 0 {
+				next.Buildings[i].Color = g.Buildings[i].Color
+			}
+		}
+	}
+	next.Settings = next.Settings.withLocal(g.Settings)
+	// each throw brings the wind it flew through
+	next.Settings.WindFluctuations = false
+	next.Rand = rand.New(rand.NewSource(next.Seed))
+	next.GorillaMask = g.GorillaMask
+	next.AnimationSteps = g.AnimationSteps
+	next.ResetHook = g.ResetHook
+	next.listeners, next.nextListener = g.listeners, g.nextListener
+	next.ScoreFile, next.ShotsFile, next.ReplayDir = "", "", ""
+	// stop once the banana lands; the next state brings the next round
+	next.replaying = true
+	*g = next
+	g.RebuildHitMap()
+	if newCity && g.ResetHook != nil {
+		g.ResetHook()
+	}
+	return nil
+}
+
+// sameCity reports whether a and b are the same skyline, craters aside.
+func sameCity(a, b []Building) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i].X != b[i].X || a[i].W != b[i].W || a[i].H != b[i].H {
+			return false
+		}
+	}
+	return true
+}
+
+// Notify returns a channel that receives whenever something arrives from
+// the game and is closed once the stream ends, so a frontend blocked
+// waiting for input can poll again.
+func (s *Spectator) Notify() <-chan struct{} {
+	return s.notify
+}
+
+// Poll plays on what has arrived while the game waits for a throw:
+// throwing the next banana or taking up the game as it stands after the
+// last. Once the stream has ended it returns why, every time.
+func (s *Spectator) Poll() error {
+	g := s.g
+	for s.err == nil && !g.Banana.Active && !g.Explosion.Active && !g.Dance.Active {
+		var m spectateMessage
+		var ok bool
+		select {
+		case m, ok = <-s.lines:
+		default:
+			return nil
+		}
+		switch {
+		case !ok:
+			s.err = fmt.Errorf("lost the connection to the game: %w", s.readErr)
+		case m.Type == "state":
+			if err := s.apply(m); err != nil {
+				s.err = err
+				s.conn.Close()
+			}
+		case m.Type == "throw":
+			if m.Seat < 0 || m.Seat >= len(g.Gorillas) {
+				s.err = fmt.Errorf("a throw from seat %d, which is not in the game", m.Seat)
+				s.conn.Close()
+				break
+			}
+			g.setCurrent(m.Seat)
+			g.Angle, g.Power, g.Wind = m.Angle, m.Power, m.Wind
+			g.Throw()
+		case m.Type == "bye":
+			s.err = errors.New("the game has ended")
+			s.conn.Close()
+		}
+	}
+	return s.err
+}
+
+// Close stops watching.
+func (s *Spectator) Close() error {
+	return s.conn.Close()
+}
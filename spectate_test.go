@@ -0,0 +1,136 @@
+package gorillas
+
+import (
+	"context"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+// catchUp polls s and plays its game on until it matches want.
+func catchUp(t *testing.T, s *Spectator, g *Game, want uint64) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for g.StateHash() != want {
+		if err := s.Poll(); err != nil {
+			t.Fatal(err)
+		}
+		settle(g)
+		if time.Now().After(deadline) {
+			t.Fatal("the spectator never caught up")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestSpectatorsFollowTheGame(t *testing.T) {
+	g := newNetGame(t, "Ann", 7)
+	g.Settings.DefaultRoundQty = 2
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := ServeSpectators(l, g)
+	defer srv.Close()
+
+	const watchers = 2
+	type watching struct {
+		s *Spectator
+		g *Game
+	}
+	joined := make(chan watching, watchers)
+	failed := make(chan error, watchers)
+	for i := 0; i < watchers; i++ {
+		go func() {
+			sg := newNetGame(t, "Eve", int64(100+i))
+			s, err := WatchGame(context.Background(), l.Addr().String(), sg)
+			if err != nil {
+				failed <- err
+				return
+			}
+			joined <- watching{s, sg}
+		}()
+	}
+	var ws []watching
+	for len(ws) < watchers {
+		srv.Update()
+		select {
+		case w := <-joined:
+			defer w.s.Close()
+			ws = append(ws, w)
+		case err := <-failed:
+			t.Fatal(err)
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+	if srv.Watching() != watchers {
+		t.Fatalf("expected %d spectators, got %d", watchers, srv.Watching())
+	}
+	thrown := 0
+	ws[0].g.Subscribe(ListenerFunc(func(e GameEvent) {
+		if e.Kind == ThrowStarted {
+			thrown++
+		}
+	}))
+	for _, w := range ws {
+		if w.g.Seed != 7 || w.g.Players[1] != "Guest" || w.g.StateHash() != g.StateHash() {
+			t.Fatal("the spectator did not take up the game as it stands")
+		}
+	}
+
+	throws := 0
+	for ; !g.MatchOver(); throws++ {
+		if throws > 60 {
+			t.Fatal("the match never ended")
+		}
+		g.AutoShot()
+		settle(g)
+		srv.Update()
+		for _, w := range ws {
+			catchUp(t, w.s, w.g, g.StateHash())
+		}
+	}
+	if thrown != throws {
+		t.Errorf("the spectator threw %d bananas, the game %d", thrown, throws)
+	}
+	if !ws[0].g.MatchOver() || ws[0].g.Winner() != g.Winner() {
+		t.Error("the spectator should see the same winner")
+	}
+
+	srv.Close()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		err := ws[0].s.Poll()
+		if err != nil {
+			if !strings.Contains(err.Error(), "ended") {
+				t.Errorf("unexpected error %v", err)
+			}
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("the spectator never heard the game end")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestWatchRefusesOtherProtocols(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	go func() {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		conn.Write([]byte(`{"type":"state","protocol":99,"game":{}}` + "\n"))
+	}()
+	_, err = WatchGame(context.Background(), l.Addr().String(), newNetGame(t, "Eve", 1))
+	if err == nil || !strings.Contains(err.Error(), "protocol 99") {
+		t.Fatalf("expected the stream to be refused, got %v", err)
+	}
+}
@@ -0,0 +1,230 @@
+package gorillas
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// FriendlyFire selects what happens when a banana catches a teammate.
+type FriendlyFire int
+
+const (
+	// FriendlyFireSelf knocks the teammate out and treats the throw like a
+	// self-kill, complete with the EventSelf message.
+	FriendlyFireSelf FriendlyFire = iota
+	// FriendlyFireOff keeps teammates safe: bananas fly past them and blasts
+	// leave them standing.
+	FriendlyFireOff
+	// FriendlyFireIgnored knocks the teammate out like any opponent without
+	// remarking on it.
+	FriendlyFireIgnored
+)
+
+// String returns the name used for the rule in flags and settings files.
+func (f FriendlyFire) String() string {
+	switch f {
+	case FriendlyFireOff:
+		return "off"
+	case FriendlyFireIgnored:
+		return "ignored"
+	default:
+		return "self"
+	}
+}
+
+// ParseFriendlyFire converts a name such as "off" or "self-kill" into a
+// FriendlyFire rule.
+func ParseFriendlyFire(s string) (FriendlyFire, error) {
+	switch strings.ToLower(strings.NewReplacer("-", "", "_", "", " ", "").Replace(strings.TrimSpace(s))) {
+	case "self", "selfkill":
+		return FriendlyFireSelf, nil
+	case "off", "none":
+		return FriendlyFireOff, nil
+	case "ignored", "ignore":
+		return FriendlyFireIgnored, nil
+	}
+	return FriendlyFireSelf, fmt.Errorf("unknown friendly fire rule %q", s)
+}
+
+// SetTeams groups the seated players into sides. labels gives a team label
+// for every seat; seats sharing a label play together, a zero label plays
+// alone, and teams are numbered in the order their first member is seated.
+// Calling SetTeams without labels, or with only zeros, returns to every
+// gorilla for itself. Session wins and the current match start over, now
+// counted per team.
+func (g *Game) SetTeams(labels ...int) error {
+	teams, err := numberTeams(labels, len(g.Players))
+	if err != nil {
+		return err
+	}
+	g.Teams = teams
+	g.Wins = make([]int, g.Sides())
+	g.TotalWins = resizeInts(g.TotalWins, g.Sides())
+	g.setCurrent(g.turnOrder()[0])
+	g.StartMatch()
+	return nil
+}
+
+// ParseTeams reads comma separated team labels such as "1,1,2,2", one for
+// each seat, for use with SetTeams. A blank label is read as zero.
+func ParseTeams(s string) ([]int, error) {
+	if strings.TrimSpace(s) == "" {
+		return nil, nil
+	}
+	parts := strings.Split(s, ",")
+	labels := make([]int, len(parts))
+	for i, p := range parts {
+		p = strings.TrimSpace(p)
+		if p == "" {
+			continue
+		}
+		n, err := strconv.Atoi(p)
+		if err != nil || n < 0 {
+			return nil, fmt.Errorf("bad team %q for seat %d", p, i+1)
+		}
+		labels[i] = n
+	}
+	return labels, nil
+}
+
+// TeamLabels returns the team label of every seat, counted from one, in the
+// form SetTeams and ParseTeams use. Seats are all zero without teams.
+func (g *Game) TeamLabels() []int {
+	labels := make([]int, len(g.Gorillas))
+	if g.Teams != nil {
+		for i := range labels {
+			labels[i] = g.Side(i) + 1
+		}
+	}
+	return labels
+}
+
+// numberTeams turns per-seat labels into team numbers counted from zero. It
+// returns nil when nobody shares a team.
+func numberTeams(labels []int, players int) ([]int, error) {
+	if len(labels) == 0 {
+		return nil, nil
+	}
+	if len(labels) != players {
+		return nil, fmt.Errorf("need a team for each of the %d players, got %d", players, len(labels))
+	}
+	ids := map[int]int{}
+	teams := make([]int, len(labels))
+	next, shared := 0, false
+	for i, l := range labels {
+		id, ok := ids[l]
+		switch {
+		case l == 0:
+			id = next
+			next++
+		case !ok:
+			id = next
+			ids[l] = id
+			next++
+		default:
+			shared = true
+		}
+		teams[i] = id
+	}
+	if !shared {
+		return nil, nil
+	}
+	if next < 2 {
+		return nil, fmt.Errorf("need at least two teams")
+	}
+	return teams, nil
+}
+
+// Side returns the side gorilla idx plays for: its team in a team game,
+// otherwise its own seat.
+func (g *Game) Side(idx int) int {
+	if len(g.Teams) == len(g.Gorillas) {
+		return g.Teams[idx]
+	}
+	return idx
+}
+
+// Sides returns the number of sides competing: the teams in a team game,
+// otherwise the players.
+func (g *Game) Sides() int {
+	if len(g.Teams) != len(g.Gorillas) {
+		return len(g.Gorillas)
+	}
+	n := 0
+	for _, t := range g.Teams {
+		if t+1 > n {
+			n = t + 1
+		}
+	}
+	return n
+}
+
+// TeamMembers returns the seats playing for side.
+func (g *Game) TeamMembers(side int) []int {
+	var idx []int
+	for i := range g.Gorillas {
+		if g.Side(i) == side {
+			idx = append(idx, i)
+		}
+	}
+	return idx
+}
+
+// SideName returns the player name for a side, or its members' names joined
+// by " & " for a team.
+func (g *Game) SideName(side int) string {
+	var names []string
+	for _, i := range g.TeamMembers(side) {
+		if i < len(g.Players) {
+			names = append(names, g.Players[i])
+		}
+	}
+	return strings.Join(names, " & ")
+}
+
+// teammates reports whether two different gorillas play for the same side.
+func (g *Game) teammates(a, b int) bool {
+	return a != b && g.Side(a) == g.Side(b)
+}
+
+// teamRoster returns the names of every side's players, indexed by side.
+func (g *Game) teamRoster() [][]string {
+	roster := make([][]string, g.Sides())
+	for i, name := range g.Players {
+		s := g.Side(i)
+		roster[s] = append(roster[s], name)
+	}
+	return roster
+}
+
+// turnOrder returns the seats in the order they throw. Teams take turns,
+// with each team's members throwing in seating order.
+func (g *Game) turnOrder() []int {
+	order := make([]int, len(g.Gorillas))
+	rank := make([]int, len(g.Gorillas))
+	count := map[int]int{}
+	for i := range order {
+		order[i] = i
+		rank[i] = count[g.Side(i)]
+		count[g.Side(i)]++
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		return rank[order[a]] < rank[order[b]]
+	})
+	return order
+}
+
+// standingSides returns the sides that still have a gorilla standing.
+func (g *Game) standingSides() []int {
+	seen := map[int]bool{}
+	var sides []int
+	for _, i := range g.alive() {
+		if s := g.Side(i); !seen[s] {
+			seen[s] = true
+			sides = append(sides, s)
+		}
+	}
+	return sides
+}
@@ -0,0 +1,205 @@
+package gorillas
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func newTeamGame(t *testing.T, labels ...int) *Game {
+	t.Helper()
+	g := newTestGame()
+	if err := g.SetPlayers("Ann", "Bob", "Cat", "Dan"); err != nil {
+		t.Fatal(err)
+	}
+	if err := g.SetTeams(labels...); err != nil {
+		t.Fatal(err)
+	}
+	g.ScoreFile = filepath.Join(t.TempDir(), "scores.json")
+	return g
+}
+
+func TestSetTeamsValidates(t *testing.T) {
+	g := newTestGame()
+	if err := g.SetPlayers("Ann", "Bob", "Cat", "Dan"); err != nil {
+		t.Fatal(err)
+	}
+	if err := g.SetTeams(1, 2, 1); err == nil {
+		t.Fatal("expected an error when a seat has no team")
+	}
+	if err := g.SetTeams(1, 1, 1, 1); err == nil {
+		t.Fatal("expected an error for a single team")
+	}
+	if err := g.SetTeams(7, 7, 3, 3); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(g.Teams, []int{0, 0, 1, 1}) || g.Sides() != 2 || len(g.Wins) != 2 || len(g.Match.Wins) != 2 {
+		t.Fatalf("teams not numbered in seating order: %v", g.Teams)
+	}
+	if g.SideName(1) != "Cat & Dan" {
+		t.Fatalf("unexpected team name %q", g.SideName(1))
+	}
+	if err := g.SetTeams(1, 1, 0, 0); err != nil || !reflect.DeepEqual(g.Teams, []int{0, 0, 1, 2}) {
+		t.Fatalf("seats without a team should play alone: %v, %v", g.Teams, err)
+	}
+	if err := g.SetTeams(0, 0, 0, 0); err != nil || g.Teams != nil {
+		t.Fatal("seats without teams should be free-for-all")
+	}
+	if err := g.SetTeams(); err != nil || g.Teams != nil || g.Sides() != 4 {
+		t.Fatal("SetTeams without labels should return to free-for-all")
+	}
+}
+
+func TestParseTeams(t *testing.T) {
+	labels, err := ParseTeams("1, 1,2,")
+	if err != nil || !reflect.DeepEqual(labels, []int{1, 1, 2, 0}) {
+		t.Fatalf("ParseTeams = %v, %v", labels, err)
+	}
+	if _, err := ParseTeams("1,red"); err == nil {
+		t.Fatal("expected an error for a team that is not a number")
+	}
+	g := newTeamGame(t, labels...)
+	if !reflect.DeepEqual(g.TeamLabels(), []int{1, 1, 2, 3}) {
+		t.Fatalf("TeamLabels = %v", g.TeamLabels())
+	}
+}
+
+func TestTeamsTakeTurns(t *testing.T) {
+	g := newTeamGame(t, 1, 1, 2, 2)
+	var order []int
+	for i := 0; i < 4; i++ {
+		order = append(order, g.Current)
+		g.nextTurn()
+	}
+	if !reflect.DeepEqual(order, []int{0, 2, 1, 3}) {
+		t.Fatalf("turn order = %v", order)
+	}
+}
+
+func TestTeamRoundEndsWithOneSideStanding(t *testing.T) {
+	g := newTeamGame(t, 1, 2, 1, 2)
+	var over GameEvent
+	g.Subscribe(ListenerFunc(func(e GameEvent) {
+		if e.Kind == RoundOver {
+			over = e
+		}
+	}))
+	g.setCurrent(0)
+	g.Shots = []int{2, 1, 1, 1}
+	g.handleGorillaKill(1)
+	if g.roundOver {
+		t.Fatal("round should go on while the other team has a gorilla standing")
+	}
+	g.setCurrent(2)
+	g.handleGorillaKill(3)
+	if !g.roundOver || over.Winner != 0 || over.Shots != 3 {
+		t.Fatalf("expected team 1 to take the round in 3 shots, got %+v", over)
+	}
+	if !reflect.DeepEqual(g.Wins, []int{1, 0}) {
+		t.Fatalf("wins = %v", g.Wins)
+	}
+	if g.Current != 2 {
+		t.Fatalf("the thrower of the deciding banana should start, got %d", g.Current)
+	}
+}
+
+func TestFriendlyFireRules(t *testing.T) {
+	for _, tc := range []struct {
+		rule  FriendlyFire
+		dead  bool
+		kind  EventKind
+		event ShotEvent
+	}{
+		{FriendlyFireSelf, true, SelfKill, EventSelf},
+		{FriendlyFireIgnored, true, GorillaKilled, EventNone},
+		{FriendlyFireOff, false, -1, EventNone},
+	} {
+		t.Run(tc.rule.String(), func(t *testing.T) {
+			g := newTeamGame(t, 1, 2, 1, 2)
+			g.Settings.FriendlyFire = tc.rule
+			g.setCurrent(0)
+			var kinds []EventKind
+			g.Subscribe(ListenerFunc(func(e GameEvent) { kinds = append(kinds, e.Kind) }))
+			mate := g.Gorillas[2]
+			if idx, _ := g.gorillaHitBetween(mate.X-6, mate.Y, mate.X+6, mate.Y); (idx == 2) != tc.dead {
+				t.Fatalf("banana hit %d with friendly fire %v", idx, tc.rule)
+			}
+			g.killGorillasInRadius(mate.X, mate.Y, 2)
+			if g.Gorillas[2].Dead != tc.dead || g.LastEvent != tc.event {
+				t.Fatalf("teammate dead = %v, last event = %v", g.Gorillas[2].Dead, g.LastEvent)
+			}
+			if tc.dead && (len(kinds) != 1 || kinds[0] != tc.kind) {
+				t.Fatalf("events = %v, want %v", kinds, tc.kind)
+			}
+			if !tc.dead && len(kinds) != 0 {
+				t.Fatalf("unexpected events %v", kinds)
+			}
+		})
+	}
+}
+
+func TestParseFriendlyFire(t *testing.T) {
+	for in, want := range map[string]FriendlyFire{"self-kill": FriendlyFireSelf, "OFF": FriendlyFireOff, "ignored": FriendlyFireIgnored} {
+		if got, err := ParseFriendlyFire(in); err != nil || got != want {
+			t.Errorf("ParseFriendlyFire(%q) = %v, %v", in, got, err)
+		}
+	}
+	if _, err := ParseFriendlyFire("sometimes"); err == nil {
+		t.Error("expected an error for an unknown rule")
+	}
+}
+
+func TestTeamMatchSummary(t *testing.T) {
+	g := newTeamGame(t, 1, 2, 1, 2)
+	g.Settings.DefaultRoundQty = 1
+	g.StartMatch()
+	g.setCurrent(1)
+	g.handleGorillaKill(0)
+	g.handleGorillaKill(2)
+	if s := g.MatchSummary(); s != "Bob & Dan wins the match 0-1" {
+		t.Fatalf("summary = %q", s)
+	}
+	if s := g.StatsString(); s != "Session - T1:0 T2:1\nOverall - T1:0 T2:1" {
+		t.Fatalf("stats = %q", s)
+	}
+}
+
+func TestLeagueTeamStandings(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "league.lge")
+	l := LoadLeague(path)
+	l.RecordTeamRound([][]string{{"Bob", "Ann"}, {"Cat", "Dan"}}, 0, 4)
+	l.Save()
+
+	l = LoadLeague(path)
+	ts := l.Teams["Ann & Bob"]
+	if ts == nil || ts.Wins != 1 || ts.Rounds != 1 || ts.Accuracy != 4 {
+		t.Fatalf("unexpected team stats %+v", ts)
+	}
+	if l.Players["Ann"].Wins != 1 || l.Players["Dan"].Rounds != 1 || l.Players["Dan"].Wins != 0 {
+		t.Fatal("team members should share the team's result")
+	}
+	if st := l.TeamStandings(); len(st) != 2 || st[0].Name != "Ann & Bob" {
+		t.Fatalf("unexpected standings %v", st)
+	}
+
+	l.RenamePlayer("Ann", "Zoe")
+	if l.Teams["Bob & Zoe"] == nil || l.Teams["Ann & Bob"] != nil {
+		t.Fatal("renaming a player should rename their teams")
+	}
+	l.DeletePlayer("Dan")
+	if l.Teams["Cat & Dan"] != nil {
+		t.Fatal("deleting a player should drop their teams")
+	}
+}
+
+func TestLoadLeagueReadsPlayerOnlyFiles(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "league.lge")
+	if err := os.WriteFile(path, []byte(`{"Ann":{"rounds":3,"wins":2,"accuracy":1.5}}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	l := LoadLeague(path)
+	if ps := l.Players["Ann"]; ps == nil || ps.Wins != 2 {
+		t.Fatalf("legacy league not loaded: %+v", l.Players)
+	}
+}